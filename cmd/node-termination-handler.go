@@ -15,6 +15,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -24,10 +25,12 @@ import (
 	"time"
 
 	"github.com/aws/aws-node-termination-handler/pkg/config"
+	"github.com/aws/aws-node-termination-handler/pkg/disruptionbudget"
 	"github.com/aws/aws-node-termination-handler/pkg/ec2metadata"
 	"github.com/aws/aws-node-termination-handler/pkg/interruptionevent/asg/launch"
 	"github.com/aws/aws-node-termination-handler/pkg/interruptionevent/draincordon"
 	"github.com/aws/aws-node-termination-handler/pkg/interruptioneventstore"
+	"github.com/aws/aws-node-termination-handler/pkg/leaderelection"
 	"github.com/aws/aws-node-termination-handler/pkg/logging"
 	"github.com/aws/aws-node-termination-handler/pkg/monitor"
 	"github.com/aws/aws-node-termination-handler/pkg/monitor/asglifecycle"
@@ -35,6 +38,7 @@ import (
 	"github.com/aws/aws-node-termination-handler/pkg/monitor/scheduledevent"
 	"github.com/aws/aws-node-termination-handler/pkg/monitor/spotitn"
 	"github.com/aws/aws-node-termination-handler/pkg/monitor/sqsevent"
+	"github.com/aws/aws-node-termination-handler/pkg/monitor/supervisor"
 	"github.com/aws/aws-node-termination-handler/pkg/node"
 	"github.com/aws/aws-node-termination-handler/pkg/observability"
 	"github.com/aws/aws-node-termination-handler/pkg/webhook"
@@ -59,11 +63,17 @@ const (
 	rebalanceRecommendation = "Rebalance Recommendation"
 	sqsEvents               = "SQS Event"
 	timeFormat              = "2006/01/02 15:04:05"
-	duplicateErrThreshold   = 3
+	// rebootCheckInterval is the interval between two checks of the nodes waiting to be uncordoned after a reboot in Queue Processor mode
+	rebootCheckInterval = 30 * time.Second
+	// informerCacheSyncTimeout bounds the wait for the informer cache to list the nodes and pods of the cluster on startup
+	informerCacheSyncTimeout = 2 * time.Minute
 )
 
+// errLeadershipLost cancels the event processors of a replica which lost its lease, whose events now belong to the new leader
+var errLeadershipLost = errors.New("leader election lease lost")
+
 type interruptionEventHandler interface {
-	HandleEvent(*monitor.InterruptionEvent) error
+	HandleEvent(context.Context, *monitor.InterruptionEvent) error
 }
 
 func main() {
@@ -125,6 +135,12 @@ func main() {
 		nthConfig.Print()
 		log.Fatal().Err(err).Msg("Unable to instantiate a node for various kubernetes node functions,")
 	}
+	if nodeCache := node.Cache(); nodeCache != nil {
+		log.Info().Msg("Starting the informer cache of nodes and pods")
+		if err = nodeCache.Start(context.Background(), informerCacheSyncTimeout); err != nil {
+			log.Warn().Err(err).Msg("Looking up nodes and pods with the API server until the informer cache syncs")
+		}
+	}
 
 	metrics, initMetricsErr := observability.InitMetrics(nthConfig.EnablePrometheus, nthConfig.PrometheusPort)
 	if initMetricsErr != nil {
@@ -139,7 +155,12 @@ func main() {
 	}
 	imdsDisabled := nthConfig.EnableSQSTerminationDraining
 
-	interruptionEventStore := interruptioneventstore.New(nthConfig)
+	storeBackend, err := interruptioneventstore.NewBackend(nthConfig, clientset)
+	if err != nil {
+		nthConfig.Print()
+		log.Fatal().Err(err).Msg("Unable to instantiate the interruption event store backend,")
+	}
+	interruptionEventStore := interruptioneventstore.NewWithBackend(nthConfig, storeBackend)
 	var imds *ec2metadata.Service
 	var nodeMetadata ec2metadata.NodeMetadata
 
@@ -160,19 +181,35 @@ func main() {
 		log.Fatal().Msgf("Unable to find the AWS region to process queue events.")
 	}
 
-	recorder, err := observability.InitK8sEventRecorder(nthConfig.EmitKubernetesEvents, nthConfig.NodeName, nthConfig.EnableSQSTerminationDraining, nodeMetadata, nthConfig.KubernetesEventsExtraAnnotations, clientset)
+	recorder, err := observability.InitK8sEventRecorder(nthConfig.EmitKubernetesEvents, nthConfig.NodeName, nthConfig.EnableSQSTerminationDraining, nodeMetadata, nthConfig.KubernetesEventsExtraAnnotations, clientset, node.Cache())
 	if err != nil {
 		nthConfig.Print()
 		log.Fatal().Err(err).Msg("Unable to create Kubernetes event recorder,")
 	}
 
+	// logging is done by the store, every other observer of event progress hangs off lifecycle transitions
+	interruptionEventStore.AddTransitionObserver(func(event *monitor.InterruptionEvent, transition monitor.Transition, err error) {
+		metrics.ObserveTransition(event.NodeName, event, transition, err)
+		recorder.EmitTransition(event.NodeName, event, transition, err)
+	})
+	if nthConfig.NodeTerminationAnnotation {
+		interruptionEventStore.AddTransitionObserver(func(event *monitor.InterruptionEvent, transition monitor.Transition, _ error) {
+			observability.AnnotateTransition(*node, event.NodeName, event, transition)
+		})
+	}
+	if nthConfig.WebhookURL != "" {
+		interruptionEventStore.AddTransitionObserver(func(event *monitor.InterruptionEvent, transition monitor.Transition, _ error) {
+			webhook.PostTransition(nodeMetadata, event, transition, nthConfig)
+		})
+	}
+
 	nthConfig.Print()
 
 	if !imdsDisabled && nthConfig.EnableScheduledEventDraining {
 		//will retry 4 times with an interval of 2 seconds.
 		pollCtx, cancelPollCtx := context.WithTimeout(context.Background(), 8*time.Second)
 		err = wait.PollUntilContextCancel(pollCtx, 2*time.Second, true, func(context.Context) (done bool, err error) {
-			err = handleRebootUncordon(nthConfig.NodeName, interruptionEventStore, *node)
+			err = handleRebootUncordon(nthConfig.NodeName, interruptionEventStore, *node, recorder)
 			if err != nil {
 				log.Warn().Err(err).Msgf("Unable to complete the uncordon after reboot workflow on startup, retrying")
 				return false, nil
@@ -185,10 +222,9 @@ func main() {
 		cancelPollCtx()
 	}
 
-	interruptionChan := make(chan monitor.InterruptionEvent)
-	defer close(interruptionChan)
-	cancelChan := make(chan monitor.InterruptionEvent)
-	defer close(cancelChan)
+	// the ingestion queues are closed once the monitors, their only senders, have stopped
+	interruptionChan := make(chan monitor.InterruptionEvent, nthConfig.IngestionQueueCapacity)
+	cancelChan := make(chan monitor.InterruptionEvent, nthConfig.IngestionQueueCapacity)
 
 	monitoringFns := map[string]monitor.Monitor{}
 	if !imdsDisabled {
@@ -228,6 +264,10 @@ func main() {
 		}
 
 		completeLifecycleActionDelay := time.Duration(nthConfig.CompleteLifecycleActionDelaySeconds) * time.Second
+		heartbeatUntil := -1
+		if nthConfig.HeartbeatInterval != -1 && nthConfig.HeartbeatUntil != -1 {
+			heartbeatUntil = nthConfig.HeartbeatUntil
+		}
 		sqsMonitor := sqsevent.SQSMonitor{
 			CheckIfManaged:                nthConfig.CheckTagBeforeDraining,
 			ManagedTag:                    nthConfig.ManagedTag,
@@ -239,47 +279,92 @@ func main() {
 			EC2:                           ec2Client,
 			BeforeCompleteLifecycleAction: func() { <-time.After(completeLifecycleActionDelay) },
 			SqsMsgVisibilityTimeoutSec:    nthConfig.SqsMsgVisibilityTimeoutSec,
+			OverflowPolicy:                nthConfig.IngestionQueueOverflowPolicy,
+			NodeResolver:                  node.NodeResolver(),
+			HeartbeatUntil:                heartbeatUntil,
 		}
 		monitoringFns[sqsEvents] = sqsMonitor
 	}
 
+	// In Queue Processor mode with leader election enabled, standbys stay idle here until they acquire the lease
+	var leadershipLost <-chan struct{}
+	electionCtx, cancelElection := context.WithCancel(context.Background())
+	defer cancelElection()
+	var leaderElector *leaderelection.Elector
+	if nthConfig.EnableLeaderElection {
+		leaderElector, err = leaderelection.New(nthConfig, clientset)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Unable to set up leader election,")
+		}
+		go leaderElector.Run(electionCtx)
+		select {
+		case <-leaderElector.Leading():
+			leadershipLost = leaderElector.Lost()
+		case <-signalChan:
+			log.Info().Msg("AWS Node Termination Handler is shutting down")
+			cancelElection()
+			<-leaderElector.Done()
+			return
+		}
+	}
+
+	// with leader election the state is only restored once this replica leads, since the previous leader may have kept saving it until then
+	restoreCtx, cancelRestore := context.WithTimeout(context.Background(), 30*time.Second)
+	err = interruptionEventStore.Restore(restoreCtx, node)
+	cancelRestore()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Unable to restore the interruption event store,")
+	}
+
+	// monitorCtx is canceled as soon as a SIGTERM is received so that no new events are accepted, while
+	// handlerCtx is only canceled once in-flight event processors have exceeded the shutdown grace period.
+	monitorCtx, cancelMonitors := context.WithCancel(context.Background())
+	defer cancelMonitors()
+	handlerCtx, cancelHandlers := context.WithCancelCause(context.Background())
+	defer cancelHandlers(nil)
+
+	var monitorWg sync.WaitGroup
+	// each monitor is supervised independently so that a failing monitor does not affect the others
+	supervisorConfig := supervisor.NewConfig(nthConfig)
+	monitorFailures := make(chan error, len(monitoringFns))
 	for _, fn := range monitoringFns {
-		go func(monitor monitor.Monitor) {
-			logging.VersionedMsgs.MonitoringStarted(monitor.Kind())
-			var previousErr error
-			var duplicateErrCount int
-			for range time.Tick(time.Second * 2) {
-				err := monitor.Monitor()
-				if err != nil {
-					logging.VersionedMsgs.ProblemMonitoringForEvents(monitor.Kind(), err)
-					metrics.ErrorEventsInc(monitor.Kind())
-					recorder.Emit(nthConfig.NodeName, observability.Warning, observability.MonitorErrReason, observability.MonitorErrMsgFmt, monitor.Kind())
-					if previousErr != nil && err.Error() == previousErr.Error() {
-						duplicateErrCount++
-					} else {
-						duplicateErrCount = 0
-						previousErr = err
-					}
-					if duplicateErrCount >= duplicateErrThreshold {
-						log.Warn().Msg("Stopping NTH - Duplicate Error Threshold hit.")
-						panic(fmt.Sprintf("%v", err))
-					}
-				}
+		monitorSupervisor := supervisor.New(fn, supervisorConfig, metrics, recorder, nthConfig.NodeName)
+		observability.RegisterMonitorState(fn.Kind(), func() string { return string(monitorSupervisor.State()) })
+		monitorWg.Add(1)
+		go func() {
+			defer monitorWg.Done()
+			if err := monitorSupervisor.Run(monitorCtx); err != nil {
+				monitorFailures <- err
 			}
-		}(fn)
+		}()
+	}
+
+	// in Queue Processor mode the rebooted nodes are not the one NTH runs on, so they are looked for periodically rather than on startup
+	if nthConfig.EnableSQSTerminationDraining {
+		go watchForRebootedNodes(monitorCtx, *node, recorder)
 	}
 
-	go watchForInterruptionEvents(interruptionChan, interruptionEventStore)
+	var ingestionWg sync.WaitGroup
+	ingestionWg.Add(2)
+	go func() {
+		defer ingestionWg.Done()
+		watchForInterruptionEvents(interruptionChan, interruptionEventStore)
+	}()
 	log.Info().Msg("Started watching for interruption events")
 	log.Info().Msg("Kubernetes AWS Node Termination Handler has started successfully!")
 
-	go watchForCancellationEvents(cancelChan, interruptionEventStore, node, metrics, recorder)
+	go func() {
+		defer ingestionWg.Done()
+		watchForCancellationEvents(cancelChan, interruptionEventStore, node, metrics, recorder)
+	}()
 	log.Info().Msg("Started watching for event cancellations")
 
 	var wg sync.WaitGroup
+	var monitorErr error
 
-	asgLaunchHandler := launch.New(interruptionEventStore, *node, nthConfig, metrics, recorder, clientset)
-	drainCordonHander := draincordon.New(interruptionEventStore, *node, nthConfig, nodeMetadata, metrics, recorder)
+	asgLaunchHandler := launch.New(interruptionEventStore, *node, nthConfig, metrics, recorder)
+	disruptionBudget := disruptionbudget.New(nthConfig, clientset, node.Cache(), interruptionEventStore)
+	drainCordonHander := draincordon.New(interruptionEventStore, *node, nthConfig, metrics, recorder, disruptionBudget)
 
 InterruptionLoop:
 	for range time.NewTicker(1 * time.Second).C {
@@ -287,29 +372,98 @@ InterruptionLoop:
 		case <-signalChan:
 			// Exit interruption loop if a SIGTERM is received or the channel is closed
 			break InterruptionLoop
+		case monitorErr = <-monitorFailures:
+			log.Warn().Err(monitorErr).Msg("Stopping NTH - a monitor failed and exit-on-monitor-failure is enabled")
+			break InterruptionLoop
+		case <-leadershipLost:
+			// Another replica may take over at any moment, so in-flight event processors are canceled right away
+			// and the state is no longer saved, which would overwrite the one saved by the new leader
+			log.Warn().Msg("Lost leader election lease, stopping event processing")
+			interruptionEventStore.Abandon()
+			cancelHandlers(errLeadershipLost)
+			break InterruptionLoop
 		default:
+			var budgetSnapshot *disruptionbudget.Snapshot
 		EventLoop:
 			for event, ok := interruptionEventStore.GetActiveEvent(); ok; event, ok = interruptionEventStore.GetActiveEvent() {
+				if blocking, err := disruptionBudget.DoNotDisrupt(monitorCtx, event); err != nil {
+					log.Warn().Err(err).Str("event_id", event.EventID).Msg("Unable to check the do-not-disrupt annotations of the pods, not deferring interruption event")
+				} else if len(blocking) > 0 {
+					log.Info().Str("event_id", event.EventID).Str("node_name", event.NodeName).Strs("pods", blocking).Dur("deferral", disruptionbudget.RecheckInterval).Msg("Pods of the node ask not to be disrupted, deferring interruption event")
+					deferInterruptionEvent(monitorCtx, interruptionEventStore, event, *node)
+					continue
+				}
+				if disruptionBudget.Enabled() {
+					if budgetSnapshot == nil {
+						budgetSnapshot = getBudgetSnapshot(monitorCtx, disruptionBudget)
+					}
+					if budgetSnapshot != nil && !budgetSnapshot.Admit(event) {
+						log.Info().Str("event_id", event.EventID).Str("node_name", event.NodeName).Dur("deferral", disruptionbudget.RecheckInterval).Msg("Disruption budget exhausted, deferring interruption event")
+						deferInterruptionEvent(monitorCtx, interruptionEventStore, event, *node)
+						continue
+					}
+				}
 				select {
 				case interruptionEventStore.Workers <- 1:
 					logging.VersionedMsgs.ProcessingInterruptionEvent(event)
-					event.InProgress = true
+					queueWait := interruptionEventStore.MarkScheduled(event)
+					log.Info().Str("event_id", event.EventID).Dur("queue_wait", queueWait).Msg("Handing interruption event to a worker")
+					metrics.EventQueueWaitRecord(event.Kind, queueWait)
 					wg.Add(1)
-					recorder.Emit(event.NodeName, observability.Normal, observability.GetReasonForKind(event.Kind, event.Monitor), event.Description)
-					go processInterruptionEvent(interruptionEventStore, event, []interruptionEventHandler{asgLaunchHandler, drainCordonHander}, &wg)
+					go processInterruptionEvent(handlerCtx, interruptionEventStore, event, []interruptionEventHandler{asgLaunchHandler, drainCordonHander}, *node, &wg)
 				default:
-					log.Warn().Msg("all workers busy, waiting")
+					log.Warn().Int("queue_depth", interruptionEventStore.QueueDepth()).Msg("all workers busy, waiting")
 					break EventLoop
 				}
 			}
+			metrics.EventQueueDepthRecord(int64(interruptionEventStore.QueueDepth()))
+			metrics.IngestionQueueDepthRecord("interruption", int64(len(interruptionChan)))
+			metrics.IngestionQueueDepthRecord("cancel", int64(len(cancelChan)))
 		}
 	}
 	log.Info().Msg("AWS Node Termination Handler is shutting down")
+	cancelMonitors()
+	monitorWg.Wait()
+	log.Debug().Msg("all monitors stopped")
+	// events still in the ingestion queues are taken in by the store, so that the unprocessed ones are released below
+	close(interruptionChan)
+	close(cancelChan)
+	ingestionWg.Wait()
+
+	shutdownGracePeriod := time.Duration(nthConfig.ShutdownGracePeriod) * time.Second
+	shutdownTimer := time.AfterFunc(shutdownGracePeriod, func() {
+		log.Warn().Dur("shutdown_grace_period", shutdownGracePeriod).Msg("Shutdown grace period exceeded, canceling in-flight event processors")
+		cancelHandlers(nil)
+	})
 	wg.Wait()
+	shutdownTimer.Stop()
 	log.Debug().Msg("all event processors finished")
+
+	select {
+	case <-leadershipLost:
+		// the events and the state belong to the new leader, so they are neither released nor saved
+		interruptionEventStore.Abandon()
+		log.Fatal().Msg("Leader election lease lost, exiting to rejoin as a standby")
+	default:
+	}
+	for _, event := range interruptionEventStore.PendingEvents() {
+		releaseInterruptionEvent(event, *node)
+	}
+	// the state is saved before the lease is released, so that the next leader restores it
+	interruptionEventStore.Close()
+
+	if leaderElector != nil {
+		cancelElection()
+		<-leaderElector.Done()
+		log.Debug().Msg("released leader election lease")
+	}
+
+	if monitorErr != nil {
+		log.Fatal().Err(monitorErr).Msg("Exiting due to monitor failure")
+	}
 }
 
-func handleRebootUncordon(nodeName string, interruptionEventStore *interruptioneventstore.Store, node node.Node) error {
+func handleRebootUncordon(nodeName string, interruptionEventStore *interruptioneventstore.Store, node node.Node, recorder observability.K8sEventRecorder) error {
 	isLabeled, err := node.IsLabeledWithAction(nodeName)
 	if err != nil {
 		return err
@@ -321,7 +475,7 @@ func handleRebootUncordon(nodeName string, interruptionEventStore *interruptione
 	if err != nil {
 		return err
 	}
-	err = node.UncordonIfRebooted(nodeName)
+	err = recorder.EmitOwnershipConflicts(node.UncordonIfRebooted(nodeName))
 	if err != nil {
 		return fmt.Errorf("unable to complete node label actions: %w", err)
 	}
@@ -329,36 +483,47 @@ func handleRebootUncordon(nodeName string, interruptionEventStore *interruptione
 	return nil
 }
 
+// watchForRebootedNodes uncordons the nodes marked for uncordon after reboot once they rebooted, until ctx is canceled
+func watchForRebootedNodes(ctx context.Context, node node.Node, recorder observability.K8sEventRecorder) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := recorder.EmitOwnershipConflicts(node.UncordonRebootedNodes(ctx)); err != nil {
+			log.Warn().Err(err).Msg("Unable to uncordon the nodes which rebooted")
+		}
+	}, rebootCheckInterval)
+}
+
 func watchForInterruptionEvents(interruptionChan <-chan monitor.InterruptionEvent, interruptionEventStore *interruptioneventstore.Store) {
-	for {
-		interruptionEvent := <-interruptionChan
+	for interruptionEvent := range interruptionChan {
 		interruptionEventStore.AddInterruptionEvent(&interruptionEvent)
 	}
 }
 
 func watchForCancellationEvents(cancelChan <-chan monitor.InterruptionEvent, interruptionEventStore *interruptioneventstore.Store, node *node.Node, metrics observability.Metrics, recorder observability.K8sEventRecorder) {
-	for {
-		interruptionEvent := <-cancelChan
+	for interruptionEvent := range cancelChan {
 		nodeName := interruptionEvent.NodeName
 		eventID := interruptionEvent.EventID
 		interruptionEventStore.CancelInterruptionEvent(interruptionEvent.EventID)
 		if interruptionEventStore.ShouldUncordonNode(nodeName) {
 			log.Info().Msg("Uncordoning the node due to a cancellation event")
-			err := node.Uncordon(nodeName)
-			if err != nil {
+			// a node cordoned by another actor is left cordoned, which is neither an uncordon nor a failure
+			uncordonErr := node.Uncordon(nodeName)
+			err := recorder.EmitOwnershipConflicts(uncordonErr)
+			switch {
+			case err != nil:
 				log.Err(err).Msg("Uncordoning the node failed")
 				recorder.Emit(nodeName, observability.Warning, observability.UncordonErrReason, observability.UncordonErrMsgFmt, err.Error())
-			} else {
+				metrics.NodeActionsInc("uncordon", nodeName, eventID, err)
+			case uncordonErr == nil:
 				recorder.Emit(nodeName, observability.Normal, observability.UncordonReason, observability.UncordonMsg)
+				metrics.NodeActionsInc("uncordon", nodeName, eventID, nil)
 			}
-			metrics.NodeActionsInc("uncordon", nodeName, eventID, err)
 
-			err = node.RemoveNTHLabels(nodeName)
+			err = recorder.EmitOwnershipConflicts(node.RemoveNTHLabels(nodeName))
 			if err != nil {
 				log.Warn().Err(err).Msg("There was an issue removing NTH labels from node")
 			}
 
-			err = node.RemoveNTHTaints(nodeName)
+			err = recorder.EmitOwnershipConflicts(node.RemoveNTHTaints(nodeName))
 			if err != nil {
 				log.Warn().Err(err).Msg("There was an issue removing NTH taints from node")
 			}
@@ -368,7 +533,7 @@ func watchForCancellationEvents(cancelChan <-chan monitor.InterruptionEvent, int
 	}
 }
 
-func processInterruptionEvent(interruptionEventStore *interruptioneventstore.Store, event *monitor.InterruptionEvent, eventHandlers []interruptionEventHandler, wg *sync.WaitGroup) {
+func processInterruptionEvent(ctx context.Context, interruptionEventStore *interruptioneventstore.Store, event *monitor.InterruptionEvent, eventHandlers []interruptionEventHandler, node node.Node, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	if event == nil {
@@ -379,14 +544,65 @@ func processInterruptionEvent(interruptionEventStore *interruptioneventstore.Sto
 
 	var err error
 	for _, eventHandler := range eventHandlers {
-		err = eventHandler.HandleEvent(event)
+		err = eventHandler.HandleEvent(ctx, event)
 		if err != nil {
 			log.Error().Err(err).Interface("event", event).Msg("handling event")
 		}
 	}
+	if interruptionEventStore.LifecycleState(event) == monitor.StateScheduled {
+		// none of the handlers acts on this kind of event
+		if err = interruptionEventStore.Transition(event, monitor.StateCompleted, nil); err != nil {
+			log.Warn().Err(err).Msg("Unable to complete interruption event")
+		}
+	}
+	// the events of a replica which lost its lease belong to the new leader, which must not see them released
+	if ctx.Err() != nil && !errors.Is(context.Cause(ctx), errLeadershipLost) {
+		for _, processed := range append([]*monitor.InterruptionEvent{event}, interruptionEventStore.MergedEvents(event)...) {
+			if !interruptionEventStore.LifecycleState(processed).NodeProcessed() {
+				releaseInterruptionEvent(processed, node)
+			}
+		}
+	}
+	// queued events of the node wait until its worker is done, so that at most one handler acts on a node at a time
+	interruptionEventStore.ReleaseNode(event)
 	<-interruptionEventStore.Workers
 }
 
+// getBudgetSnapshot returns the current disruption of the cluster, or nil if it cannot be counted, in which case events are not held back
+func getBudgetSnapshot(ctx context.Context, disruptionBudget *disruptionbudget.Budget) *disruptionbudget.Snapshot {
+	snapshotCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	snapshot, err := disruptionBudget.Snapshot(snapshotCtx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Unable to count disrupted nodes, the disruption budget is not enforced")
+		return nil
+	}
+	return snapshot
+}
+
+// deferInterruptionEvent holds an event back until the disruption budget is checked again, running its defer task first
+// so that its source does not give up on it in the meantime
+func deferInterruptionEvent(ctx context.Context, interruptionEventStore *interruptioneventstore.Store, event *monitor.InterruptionEvent, node node.Node) {
+	if event.DeferTask != nil {
+		if err := event.DeferTask(ctx, *event, node); err != nil {
+			log.Warn().Err(err).Str("event_id", event.EventID).Msg("Unable to run the defer task of interruption event")
+		}
+	}
+	interruptionEventStore.DeferInterruptionEvent(event, time.Now().Add(disruptionbudget.RecheckInterval))
+}
+
+// releaseInterruptionEvent hands an unprocessed event back to its source, if supported, so that it can be picked up by another replica
+func releaseInterruptionEvent(event *monitor.InterruptionEvent, node node.Node) {
+	if event.ReleaseTask == nil {
+		return
+	}
+	log.Info().Str("event_id", event.EventID).Str("node_name", event.NodeName).Msg("Releasing unprocessed interruption event")
+	// events are released on shutdown, once the contexts of the monitors and the handlers are canceled
+	if err := event.ReleaseTask(context.Background(), *event, node); err != nil {
+		log.Warn().Err(err).Str("event_id", event.EventID).Msg("Unable to release interruption event")
+	}
+}
+
 func getRegionFromQueueURL(queueURL string) string {
 	for _, partition := range endpoints.DefaultPartitions() {
 		for regionID := range partition.Regions() {
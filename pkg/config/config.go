@@ -17,10 +17,89 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"text/template"
 
+	"github.com/Masterminds/sprig/v3"
 	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// StoreBackendMemory keeps the interruption event store in memory only
+	StoreBackendMemory = "memory"
+	// StoreBackendConfigMap persists the interruption event store in a ConfigMap
+	StoreBackendConfigMap = "configmap"
+	// StoreBackendFile persists the interruption event store in a local file
+	StoreBackendFile = "file"
+)
+
+const (
+	// IngestionOverflowBlock makes a monitor wait for room in a full ingestion queue, it stops receiving events meanwhile
+	IngestionOverflowBlock = "block"
+	// IngestionOverflowReject makes a monitor hand an event which does not fit in the ingestion queue back to its source
+	IngestionOverflowReject = "reject"
+)
+
+const (
+	// DisruptionScopeCluster applies the disruption budget to all the nodes of the cluster
+	DisruptionScopeCluster = "cluster"
+	// DisruptionScopeASG applies the disruption budget to the nodes of each ASG separately
+	DisruptionScopeASG = "asg"
+	// DisruptionScopeZone applies the disruption budget to the nodes of each availability zone separately
+	DisruptionScopeZone = "zone"
+	// DisruptionScopeLabel applies the disruption budget to the nodes with each value of a label separately
+	DisruptionScopeLabel = "label"
+)
+
+const (
+	// DrainWaveOrderNone evicts all the pods of a node together
+	DrainWaveOrderNone = "none"
+	// DrainWaveOrderPriority evicts the pods of a node in waves of ascending priority
+	DrainWaveOrderPriority = "priority"
+	// DrainWaveOrderAnnotation evicts the pods of a node in waves of ascending drain-order annotation value
+	DrainWaveOrderAnnotation = "annotation"
+	// DrainWaveOrderWorkload evicts stateless pods first, then StatefulSet pods in reverse ordinal, then critical pods
+	DrainWaveOrderWorkload = "workload"
+)
+
+const (
+	// DataLossOrphan is the kind of pods with no controller, which are not recreated once drained
+	DataLossOrphan = "orphan"
+	// DataLossEmptyDir is the kind of pods with emptyDir volumes, whose data is lost once drained
+	DataLossEmptyDir = "emptydir"
+	// DataLossLocalPV is the kind of pods with a local persistent volume, whose data stays on the node once drained
+	DataLossLocalPV = "localpv"
+
+	// DataLossForce evicts the pods of a kind
+	DataLossForce = "force"
+	// DataLossSkip keeps the pods of a kind on the node
+	DataLossSkip = "skip"
+	// DataLossHold evicts the pods of a kind once a hook releases them with the drain-hold annotation
+	DataLossHold = "hold"
+)
+
+const (
+	// WorkloadReadinessFallbackComplete completes the ASG termination lifecycle action when the workloads are not ready in time
+	WorkloadReadinessFallbackComplete = "complete"
+	// WorkloadReadinessFallbackRetry fails the event when the workloads are not ready in time, so that it is retried
+	WorkloadReadinessFallbackRetry = "retry"
+)
+
+const (
+	// NodeResolutionProviderID finds the node whose spec.providerID names the instance
+	NodeResolutionProviderID = "provider-id"
+	// NodeResolutionPrivateDNS finds the node named after the private DNS name of the instance, with node-resolution-dns-suffix as its domain if set
+	NodeResolutionPrivateDNS = "private-dns"
+	// NodeResolutionInstanceIDLabel finds the node whose node-resolution-instance-id-label is the instance ID
+	NodeResolutionInstanceIDLabel = "instance-id-label"
+	// NodeResolutionTag finds the node named after the value of the node-resolution-tag tag of the instance
+	NodeResolutionTag = "tag"
+	// NodeResolutionHostnameLabel finds the node whose kubernetes.io/hostname label is the private DNS name of the instance or its host part
+	NodeResolutionHostnameLabel = "hostname-label"
 )
 
 const (
@@ -96,6 +175,95 @@ const (
 	uptimeFromFileDefault                   = ""
 	workersConfigKey                        = "WORKERS"
 	workersDefault                          = 10
+	shutdownGracePeriodConfigKey            = "SHUTDOWN_GRACE_PERIOD"
+	shutdownGracePeriodDefault              = 25
+	enableLeaderElectionConfigKey           = "ENABLE_LEADER_ELECTION"
+	enableLeaderElectionDefault             = false
+	leaderElectionLeaseNameConfigKey        = "LEADER_ELECTION_LEASE_NAME"
+	leaderElectionLeaseNameDefault          = "aws-node-termination-handler"
+	leaderElectionNamespaceConfigKey        = "LEADER_ELECTION_NAMESPACE"
+	leaderElectionLeaseDurationConfigKey    = "LEADER_ELECTION_LEASE_DURATION"
+	leaderElectionLeaseDurationDefault      = 15
+	leaderElectionRenewDeadlineConfigKey    = "LEADER_ELECTION_RENEW_DEADLINE"
+	leaderElectionRenewDeadlineDefault      = 10
+	leaderElectionRetryPeriodConfigKey      = "LEADER_ELECTION_RETRY_PERIOD"
+	leaderElectionRetryPeriodDefault        = 2
+	monitorMaxBackoffConfigKey              = "MONITOR_MAX_BACKOFF"
+	monitorMaxBackoffDefault                = 60
+	monitorFailureThresholdConfigKey        = "MONITOR_FAILURE_THRESHOLD"
+	monitorFailureThresholdDefault          = 5
+	monitorCircuitCooldownConfigKey         = "MONITOR_CIRCUIT_COOLDOWN"
+	monitorCircuitCooldownDefault           = 60
+	exitOnMonitorFailureConfigKey           = "EXIT_ON_MONITOR_FAILURE"
+	exitOnMonitorFailureDefault             = false
+	eventKindSeverityConfigKey              = "EVENT_KIND_SEVERITY"
+	storeBackendConfigKey                   = "STORE_BACKEND"
+	storeBackendDefault                     = StoreBackendMemory
+	storeConfigMapNameConfigKey             = "STORE_CONFIGMAP_NAME"
+	storeConfigMapNameDefault               = "aws-node-termination-handler-state"
+	storeConfigMapNamespaceConfigKey        = "STORE_CONFIGMAP_NAMESPACE"
+	storeFilePathConfigKey                  = "STORE_FILE_PATH"
+	storeFilePathDefault                    = "/var/lib/aws-node-termination-handler/state.json"
+	drainRetryMaxAttemptsConfigKey          = "DRAIN_RETRY_MAX_ATTEMPTS"
+	drainRetryMaxAttemptsDefault            = 3
+	drainRetryInitialBackoffConfigKey       = "DRAIN_RETRY_INITIAL_BACKOFF"
+	drainRetryInitialBackoffDefault         = 2
+	drainRetryMaxBackoffConfigKey           = "DRAIN_RETRY_MAX_BACKOFF"
+	drainRetryMaxBackoffDefault             = 30
+	drainRetryDeadlineConfigKey             = "DRAIN_RETRY_DEADLINE"
+	drainRetryDeadlineDefault               = 120
+	ingestionQueueCapacityConfigKey         = "INGESTION_QUEUE_CAPACITY"
+	ingestionQueueCapacityDefault           = 100
+	ingestionQueueOverflowPolicyConfigKey   = "INGESTION_QUEUE_OVERFLOW_POLICY"
+	ingestionQueueOverflowPolicyDefault     = IngestionOverflowBlock
+	disruptionBudgetConfigKey               = "DISRUPTION_BUDGET"
+	disruptionBudgetScopeConfigKey          = "DISRUPTION_BUDGET_SCOPE"
+	disruptionBudgetScopeDefault            = DisruptionScopeCluster
+	disruptionBudgetScopeLabelConfigKey     = "DISRUPTION_BUDGET_SCOPE_LABEL"
+	disruptionBudgetMaxDeferralConfigKey    = "DISRUPTION_BUDGET_MAX_DEFERRAL"
+	disruptionBudgetMaxDeferralDefault      = 600
+	stormModeThresholdConfigKey             = "STORM_MODE_THRESHOLD"
+	stormModeThresholdDefault               = 0
+	evictionMaxParallelismConfigKey         = "EVICTION_MAX_PARALLELISM"
+	evictionMaxParallelismDefault           = 10
+	drainWaveOrderConfigKey                 = "DRAIN_WAVE_ORDER"
+	drainWaveOrderDefault                   = DrainWaveOrderNone
+	pdbEscalationThresholdConfigKey         = "PDB_ESCALATION_THRESHOLD"
+	pdbEscalationThresholdDefault           = 100
+	podDataLossPolicyConfigKey              = "POD_DATA_LOSS_POLICY"
+	podDataLossPolicyDefault                = ""
+	podDataLossPolicyOverridesConfigKey     = "POD_DATA_LOSS_POLICY_OVERRIDES"
+	podDataLossPolicyOverridesDefault       = ""
+	podDisruptionConditionConfigKey         = "POD_DISRUPTION_CONDITION"
+	podDisruptionConditionDefault           = true
+	podDisruptionAnnotationConfigKey        = "POD_DISRUPTION_ANNOTATION"
+	podDisruptionAnnotationDefault          = false
+	waitForVolumeDetachmentConfigKey        = "WAIT_FOR_VOLUME_DETACHMENT"
+	waitForVolumeDetachmentDefault          = false
+	volumeDetachmentTimeoutConfigKey        = "VOLUME_DETACHMENT_TIMEOUT"
+	volumeDetachmentTimeoutDefault          = 120
+	volumeDetachmentCheckNodeStatusKey      = "VOLUME_DETACHMENT_CHECK_NODE_STATUS"
+	volumeDetachmentCheckNodeStatusDefault  = false
+	waitForWorkloadReadinessConfigKey       = "WAIT_FOR_WORKLOAD_READINESS"
+	waitForWorkloadReadinessDefault         = false
+	workloadReadinessTimeoutConfigKey       = "WORKLOAD_READINESS_TIMEOUT"
+	workloadReadinessTimeoutDefault         = 300
+	workloadReadinessFallbackConfigKey      = "WORKLOAD_READINESS_FALLBACK"
+	workloadReadinessFallbackDefault        = WorkloadReadinessFallbackComplete
+	markingPolicyConfigKey                  = "MARKING_POLICY"
+	markingPolicyDefault                    = ""
+	nodeTerminationAnnotationConfigKey      = "NODE_TERMINATION_ANNOTATION"
+	nodeTerminationAnnotationDefault        = false
+	nodeResolutionConfigKey                 = "NODE_RESOLUTION"
+	nodeResolutionDefault                   = ""
+	nodeResolutionDNSSuffixConfigKey        = "NODE_RESOLUTION_DNS_SUFFIX"
+	nodeResolutionDNSSuffixDefault          = ""
+	nodeResolutionInstanceIDLabelConfigKey  = "NODE_RESOLUTION_INSTANCE_ID_LABEL"
+	nodeResolutionInstanceIDLabelDefault    = "alpha.eksctl.io/instance-id"
+	nodeResolutionTagConfigKey              = "NODE_RESOLUTION_TAG"
+	nodeResolutionTagDefault                = ""
+	enableInformerCacheConfigKey            = "ENABLE_INFORMER_CACHE"
+	enableInformerCacheDefault              = true
 	useAPIServerCache                       = "USE_APISERVER_CACHE"
 	// prometheus
 	enablePrometheusDefault   = false
@@ -177,6 +345,53 @@ type Config struct {
 	AWSEndpoint                         string
 	QueueURL                            string
 	Workers                             int
+	ShutdownGracePeriod                 int
+	EnableLeaderElection                bool
+	LeaderElectionLeaseName             string
+	LeaderElectionNamespace             string
+	LeaderElectionLeaseDuration         int
+	LeaderElectionRenewDeadline         int
+	LeaderElectionRetryPeriod           int
+	MonitorMaxBackoff                   int
+	MonitorFailureThreshold             int
+	MonitorCircuitCooldown              int
+	ExitOnMonitorFailure                bool
+	EventKindSeverity                   string
+	StoreBackend                        string
+	StoreConfigMapName                  string
+	StoreConfigMapNamespace             string
+	StoreFilePath                       string
+	DrainRetryMaxAttempts               int
+	DrainRetryInitialBackoff            int
+	DrainRetryMaxBackoff                int
+	DrainRetryDeadline                  int
+	IngestionQueueCapacity              int
+	IngestionQueueOverflowPolicy        string
+	DisruptionBudget                    string
+	DisruptionBudgetScope               string
+	DisruptionBudgetScopeLabel          string
+	DisruptionBudgetMaxDeferral         int
+	StormModeThreshold                  int
+	EvictionMaxParallelism              int
+	DrainWaveOrder                      string
+	PDBEscalationThreshold              int
+	PodDataLossPolicy                   string
+	PodDataLossPolicyOverrides          string
+	PodDisruptionCondition              bool
+	PodDisruptionAnnotation             bool
+	WaitForVolumeDetachment             bool
+	VolumeDetachmentTimeout             int
+	VolumeDetachmentCheckNodeStatus     bool
+	WaitForWorkloadReadiness            bool
+	WorkloadReadinessTimeout            int
+	WorkloadReadinessFallback           string
+	MarkingPolicy                       string
+	NodeTerminationAnnotation           bool
+	NodeResolution                      string
+	NodeResolutionDNSSuffix             string
+	NodeResolutionInstanceIDLabel       string
+	NodeResolutionTag                   string
+	EnableInformerCache                 bool
 	UseProviderId                       bool
 	CompleteLifecycleActionDelaySeconds int
 	DeleteSqsMsgIfNodeNotFound          bool
@@ -203,7 +418,7 @@ func ParseCliArgs() (config Config, err error) {
 	flag.StringVar(&config.PodNamespace, "pod-namespace", getEnv(podNamespaceConfigKey, ""), "The kubernetes pod namespace")
 	flag.StringVar(&config.MetadataURL, "metadata-url", getEnv(instanceMetadataURLConfigKey, defaultInstanceMetadataURL), "The URL of EC2 instance metadata. This shouldn't need to be changed unless you are testing.")
 	flag.BoolVar(&config.IgnoreDaemonSets, "ignore-daemon-sets", getBoolEnv(ignoreDaemonSetsConfigKey, true), "If true, ignore daemon sets and drain other pods when a spot interrupt is received.")
-	flag.BoolVar(&config.DeleteLocalData, "delete-local-data", getBoolEnv(deleteLocalDataConfigKey, true), "If true, do not drain pods that are using local node storage in emptyDir")
+	flag.BoolVar(&config.DeleteLocalData, "delete-local-data", getBoolEnv(deleteLocalDataConfigKey, true), "If true, drain pods that are using local node storage in emptyDir, otherwise keep them on the node. Overridden by the emptydir action of pod-data-loss-policy.")
 	flag.StringVar(&config.KubernetesServiceHost, "kubernetes-service-host", getEnv(kubernetesServiceHostConfigKey, ""), "[ADVANCED] The k8s service host to send api calls to.")
 	flag.StringVar(&config.KubernetesServicePort, "kubernetes-service-port", getEnv(kubernetesServicePortConfigKey, ""), "[ADVANCED] The k8s service port to send api calls to.")
 	flag.IntVar(&gracePeriod, "grace-period", getIntEnv(gracePeriodConfigKey, podTerminationGracePeriodDefault), "[DEPRECATED] * Use pod-termination-grace-period instead * Period of time in seconds given to each pod to terminate gracefully. If negative, the default value specified in the pod will be used.")
@@ -247,7 +462,54 @@ func ParseCliArgs() (config Config, err error) {
 	flag.StringVar(&config.AWSEndpoint, "aws-endpoint", getEnv(awsEndpointConfigKey, ""), "[testing] If specified, use the AWS endpoint to make API calls")
 	flag.StringVar(&config.QueueURL, "queue-url", getEnv(queueURLConfigKey, ""), "Listens for messages on the specified SQS queue URL")
 	flag.IntVar(&config.Workers, "workers", getIntEnv(workersConfigKey, workersDefault), "The amount of parallel event processors.")
-	flag.BoolVar(&config.UseProviderId, "use-provider-id", getBoolEnv(useProviderIdConfigKey, useProviderIdDefault), "If true, fetch node name through Kubernetes node spec ProviderID instead of AWS event PrivateDnsHostname.")
+	flag.IntVar(&config.ShutdownGracePeriod, "shutdown-grace-period", getIntEnv(shutdownGracePeriodConfigKey, shutdownGracePeriodDefault), "Period of time in seconds given to in-flight event processors to finish after a SIGTERM is received. Unfinished drains are canceled and unprocessed queue messages are released once it expires.")
+	flag.BoolVar(&config.EnableLeaderElection, "enable-leader-election", getBoolEnv(enableLeaderElectionConfigKey, enableLeaderElectionDefault), "If true, only the replica holding the leader election lease monitors and processes events. Only supported in Queue Processor mode.")
+	flag.StringVar(&config.LeaderElectionLeaseName, "leader-election-lease-name", getEnv(leaderElectionLeaseNameConfigKey, leaderElectionLeaseNameDefault), "The name of the coordination.k8s.io Lease used for leader election.")
+	flag.StringVar(&config.LeaderElectionNamespace, "leader-election-namespace", getEnv(leaderElectionNamespaceConfigKey, ""), "The namespace of the leader election Lease. Defaults to pod-namespace.")
+	flag.IntVar(&config.LeaderElectionLeaseDuration, "leader-election-lease-duration", getIntEnv(leaderElectionLeaseDurationConfigKey, leaderElectionLeaseDurationDefault), "Duration in seconds that standby replicas wait before forcing acquisition of a lease which has not been renewed.")
+	flag.IntVar(&config.LeaderElectionRenewDeadline, "leader-election-renew-deadline", getIntEnv(leaderElectionRenewDeadlineConfigKey, leaderElectionRenewDeadlineDefault), "Duration in seconds that the leader retries renewing the lease before giving up leadership. Should be less than leader-election-lease-duration.")
+	flag.IntVar(&config.LeaderElectionRetryPeriod, "leader-election-retry-period", getIntEnv(leaderElectionRetryPeriodConfigKey, leaderElectionRetryPeriodDefault), "Duration in seconds between attempts to acquire or renew the lease. Should be less than leader-election-renew-deadline.")
+	flag.IntVar(&config.MonitorMaxBackoff, "monitor-max-backoff", getIntEnv(monitorMaxBackoffConfigKey, monitorMaxBackoffDefault), "Maximum period of time in seconds between polls of a monitor which keeps failing. The backoff grows exponentially from the 2 second polling interval.")
+	flag.IntVar(&config.MonitorFailureThreshold, "monitor-failure-threshold", getIntEnv(monitorFailureThresholdConfigKey, monitorFailureThresholdDefault), "Number of consecutive failures after which a monitor's circuit breaker opens and the monitor is paused.")
+	flag.IntVar(&config.MonitorCircuitCooldown, "monitor-circuit-cooldown", getIntEnv(monitorCircuitCooldownConfigKey, monitorCircuitCooldownDefault), "Period of time in seconds a monitor stays paused after its circuit breaker opens before it is polled again.")
+	flag.BoolVar(&config.ExitOnMonitorFailure, "exit-on-monitor-failure", getBoolEnv(exitOnMonitorFailureConfigKey, exitOnMonitorFailureDefault), "If true, NTH shuts down and exits with an error once any monitor's circuit breaker opens.")
+	flag.StringVar(&config.EventKindSeverity, "event-kind-severity", getEnv(eventKindSeverityConfigKey, ""), "A comma-separated list of kind=severity pairs overriding the priority of events with the same drain deadline, higher severity events are drained first. Example: --event-kind-severity SPOT_ITN=100,REBALANCE_RECOMMENDATION=0")
+	flag.StringVar(&config.StoreBackend, "store-backend", getEnv(storeBackendConfigKey, storeBackendDefault), "Where the interruption event store persists its state so that it survives restarts: memory (not persisted), configmap (Queue Processor mode only) or file.")
+	flag.StringVar(&config.StoreConfigMapName, "store-configmap-name", getEnv(storeConfigMapNameConfigKey, storeConfigMapNameDefault), "The name of the ConfigMap used by the configmap store backend.")
+	flag.StringVar(&config.StoreConfigMapNamespace, "store-configmap-namespace", getEnv(storeConfigMapNamespaceConfigKey, ""), "The namespace of the ConfigMap used by the configmap store backend. Defaults to pod-namespace.")
+	flag.StringVar(&config.StoreFilePath, "store-file-path", getEnv(storeFilePathConfigKey, storeFilePathDefault), "The path of the file used by the file store backend.")
+	flag.IntVar(&config.DrainRetryMaxAttempts, "drain-retry-max-attempts", getIntEnv(drainRetryMaxAttemptsConfigKey, drainRetryMaxAttemptsDefault), "The maximum number of times an event is processed when its drain keeps failing with retryable errors, 1 disables retries.")
+	flag.IntVar(&config.DrainRetryInitialBackoff, "drain-retry-initial-backoff", getIntEnv(drainRetryInitialBackoffConfigKey, drainRetryInitialBackoffDefault), "Period of time in seconds before a failed drain is retried for the first time. The backoff doubles with every failed attempt.")
+	flag.IntVar(&config.DrainRetryMaxBackoff, "drain-retry-max-backoff", getIntEnv(drainRetryMaxBackoffConfigKey, drainRetryMaxBackoffDefault), "Maximum period of time in seconds before a failed drain is retried.")
+	flag.IntVar(&config.DrainRetryDeadline, "drain-retry-deadline", getIntEnv(drainRetryDeadlineConfigKey, drainRetryDeadlineDefault), "Period of time in seconds after the start time of an event past which a failed drain is no longer retried.")
+	flag.IntVar(&config.IngestionQueueCapacity, "ingestion-queue-capacity", getIntEnv(ingestionQueueCapacityConfigKey, ingestionQueueCapacityDefault), "The number of events monitors can report before the event store takes them in. Once it is full, the SQS monitor stops receiving messages.")
+	flag.StringVar(&config.IngestionQueueOverflowPolicy, "ingestion-queue-overflow-policy", getEnv(ingestionQueueOverflowPolicyConfigKey, ingestionQueueOverflowPolicyDefault), "What a monitor does with an event which does not fit in the full ingestion queue: block (wait for room) or reject (hand the event back to its source, Queue Processor mode only).")
+	flag.StringVar(&config.DisruptionBudget, "disruption-budget", getEnv(disruptionBudgetConfigKey, ""), "The maximum number of nodes which can be cordoned or drained at the same time within a scope, as a count (e.g. 5) or a percentage of the nodes in the scope (e.g. 10%). Empty means no budget.")
+	flag.StringVar(&config.DisruptionBudgetScope, "disruption-budget-scope", getEnv(disruptionBudgetScopeConfigKey, disruptionBudgetScopeDefault), "The nodes the disruption budget applies to: cluster, asg (a count only), zone or label.")
+	flag.StringVar(&config.DisruptionBudgetScopeLabel, "disruption-budget-scope-label", getEnv(disruptionBudgetScopeLabelConfigKey, ""), "The node label whose values partition the nodes when the disruption budget scope is label.")
+	flag.IntVar(&config.DisruptionBudgetMaxDeferral, "disruption-budget-max-deferral", getIntEnv(disruptionBudgetMaxDeferralConfigKey, disruptionBudgetMaxDeferralDefault), "Period of time in seconds after the start time of an event past which it is processed even if it exceeds the disruption budget or pods of its node have the do-not-disrupt annotation, or earlier if its node could not be drained before the deadline of the event otherwise. Spot ITNs and EC2 state changes are never deferred.")
+	flag.IntVar(&config.StormModeThreshold, "storm-mode-threshold", getIntEnv(stormModeThresholdConfigKey, stormModeThresholdDefault), "The number of new events per minute above which nodes are only cordoned rather than drained. 0 disables storm mode.")
+	flag.IntVar(&config.EvictionMaxParallelism, "eviction-max-parallelism", getIntEnv(evictionMaxParallelismConfigKey, evictionMaxParallelismDefault), "The maximum number of pods of a node evicted at the same time. Evictions refused with a 429, e.g. by a PodDisruptionBudget, are retried with backoff until the drain times out.")
+	flag.StringVar(&config.DrainWaveOrder, "drain-wave-order", getEnv(drainWaveOrderConfigKey, drainWaveOrderDefault), "The order in which the pods of a node are evicted in waves: none (all together), priority, annotation (aws-node-termination-handler/drain-order) or workload (stateless, StatefulSets in reverse ordinal, then critical pods). DaemonSet pods with the drain-order annotation are evicted in the last waves.")
+	flag.IntVar(&config.PDBEscalationThreshold, "pdb-escalation-threshold", getIntEnv(pdbEscalationThresholdConfigKey, pdbEscalationThresholdDefault), "The percentage of the time between the start of a drain and the interruption deadline after which pods whose eviction a PodDisruptionBudget refuses are deleted instead. 100 never overrides PodDisruptionBudgets.")
+	flag.StringVar(&config.PodDataLossPolicy, "pod-data-loss-policy", getEnv(podDataLossPolicyConfigKey, podDataLossPolicyDefault), "Comma-separated kind=action pairs setting what happens to the pods whose drain loses them or their data: orphan (no controller), emptydir or localpv pods, with force (evict them), skip (keep them on the node) or hold (evict them once a hook sets their aws-node-termination-handler/drain-hold annotation to released). Kinds default to force, except emptydir which defaults to skip if delete-local-data is false.")
+	flag.StringVar(&config.PodDataLossPolicyOverrides, "pod-data-loss-policy-overrides", getEnv(podDataLossPolicyOverridesConfigKey, podDataLossPolicyOverridesDefault), "Semicolon-separated rules overriding pod-data-loss-policy for the pods of a namespace or matching a label selector, e.g. namespace/debug:orphan=skip;selector/team=data:localpv=hold,emptydir=hold. The first rule which matches a pod and sets a kind applies.")
+	flag.BoolVar(&config.PodDisruptionCondition, "pod-disruption-condition", getBoolEnv(podDisruptionConditionConfigKey, podDisruptionConditionDefault), "If true, the DisruptionTarget condition is set on the pods of a node as soon as an interruption event which drains it is accepted, before they are evicted.")
+	flag.BoolVar(&config.PodDisruptionAnnotation, "pod-disruption-annotation", getBoolEnv(podDisruptionAnnotationConfigKey, podDisruptionAnnotationDefault), "If true, the pods of a node are annotated with the kind and the deadline of an interruption event which drains it as soon as it is accepted, with the aws-node-termination-handler/disruption annotation.")
+	flag.BoolVar(&config.WaitForVolumeDetachment, "wait-for-volume-detachment", getBoolEnv(waitForVolumeDetachmentConfigKey, waitForVolumeDetachmentDefault), "If true, the ASG termination lifecycle action of a drained node is completed once the VolumeAttachments of the node, other than those of its DaemonSet pods, are detached, or once volume-detachment-timeout expires.")
+	flag.IntVar(&config.VolumeDetachmentTimeout, "volume-detachment-timeout", getIntEnv(volumeDetachmentTimeoutConfigKey, volumeDetachmentTimeoutDefault), "The period of time in seconds to wait for the volumes of a drained node to be detached when wait-for-volume-detachment is true.")
+	flag.BoolVar(&config.VolumeDetachmentCheckNodeStatus, "volume-detachment-check-node-status", getBoolEnv(volumeDetachmentCheckNodeStatusKey, volumeDetachmentCheckNodeStatusDefault), "If true, the volumes attached in the status of a drained node must be detached too when wait-for-volume-detachment is true.")
+	flag.BoolVar(&config.WaitForWorkloadReadiness, "wait-for-workload-readiness", getBoolEnv(waitForWorkloadReadinessConfigKey, waitForWorkloadReadinessDefault), "If true, the ASG termination lifecycle action of a drained node is completed once the Deployments, StatefulSets and ReplicaSets of the evicted pods have their desired number of ready replicas, while lifecycle heartbeats keep being sent.")
+	flag.IntVar(&config.WorkloadReadinessTimeout, "workload-readiness-timeout", getIntEnv(workloadReadinessTimeoutConfigKey, workloadReadinessTimeoutDefault), "The period of time in seconds to wait for the workloads of the evicted pods to be ready when wait-for-workload-readiness is true.")
+	flag.StringVar(&config.WorkloadReadinessFallback, "workload-readiness-fallback", getEnv(workloadReadinessFallbackConfigKey, workloadReadinessFallbackDefault), "What happens when the workloads of the evicted pods are not ready before workload-readiness-timeout expires: complete (complete the lifecycle action anyway) or retry (fail the event so that it is retried with the drain retry policy, which requires workload-readiness-timeout to be less than drain-retry-deadline).")
+	flag.StringVar(&config.MarkingPolicy, "marking-policy", getEnv(markingPolicyConfigKey, markingPolicyDefault), "Semicolon-separated rules setting the taints, labels and annotations of the node of an event of a kind, in place of the taint of the kind set by taint-node, e.g. REBALANCE_RECOMMENDATION:taint/example.com/rebalance={{ .EventID }}:NoSchedule,label/example.com/draining=true;STATE_CHANGE:taint/example.com/terminating=true:NoExecute,annotation/example.com/event={{ .Description }}. Values are templates of the interruption event, a taint without an effect takes the taint-effect.")
+	flag.BoolVar(&config.NodeTerminationAnnotation, "node-termination-annotation", getBoolEnv(nodeTerminationAnnotationConfigKey, nodeTerminationAnnotationDefault), "If true, the node of an accepted interruption event is annotated with the ID, the kind, the monitor, the expected termination time, the deadline and the processing phase of the event, with the aws-node-termination-handler/termination annotation, which is updated as the event is processed.")
+	flag.StringVar(&config.NodeResolution, "node-resolution", getEnv(nodeResolutionConfigKey, nodeResolutionDefault), "Comma-separated strategies tried in order to find the Kubernetes node of an EC2 instance in Queue Processor mode: provider-id, private-dns, instance-id-label, tag or hostname-label. Empty tries private-dns, hostname-label, instance-id-label and provider-id, with provider-id first if use-provider-id is true.")
+	flag.StringVar(&config.NodeResolutionDNSSuffix, "node-resolution-dns-suffix", getEnv(nodeResolutionDNSSuffixConfigKey, nodeResolutionDNSSuffixDefault), "The domain the private-dns node resolution strategy appends to the host part of the private DNS name of an instance, for clusters whose nodes are named with a custom domain.")
+	flag.StringVar(&config.NodeResolutionInstanceIDLabel, "node-resolution-instance-id-label", getEnv(nodeResolutionInstanceIDLabelConfigKey, nodeResolutionInstanceIDLabelDefault), "The node label whose value is the EC2 instance ID, for the instance-id-label node resolution strategy.")
+	flag.StringVar(&config.NodeResolutionTag, "node-resolution-tag", getEnv(nodeResolutionTagConfigKey, nodeResolutionTagDefault), "The EC2 instance tag whose value is the node name, for the tag node resolution strategy.")
+	flag.BoolVar(&config.EnableInformerCache, "enable-informer-cache", getBoolEnv(enableInformerCacheConfigKey, enableInformerCacheDefault), "If true, serve nodes and pods, and volume attachments if wait-for-volume-detachment is set, from a cache kept in sync by watching the API server instead of listing them on every lookup. Only used in Queue Processor mode.")
+	flag.BoolVar(&config.UseProviderId, "use-provider-id", getBoolEnv(useProviderIdConfigKey, useProviderIdDefault), "If true, fetch node name through Kubernetes node spec ProviderID instead of AWS event PrivateDnsHostname, by trying the provider-id node resolution strategy first when node-resolution is empty.")
 	flag.IntVar(&config.CompleteLifecycleActionDelaySeconds, "complete-lifecycle-action-delay-seconds", getIntEnv(completeLifecycleActionDelaySecondsKey, -1), "Delay completing the Autoscaling lifecycle action after a node has been drained.")
 	flag.BoolVar(&config.DeleteSqsMsgIfNodeNotFound, "delete-sqs-msg-if-node-not-found", getBoolEnv(deleteSqsMsgIfNodeNotFoundKey, false), "If true, delete SQS Messages from the SQS Queue if the targeted node(s) are not found.")
 	flag.BoolVar(&config.UseAPIServerCacheToListPods, "use-apiserver-cache", getBoolEnv(useAPIServerCache, false), "If true, leverage the k8s apiserver's index on pod's spec.nodeName to list pods on a node, instead of doing an etcd quorum read.")
@@ -319,6 +581,145 @@ func ParseCliArgs() (config Config, err error) {
 		return config, fmt.Errorf("invalid heartbeat configuration: heartbeat-interval should be less than or equal to heartbeat-until")
 	}
 
+	if config.ShutdownGracePeriod < 0 {
+		return config, fmt.Errorf("invalid shutdown-grace-period passed: %d  Should be greater than or equal to 0", config.ShutdownGracePeriod)
+	}
+
+	if config.EnableLeaderElection {
+		if !config.EnableSQSTerminationDraining {
+			return config, fmt.Errorf("currently using IMDS mode. Leader election is only supported for Queue Processor mode")
+		}
+		if config.LeaderElectionNamespace == "" {
+			config.LeaderElectionNamespace = config.PodNamespace
+		}
+		if config.LeaderElectionNamespace == "" {
+			return config, fmt.Errorf("invalid leader election configuration: leader-election-namespace or pod-namespace is required when leader election is enabled")
+		}
+		if config.LeaderElectionLeaseName == "" {
+			return config, fmt.Errorf("invalid leader election configuration: leader-election-lease-name must not be empty")
+		}
+		if config.LeaderElectionRetryPeriod <= 0 || config.LeaderElectionRenewDeadline <= config.LeaderElectionRetryPeriod || config.LeaderElectionLeaseDuration <= config.LeaderElectionRenewDeadline {
+			return config, fmt.Errorf("invalid leader election configuration: expected 0 < leader-election-retry-period (%d) < leader-election-renew-deadline (%d) < leader-election-lease-duration (%d)",
+				config.LeaderElectionRetryPeriod, config.LeaderElectionRenewDeadline, config.LeaderElectionLeaseDuration)
+		}
+	}
+
+	if config.MonitorMaxBackoff < 2 {
+		return config, fmt.Errorf("invalid monitor-max-backoff passed: %d  Should be greater than or equal to 2 seconds", config.MonitorMaxBackoff)
+	}
+	if config.MonitorFailureThreshold < 1 {
+		return config, fmt.Errorf("invalid monitor-failure-threshold passed: %d  Should be greater than or equal to 1", config.MonitorFailureThreshold)
+	}
+	if config.MonitorCircuitCooldown < 1 {
+		return config, fmt.Errorf("invalid monitor-circuit-cooldown passed: %d  Should be greater than or equal to 1 second", config.MonitorCircuitCooldown)
+	}
+
+	if _, err := ParseEventKindSeverity(config.EventKindSeverity); err != nil {
+		return config, fmt.Errorf("invalid event-kind-severity passed: %w", err)
+	}
+
+	switch config.StoreBackend {
+	case StoreBackendMemory:
+	case StoreBackendConfigMap:
+		if !config.EnableSQSTerminationDraining {
+			return config, fmt.Errorf("currently using IMDS mode. The configmap store backend is only supported for Queue Processor mode, use the file store backend instead")
+		}
+		if config.StoreConfigMapNamespace == "" {
+			config.StoreConfigMapNamespace = config.PodNamespace
+		}
+		if config.StoreConfigMapNamespace == "" || config.StoreConfigMapName == "" {
+			return config, fmt.Errorf("invalid store configuration: store-configmap-name and store-configmap-namespace or pod-namespace are required by the configmap store backend")
+		}
+	case StoreBackendFile:
+		if config.StoreFilePath == "" {
+			return config, fmt.Errorf("invalid store configuration: store-file-path is required by the file store backend")
+		}
+	default:
+		return config, fmt.Errorf("invalid store-backend passed: %s  Should be one of %s, %s or %s", config.StoreBackend, StoreBackendMemory, StoreBackendConfigMap, StoreBackendFile)
+	}
+
+	if config.DrainRetryMaxAttempts < 1 {
+		return config, fmt.Errorf("invalid drain-retry-max-attempts passed: %d  Should be greater than or equal to 1", config.DrainRetryMaxAttempts)
+	}
+	if config.DrainRetryInitialBackoff < 1 || config.DrainRetryMaxBackoff < config.DrainRetryInitialBackoff {
+		return config, fmt.Errorf("invalid drain retry backoff passed: expected 1 <= drain-retry-initial-backoff (%d) <= drain-retry-max-backoff (%d)", config.DrainRetryInitialBackoff, config.DrainRetryMaxBackoff)
+	}
+	if config.DrainRetryDeadline < 0 {
+		return config, fmt.Errorf("invalid drain-retry-deadline passed: %d  Should be greater than or equal to 0", config.DrainRetryDeadline)
+	}
+
+	if config.IngestionQueueCapacity < 1 {
+		return config, fmt.Errorf("invalid ingestion-queue-capacity passed: %d  Should be greater than or equal to 1", config.IngestionQueueCapacity)
+	}
+	if config.IngestionQueueOverflowPolicy != IngestionOverflowBlock && config.IngestionQueueOverflowPolicy != IngestionOverflowReject {
+		return config, fmt.Errorf("invalid ingestion-queue-overflow-policy passed: %s  Should be one of %s or %s", config.IngestionQueueOverflowPolicy, IngestionOverflowBlock, IngestionOverflowReject)
+	}
+
+	_, percent, err := ParseDisruptionBudget(config.DisruptionBudget)
+	if err != nil {
+		return config, fmt.Errorf("invalid disruption-budget passed: %w", err)
+	}
+	if config.DisruptionBudget != "" && !config.EnableSQSTerminationDraining {
+		// every replica of the DaemonSet would count the disrupted nodes of the whole cluster on its own
+		return config, fmt.Errorf("currently using IMDS mode. The disruption budget is only supported for Queue Processor mode")
+	}
+	switch config.DisruptionBudgetScope {
+	case DisruptionScopeCluster, DisruptionScopeZone:
+	case DisruptionScopeASG:
+		if percent {
+			// the size of an ASG is not known from the cluster
+			return config, fmt.Errorf("invalid disruption-budget passed: %s  A percentage is not supported with the %s scope", config.DisruptionBudget, DisruptionScopeASG)
+		}
+	case DisruptionScopeLabel:
+		if config.DisruptionBudgetScopeLabel == "" {
+			return config, fmt.Errorf("disruption-budget-scope-label must be set when disruption-budget-scope is %s", DisruptionScopeLabel)
+		}
+	default:
+		return config, fmt.Errorf("invalid disruption-budget-scope passed: %s  Should be one of %s, %s, %s or %s", config.DisruptionBudgetScope, DisruptionScopeCluster, DisruptionScopeASG, DisruptionScopeZone, DisruptionScopeLabel)
+	}
+	if config.DisruptionBudgetMaxDeferral < 0 {
+		return config, fmt.Errorf("invalid disruption-budget-max-deferral passed: %d  Should be greater than or equal to 0", config.DisruptionBudgetMaxDeferral)
+	}
+	if config.StormModeThreshold < 0 {
+		return config, fmt.Errorf("invalid storm-mode-threshold passed: %d  Should be greater than or equal to 0", config.StormModeThreshold)
+	}
+	if config.EvictionMaxParallelism < 1 {
+		return config, fmt.Errorf("invalid eviction-max-parallelism passed: %d  Should be greater than or equal to 1", config.EvictionMaxParallelism)
+	}
+	switch config.DrainWaveOrder {
+	case DrainWaveOrderNone, DrainWaveOrderPriority, DrainWaveOrderAnnotation, DrainWaveOrderWorkload:
+	default:
+		return config, fmt.Errorf("invalid drain-wave-order passed: %s  Should be one of %s, %s, %s or %s", config.DrainWaveOrder, DrainWaveOrderNone, DrainWaveOrderPriority, DrainWaveOrderAnnotation, DrainWaveOrderWorkload)
+	}
+	if config.PDBEscalationThreshold < 0 || config.PDBEscalationThreshold > 100 {
+		return config, fmt.Errorf("invalid pdb-escalation-threshold passed: %d  Should be between 0 and 100", config.PDBEscalationThreshold)
+	}
+	if config.VolumeDetachmentTimeout < 1 {
+		return config, fmt.Errorf("invalid volume-detachment-timeout passed: %d  Should be greater than or equal to 1", config.VolumeDetachmentTimeout)
+	}
+	if config.WorkloadReadinessTimeout < 1 {
+		return config, fmt.Errorf("invalid workload-readiness-timeout passed: %d  Should be greater than or equal to 1", config.WorkloadReadinessTimeout)
+	}
+	if config.WorkloadReadinessFallback != WorkloadReadinessFallbackComplete && config.WorkloadReadinessFallback != WorkloadReadinessFallbackRetry {
+		return config, fmt.Errorf("invalid workload-readiness-fallback passed: %s  Should be one of %s or %s", config.WorkloadReadinessFallback, WorkloadReadinessFallbackComplete, WorkloadReadinessFallbackRetry)
+	}
+	if config.WaitForWorkloadReadiness && config.WorkloadReadinessFallback == WorkloadReadinessFallbackRetry && config.WorkloadReadinessTimeout >= config.DrainRetryDeadline {
+		// the event fails once the timeout expires, by which time it can no longer be retried
+		return config, fmt.Errorf("invalid workload-readiness-timeout passed: %d  Should be less than drain-retry-deadline (%d) when workload-readiness-fallback is %s", config.WorkloadReadinessTimeout, config.DrainRetryDeadline, WorkloadReadinessFallbackRetry)
+	}
+	if _, err := ParseDataLossPolicy(config.PodDataLossPolicy); err != nil {
+		return config, fmt.Errorf("invalid pod-data-loss-policy passed: %w", err)
+	}
+	if _, err := ParseDataLossPolicyOverrides(config.PodDataLossPolicyOverrides); err != nil {
+		return config, fmt.Errorf("invalid pod-data-loss-policy-overrides passed: %w", err)
+	}
+	if _, err := ParseMarkingPolicy(config.MarkingPolicy); err != nil {
+		return config, fmt.Errorf("invalid marking-policy passed: %w", err)
+	}
+	if _, err := config.NodeResolutionStrategies(); err != nil {
+		return config, fmt.Errorf("invalid node-resolution passed: %w", err)
+	}
+
 	if config.EnableSQSTerminationDraining && (config.SqsMsgVisibilityTimeoutSec <= 0 || config.SqsMsgVisibilityTimeoutSec >= 120) {
 		return config, fmt.Errorf("invalid SqsMsgVisibilityTimeoutSec configuration: SqsMsgVisibilityTimeoutSec valid range from 1 to 119")
 	}
@@ -391,6 +792,53 @@ func (c Config) PrintJsonConfigArgs() {
 		Int("heartbeat_interval", c.HeartbeatInterval).
 		Int("heartbeat_until", c.HeartbeatUntil).
 		Int("sqs_msg_visibility_timeout_sec", c.SqsMsgVisibilityTimeoutSec).
+		Int("shutdown_grace_period", c.ShutdownGracePeriod).
+		Bool("enable_leader_election", c.EnableLeaderElection).
+		Str("leader_election_lease_name", c.LeaderElectionLeaseName).
+		Str("leader_election_namespace", c.LeaderElectionNamespace).
+		Int("leader_election_lease_duration", c.LeaderElectionLeaseDuration).
+		Int("leader_election_renew_deadline", c.LeaderElectionRenewDeadline).
+		Int("leader_election_retry_period", c.LeaderElectionRetryPeriod).
+		Int("monitor_max_backoff", c.MonitorMaxBackoff).
+		Int("monitor_failure_threshold", c.MonitorFailureThreshold).
+		Int("monitor_circuit_cooldown", c.MonitorCircuitCooldown).
+		Bool("exit_on_monitor_failure", c.ExitOnMonitorFailure).
+		Str("event_kind_severity", c.EventKindSeverity).
+		Str("store_backend", c.StoreBackend).
+		Str("store_configmap_name", c.StoreConfigMapName).
+		Str("store_configmap_namespace", c.StoreConfigMapNamespace).
+		Str("store_file_path", c.StoreFilePath).
+		Int("drain_retry_max_attempts", c.DrainRetryMaxAttempts).
+		Int("drain_retry_initial_backoff", c.DrainRetryInitialBackoff).
+		Int("drain_retry_max_backoff", c.DrainRetryMaxBackoff).
+		Int("drain_retry_deadline", c.DrainRetryDeadline).
+		Int("ingestion_queue_capacity", c.IngestionQueueCapacity).
+		Str("ingestion_queue_overflow_policy", c.IngestionQueueOverflowPolicy).
+		Str("disruption_budget", c.DisruptionBudget).
+		Str("disruption_budget_scope", c.DisruptionBudgetScope).
+		Str("disruption_budget_scope_label", c.DisruptionBudgetScopeLabel).
+		Int("disruption_budget_max_deferral", c.DisruptionBudgetMaxDeferral).
+		Int("storm_mode_threshold", c.StormModeThreshold).
+		Int("eviction_max_parallelism", c.EvictionMaxParallelism).
+		Str("drain_wave_order", c.DrainWaveOrder).
+		Int("pdb_escalation_threshold", c.PDBEscalationThreshold).
+		Str("pod_data_loss_policy", c.PodDataLossPolicy).
+		Str("pod_data_loss_policy_overrides", c.PodDataLossPolicyOverrides).
+		Bool("pod_disruption_condition", c.PodDisruptionCondition).
+		Bool("pod_disruption_annotation", c.PodDisruptionAnnotation).
+		Bool("wait_for_volume_detachment", c.WaitForVolumeDetachment).
+		Int("volume_detachment_timeout", c.VolumeDetachmentTimeout).
+		Bool("volume_detachment_check_node_status", c.VolumeDetachmentCheckNodeStatus).
+		Bool("wait_for_workload_readiness", c.WaitForWorkloadReadiness).
+		Int("workload_readiness_timeout", c.WorkloadReadinessTimeout).
+		Str("workload_readiness_fallback", c.WorkloadReadinessFallback).
+		Str("marking_policy", c.MarkingPolicy).
+		Bool("node_termination_annotation", c.NodeTerminationAnnotation).
+		Str("node_resolution", c.NodeResolution).
+		Str("node_resolution_dns_suffix", c.NodeResolutionDNSSuffix).
+		Str("node_resolution_instance_id_label", c.NodeResolutionInstanceIDLabel).
+		Str("node_resolution_tag", c.NodeResolutionTag).
+		Bool("enable_informer_cache", c.EnableInformerCache).
 		Msg("aws-node-termination-handler arguments")
 }
 
@@ -446,7 +894,54 @@ func (c Config) PrintHumanConfigArgs() {
 			"\tuse-apiserver-cache: %t,\n"+
 			"\theartbeat-interval: %d,\n"+
 			"\theartbeat-until: %d\n"+
-			"\tsqs-msg-visibility-timeout-sec: %d\n",
+			"\tsqs-msg-visibility-timeout-sec: %d,\n"+
+			"\tshutdown-grace-period: %d,\n"+
+			"\tenable-leader-election: %t,\n"+
+			"\tleader-election-lease-name: %s,\n"+
+			"\tleader-election-namespace: %s,\n"+
+			"\tleader-election-lease-duration: %d,\n"+
+			"\tleader-election-renew-deadline: %d,\n"+
+			"\tleader-election-retry-period: %d,\n"+
+			"\tmonitor-max-backoff: %d,\n"+
+			"\tmonitor-failure-threshold: %d,\n"+
+			"\tmonitor-circuit-cooldown: %d,\n"+
+			"\texit-on-monitor-failure: %t,\n"+
+			"\tevent-kind-severity: %s,\n"+
+			"\tstore-backend: %s,\n"+
+			"\tstore-configmap-name: %s,\n"+
+			"\tstore-configmap-namespace: %s,\n"+
+			"\tstore-file-path: %s,\n"+
+			"\tdrain-retry-max-attempts: %d,\n"+
+			"\tdrain-retry-initial-backoff: %d,\n"+
+			"\tdrain-retry-max-backoff: %d,\n"+
+			"\tdrain-retry-deadline: %d,\n"+
+			"\tingestion-queue-capacity: %d,\n"+
+			"\tingestion-queue-overflow-policy: %s,\n"+
+			"\tdisruption-budget: %s,\n"+
+			"\tdisruption-budget-scope: %s,\n"+
+			"\tdisruption-budget-scope-label: %s,\n"+
+			"\tdisruption-budget-max-deferral: %d,\n"+
+			"\tstorm-mode-threshold: %d,\n"+
+			"\teviction-max-parallelism: %d,\n"+
+			"\tdrain-wave-order: %s,\n"+
+			"\tpdb-escalation-threshold: %d,\n"+
+			"\tpod-data-loss-policy: %s,\n"+
+			"\tpod-data-loss-policy-overrides: %s,\n"+
+			"\tpod-disruption-condition: %t,\n"+
+			"\tpod-disruption-annotation: %t,\n"+
+			"\twait-for-volume-detachment: %t,\n"+
+			"\tvolume-detachment-timeout: %d,\n"+
+			"\tvolume-detachment-check-node-status: %t,\n"+
+			"\twait-for-workload-readiness: %t,\n"+
+			"\tworkload-readiness-timeout: %d,\n"+
+			"\tworkload-readiness-fallback: %s,\n"+
+			"\tmarking-policy: %s,\n"+
+			"\tnode-termination-annotation: %t,\n"+
+			"\tnode-resolution: %s,\n"+
+			"\tnode-resolution-dns-suffix: %s,\n"+
+			"\tnode-resolution-instance-id-label: %s,\n"+
+			"\tnode-resolution-tag: %s,\n"+
+			"\tenable-informer-cache: %t\n",
 		c.DryRun,
 		c.NodeName,
 		c.PodName,
@@ -491,9 +986,278 @@ func (c Config) PrintHumanConfigArgs() {
 		c.HeartbeatInterval,
 		c.HeartbeatUntil,
 		c.SqsMsgVisibilityTimeoutSec,
+		c.ShutdownGracePeriod,
+		c.EnableLeaderElection,
+		c.LeaderElectionLeaseName,
+		c.LeaderElectionNamespace,
+		c.LeaderElectionLeaseDuration,
+		c.LeaderElectionRenewDeadline,
+		c.LeaderElectionRetryPeriod,
+		c.MonitorMaxBackoff,
+		c.MonitorFailureThreshold,
+		c.MonitorCircuitCooldown,
+		c.ExitOnMonitorFailure,
+		c.EventKindSeverity,
+		c.StoreBackend,
+		c.StoreConfigMapName,
+		c.StoreConfigMapNamespace,
+		c.StoreFilePath,
+		c.DrainRetryMaxAttempts,
+		c.DrainRetryInitialBackoff,
+		c.DrainRetryMaxBackoff,
+		c.DrainRetryDeadline,
+		c.IngestionQueueCapacity,
+		c.IngestionQueueOverflowPolicy,
+		c.DisruptionBudget,
+		c.DisruptionBudgetScope,
+		c.DisruptionBudgetScopeLabel,
+		c.DisruptionBudgetMaxDeferral,
+		c.StormModeThreshold,
+		c.EvictionMaxParallelism,
+		c.DrainWaveOrder,
+		c.PDBEscalationThreshold,
+		c.PodDataLossPolicy,
+		c.PodDataLossPolicyOverrides,
+		c.PodDisruptionCondition,
+		c.PodDisruptionAnnotation,
+		c.WaitForVolumeDetachment,
+		c.VolumeDetachmentTimeout,
+		c.VolumeDetachmentCheckNodeStatus,
+		c.WaitForWorkloadReadiness,
+		c.WorkloadReadinessTimeout,
+		c.WorkloadReadinessFallback,
+		c.MarkingPolicy,
+		c.NodeTerminationAnnotation,
+		c.NodeResolution,
+		c.NodeResolutionDNSSuffix,
+		c.NodeResolutionInstanceIDLabel,
+		c.NodeResolutionTag,
+		c.EnableInformerCache,
 	)
 }
 
+// ParseEventKindSeverity parses a comma-separated list of kind=severity pairs
+func ParseEventKindSeverity(kindSeverity string) (map[string]int, error) {
+	severities := map[string]int{}
+	if strings.TrimSpace(kindSeverity) == "" {
+		return severities, nil
+	}
+	for _, pair := range strings.Split(kindSeverity, ",") {
+		kind, severity, found := strings.Cut(pair, "=")
+		kind = strings.TrimSpace(kind)
+		if !found || kind == "" {
+			return nil, fmt.Errorf("expected kind=severity but got %q", pair)
+		}
+		value, err := strconv.Atoi(strings.TrimSpace(severity))
+		if err != nil {
+			return nil, fmt.Errorf("severity of kind %s must be an integer: %w", kind, err)
+		}
+		severities[kind] = value
+	}
+	return severities, nil
+}
+
+// ParseDisruptionBudget parses a disruption budget, either a count of nodes or a percentage of nodes such as 10%,
+// and returns it and true if it is a percentage. An empty budget is returned as -1, meaning no budget.
+func ParseDisruptionBudget(budget string) (int, bool, error) {
+	budget = strings.TrimSpace(budget)
+	if budget == "" {
+		return -1, false, nil
+	}
+	value, percent := strings.CutSuffix(budget, "%")
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, false, fmt.Errorf("expected a count or a percentage of nodes but got %q", budget)
+	}
+	if n < 0 || (percent && n > 100) {
+		return 0, false, fmt.Errorf("disruption budget %q is out of range", budget)
+	}
+	return n, percent, nil
+}
+
+// ParseDataLossPolicy parses a comma-separated list of kind=action pairs
+func ParseDataLossPolicy(policy string) (map[string]string, error) {
+	actions := map[string]string{}
+	if strings.TrimSpace(policy) == "" {
+		return actions, nil
+	}
+	for _, pair := range strings.Split(policy, ",") {
+		kind, action, found := strings.Cut(pair, "=")
+		kind, action = strings.TrimSpace(kind), strings.TrimSpace(action)
+		if !found {
+			return nil, fmt.Errorf("expected kind=action but got %q", pair)
+		}
+		switch kind {
+		case DataLossOrphan, DataLossEmptyDir, DataLossLocalPV:
+		default:
+			return nil, fmt.Errorf("unknown pod kind %q, should be one of %s, %s or %s", kind, DataLossOrphan, DataLossEmptyDir, DataLossLocalPV)
+		}
+		switch action {
+		case DataLossForce, DataLossSkip, DataLossHold:
+		default:
+			return nil, fmt.Errorf("unknown action %q for kind %s, should be one of %s, %s or %s", action, kind, DataLossForce, DataLossSkip, DataLossHold)
+		}
+		actions[kind] = action
+	}
+	return actions, nil
+}
+
+// DataLossPolicyRule overrides the data loss policy for the pods of a namespace or the pods matching a label selector
+type DataLossPolicyRule struct {
+	// Namespace is the namespace of the pods the rule applies to, if Selector is nil
+	Namespace string
+	Selector  labels.Selector
+	Actions   map[string]string
+}
+
+// Matches returns true if the rule applies to a pod of the namespace with the labels
+func (r DataLossPolicyRule) Matches(namespace string, podLabels map[string]string) bool {
+	if r.Selector != nil {
+		return r.Selector.Matches(labels.Set(podLabels))
+	}
+	return r.Namespace == namespace
+}
+
+// ParseDataLossPolicyOverrides parses a semicolon-separated list of rules, each either namespace/<namespace>:<policy>
+// or selector/<label selector>:<policy>, where the policy is parsed by ParseDataLossPolicy
+func ParseDataLossPolicyOverrides(overrides string) ([]DataLossPolicyRule, error) {
+	var rules []DataLossPolicyRule
+	for _, rawRule := range strings.Split(overrides, ";") {
+		if strings.TrimSpace(rawRule) == "" {
+			continue
+		}
+		scope, policy, found := strings.Cut(rawRule, ":")
+		if !found {
+			return nil, fmt.Errorf("expected namespace/<namespace>:<policy> or selector/<label selector>:<policy> but got %q", rawRule)
+		}
+		var rule DataLossPolicyRule
+		scopeKind, scopeValue, _ := strings.Cut(strings.TrimSpace(scope), "/")
+		switch scopeKind {
+		case "namespace":
+			if scopeValue == "" {
+				return nil, fmt.Errorf("missing namespace in %q", rawRule)
+			}
+			rule.Namespace = scopeValue
+		case "selector":
+			selector, err := labels.Parse(scopeValue)
+			if err != nil {
+				return nil, fmt.Errorf("invalid label selector in %q: %w", rawRule, err)
+			}
+			rule.Selector = selector
+		default:
+			return nil, fmt.Errorf("expected namespace/<namespace>:<policy> or selector/<label selector>:<policy> but got %q", rawRule)
+		}
+		actions, err := ParseDataLossPolicy(policy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy in %q: %w", rawRule, err)
+		}
+		rule.Actions = actions
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// MarkingTaint is a taint set on the node of an event, whose value is a template of the event
+type MarkingTaint struct {
+	Key   string
+	Value *template.Template
+	// Effect is empty if the taint takes the taint-effect
+	Effect string
+}
+
+// MarkingRule lists the taints, labels and annotations set on the node of an event of a kind, whose values are templates of the event
+type MarkingRule struct {
+	Taints      []MarkingTaint
+	Labels      map[string]*template.Template
+	Annotations map[string]*template.Template
+}
+
+// NodeResolutionStrategies returns the node resolution strategies in the order they are tried
+func (c Config) NodeResolutionStrategies() ([]string, error) {
+	if strings.TrimSpace(c.NodeResolution) == "" {
+		if c.UseProviderId {
+			return []string{NodeResolutionProviderID, NodeResolutionPrivateDNS, NodeResolutionHostnameLabel, NodeResolutionInstanceIDLabel}, nil
+		}
+		return []string{NodeResolutionPrivateDNS, NodeResolutionHostnameLabel, NodeResolutionInstanceIDLabel, NodeResolutionProviderID}, nil
+	}
+	var strategies []string
+	for _, strategy := range strings.Split(c.NodeResolution, ",") {
+		strategy = strings.TrimSpace(strategy)
+		switch strategy {
+		case NodeResolutionProviderID, NodeResolutionPrivateDNS, NodeResolutionInstanceIDLabel, NodeResolutionHostnameLabel:
+		case NodeResolutionTag:
+			if c.NodeResolutionTag == "" {
+				return nil, fmt.Errorf("the %s strategy needs node-resolution-tag", strategy)
+			}
+		default:
+			return nil, fmt.Errorf("unknown strategy %q, should be one of %s, %s, %s, %s or %s", strategy, NodeResolutionProviderID,
+				NodeResolutionPrivateDNS, NodeResolutionInstanceIDLabel, NodeResolutionTag, NodeResolutionHostnameLabel)
+		}
+		if slices.Contains(strategies, strategy) {
+			return nil, fmt.Errorf("duplicate strategy %q", strategy)
+		}
+		strategies = append(strategies, strategy)
+	}
+	return strategies, nil
+}
+
+// ParseMarkingPolicy parses a semicolon-separated list of rules <kind>:<marks>, where the marks are a comma-separated list
+// of taint/<key>=<value>[:<effect>], label/<key>=<value> or annotation/<key>=<value>, and returns the rule of each kind
+func ParseMarkingPolicy(policy string) (map[string]MarkingRule, error) {
+	rules := map[string]MarkingRule{}
+	for _, rawRule := range strings.Split(policy, ";") {
+		if strings.TrimSpace(rawRule) == "" {
+			continue
+		}
+		kind, marks, found := strings.Cut(rawRule, ":")
+		kind = strings.TrimSpace(kind)
+		if !found || kind == "" {
+			return nil, fmt.Errorf("expected <kind>:<marks> but got %q", rawRule)
+		}
+		if _, ok := rules[kind]; ok {
+			return nil, fmt.Errorf("kind %s has more than one rule", kind)
+		}
+		rule := MarkingRule{Labels: map[string]*template.Template{}, Annotations: map[string]*template.Template{}}
+		for _, mark := range strings.Split(marks, ",") {
+			markKind, keyValue, _ := strings.Cut(strings.TrimSpace(mark), "/")
+			key, value, found := strings.Cut(keyValue, "=")
+			if !found {
+				return nil, fmt.Errorf("expected taint/<key>=<value>[:<effect>], label/<key>=<value> or annotation/<key>=<value> but got %q in the rule of kind %s", mark, kind)
+			}
+			if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+				return nil, fmt.Errorf("invalid key %q in the rule of kind %s: %s", key, kind, strings.Join(errs, ", "))
+			}
+			effect := ""
+			if markKind == "taint" {
+				if i := strings.LastIndex(value, ":"); i >= 0 {
+					value, effect = value[:i], value[i+1:]
+					switch effect {
+					case "NoSchedule", "PreferNoSchedule", "NoExecute":
+					default:
+						return nil, fmt.Errorf("unknown taint effect %q in the rule of kind %s, should be one of NoSchedule, PreferNoSchedule or NoExecute", effect, kind)
+					}
+				}
+			}
+			valueTemplate, err := template.New(key).Funcs(sprig.TxtFuncMap()).Parse(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of %s in the rule of kind %s: %w", key, kind, err)
+			}
+			switch markKind {
+			case "taint":
+				rule.Taints = append(rule.Taints, MarkingTaint{Key: key, Value: valueTemplate, Effect: effect})
+			case "label":
+				rule.Labels[key] = valueTemplate
+			case "annotation":
+				rule.Annotations[key] = valueTemplate
+			default:
+				return nil, fmt.Errorf("expected taint/<key>=<value>[:<effect>], label/<key>=<value> or annotation/<key>=<value> but got %q in the rule of kind %s", mark, kind)
+			}
+		}
+		rules[kind] = rule
+	}
+	return rules, nil
+}
+
 // Get env var or default
 func getEnv(key string, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
@@ -294,6 +294,372 @@ func TestParseCliArgsAWSSession(t *testing.T) {
 	h.Assert(t, nthConfig.AWSRegion == "us-weast-1", "Should find region as us-weast-1")
 }
 
+func TestParseCliArgsLeaderElection(t *testing.T) {
+	resetFlagsForTest()
+	t.Setenv("NODE_NAME", "node")
+	t.Setenv("ENABLE_SQS_TERMINATION_DRAINING", "true")
+	t.Setenv("ENABLE_LEADER_ELECTION", "true")
+	t.Setenv("NAMESPACE", "kube-system")
+	nthConfig, err := config.ParseCliArgs()
+	h.Ok(t, err)
+	h.Equals(t, true, nthConfig.EnableLeaderElection)
+	h.Equals(t, "aws-node-termination-handler", nthConfig.LeaderElectionLeaseName)
+	h.Equals(t, "kube-system", nthConfig.LeaderElectionNamespace)
+	h.Equals(t, 15, nthConfig.LeaderElectionLeaseDuration)
+	h.Equals(t, 10, nthConfig.LeaderElectionRenewDeadline)
+	h.Equals(t, 2, nthConfig.LeaderElectionRetryPeriod)
+}
+
+func TestParseCliArgsLeaderElectionFailure(t *testing.T) {
+	resetFlagsForTest()
+	t.Setenv("NODE_NAME", "node")
+	t.Setenv("ENABLE_LEADER_ELECTION", "true")
+	t.Setenv("NAMESPACE", "kube-system")
+	_, err := config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when leader election is enabled in IMDS mode")
+
+	resetFlagsForTest()
+	t.Setenv("ENABLE_SQS_TERMINATION_DRAINING", "true")
+	t.Setenv("LEADER_ELECTION_RENEW_DEADLINE", "20")
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when renew deadline exceeds the lease duration")
+}
+
+func TestParseCliArgsStoreBackend(t *testing.T) {
+	resetFlagsForTest()
+	t.Setenv("NODE_NAME", "node")
+	t.Setenv("NAMESPACE", "kube-system")
+	t.Setenv("ENABLE_SQS_TERMINATION_DRAINING", "true")
+	t.Setenv("STORE_BACKEND", "configmap")
+	nthConfig, err := config.ParseCliArgs()
+	h.Ok(t, err)
+	h.Equals(t, config.StoreBackendConfigMap, nthConfig.StoreBackend)
+	h.Equals(t, "aws-node-termination-handler-state", nthConfig.StoreConfigMapName)
+	h.Equals(t, "kube-system", nthConfig.StoreConfigMapNamespace)
+
+	resetFlagsForTest()
+	t.Setenv("ENABLE_SQS_TERMINATION_DRAINING", "false")
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when the configmap store backend is used in IMDS mode")
+
+	resetFlagsForTest()
+	t.Setenv("STORE_BACKEND", "etcd")
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error for an unknown store backend")
+}
+
+func TestParseCliArgsDrainRetry(t *testing.T) {
+	resetFlagsForTest()
+	t.Setenv("NODE_NAME", "node")
+	nthConfig, err := config.ParseCliArgs()
+	h.Ok(t, err)
+	h.Equals(t, 3, nthConfig.DrainRetryMaxAttempts)
+	h.Equals(t, 2, nthConfig.DrainRetryInitialBackoff)
+	h.Equals(t, 30, nthConfig.DrainRetryMaxBackoff)
+	h.Equals(t, 120, nthConfig.DrainRetryDeadline)
+
+	resetFlagsForTest()
+	t.Setenv("DRAIN_RETRY_MAX_ATTEMPTS", "0")
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when drain-retry-max-attempts is less than 1")
+
+	resetFlagsForTest()
+	t.Setenv("DRAIN_RETRY_MAX_ATTEMPTS", "5")
+	t.Setenv("DRAIN_RETRY_INITIAL_BACKOFF", "60")
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when drain-retry-initial-backoff is greater than drain-retry-max-backoff")
+}
+
+func TestParseCliArgsIngestionQueue(t *testing.T) {
+	resetFlagsForTest()
+	t.Setenv("NODE_NAME", "node")
+	nthConfig, err := config.ParseCliArgs()
+	h.Ok(t, err)
+	h.Equals(t, 100, nthConfig.IngestionQueueCapacity)
+	h.Equals(t, config.IngestionOverflowBlock, nthConfig.IngestionQueueOverflowPolicy)
+
+	resetFlagsForTest()
+	t.Setenv("INGESTION_QUEUE_CAPACITY", "0")
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when ingestion-queue-capacity is less than 1")
+
+	resetFlagsForTest()
+	t.Setenv("INGESTION_QUEUE_CAPACITY", "10")
+	t.Setenv("INGESTION_QUEUE_OVERFLOW_POLICY", "drop")
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when ingestion-queue-overflow-policy is unknown")
+}
+
+func TestParseCliArgsEvictionMaxParallelism(t *testing.T) {
+	resetFlagsForTest()
+	t.Setenv("NODE_NAME", "node")
+	nthConfig, err := config.ParseCliArgs()
+	h.Ok(t, err)
+	h.Equals(t, 10, nthConfig.EvictionMaxParallelism)
+
+	resetFlagsForTest()
+	t.Setenv("EVICTION_MAX_PARALLELISM", "0")
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when eviction-max-parallelism is less than 1")
+}
+
+func TestParseCliArgsDrainWaveOrder(t *testing.T) {
+	resetFlagsForTest()
+	t.Setenv("NODE_NAME", "node")
+	nthConfig, err := config.ParseCliArgs()
+	h.Ok(t, err)
+	h.Equals(t, config.DrainWaveOrderNone, nthConfig.DrainWaveOrder)
+
+	resetFlagsForTest()
+	t.Setenv("DRAIN_WAVE_ORDER", "workload")
+	nthConfig, err = config.ParseCliArgs()
+	h.Ok(t, err)
+	h.Equals(t, config.DrainWaveOrderWorkload, nthConfig.DrainWaveOrder)
+
+	resetFlagsForTest()
+	t.Setenv("DRAIN_WAVE_ORDER", "alphabetical")
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when drain-wave-order is unknown")
+}
+
+func TestParseCliArgsPDBEscalationThreshold(t *testing.T) {
+	resetFlagsForTest()
+	t.Setenv("NODE_NAME", "node")
+	nthConfig, err := config.ParseCliArgs()
+	h.Ok(t, err)
+	h.Equals(t, 100, nthConfig.PDBEscalationThreshold)
+
+	resetFlagsForTest()
+	t.Setenv("PDB_ESCALATION_THRESHOLD", "101")
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when pdb-escalation-threshold is greater than 100")
+}
+
+func TestParseCliArgsPodDataLossPolicy(t *testing.T) {
+	resetFlagsForTest()
+	t.Setenv("NODE_NAME", "node")
+	t.Setenv("POD_DATA_LOSS_POLICY", "orphan=skip, emptydir=hold")
+	t.Setenv("POD_DATA_LOSS_POLICY_OVERRIDES", "namespace/debug:orphan=force;selector/app.kubernetes.io/name in (db,cache):localpv=hold,emptydir=skip")
+	nthConfig, err := config.ParseCliArgs()
+	h.Ok(t, err)
+	actions, err := config.ParseDataLossPolicy(nthConfig.PodDataLossPolicy)
+	h.Ok(t, err)
+	h.Equals(t, map[string]string{config.DataLossOrphan: config.DataLossSkip, config.DataLossEmptyDir: config.DataLossHold}, actions)
+	rules, err := config.ParseDataLossPolicyOverrides(nthConfig.PodDataLossPolicyOverrides)
+	h.Ok(t, err)
+	h.Equals(t, 2, len(rules))
+	h.Equals(t, true, rules[0].Matches("debug", nil))
+	h.Equals(t, false, rules[0].Matches("default", nil))
+	h.Equals(t, true, rules[1].Matches("default", map[string]string{"app.kubernetes.io/name": "db"}))
+	h.Equals(t, map[string]string{config.DataLossLocalPV: config.DataLossHold, config.DataLossEmptyDir: config.DataLossSkip}, rules[1].Actions)
+
+	resetFlagsForTest()
+	t.Setenv("POD_DATA_LOSS_POLICY", "orphan=delete")
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when pod-data-loss-policy has an unknown action")
+
+	resetFlagsForTest()
+	t.Setenv("POD_DATA_LOSS_POLICY", "")
+	t.Setenv("POD_DATA_LOSS_POLICY_OVERRIDES", "team=data:localpv=hold")
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when a pod-data-loss-policy-overrides rule has no scope")
+}
+
+func TestParseCliArgsPodDisruptionNotice(t *testing.T) {
+	resetFlagsForTest()
+	t.Setenv("NODE_NAME", "node")
+	nthConfig, err := config.ParseCliArgs()
+	h.Ok(t, err)
+	h.Equals(t, true, nthConfig.PodDisruptionCondition)
+	h.Equals(t, false, nthConfig.PodDisruptionAnnotation)
+
+	resetFlagsForTest()
+	t.Setenv("POD_DISRUPTION_CONDITION", "false")
+	t.Setenv("POD_DISRUPTION_ANNOTATION", "true")
+	nthConfig, err = config.ParseCliArgs()
+	h.Ok(t, err)
+	h.Equals(t, false, nthConfig.PodDisruptionCondition)
+	h.Equals(t, true, nthConfig.PodDisruptionAnnotation)
+}
+
+func TestParseCliArgsVolumeDetachment(t *testing.T) {
+	resetFlagsForTest()
+	t.Setenv("NODE_NAME", "node")
+	nthConfig, err := config.ParseCliArgs()
+	h.Ok(t, err)
+	h.Equals(t, false, nthConfig.WaitForVolumeDetachment)
+	h.Equals(t, 120, nthConfig.VolumeDetachmentTimeout)
+	h.Equals(t, false, nthConfig.VolumeDetachmentCheckNodeStatus)
+
+	resetFlagsForTest()
+	t.Setenv("WAIT_FOR_VOLUME_DETACHMENT", "true")
+	t.Setenv("VOLUME_DETACHMENT_TIMEOUT", "0")
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when volume-detachment-timeout is 0")
+}
+
+func TestParseCliArgsWorkloadReadiness(t *testing.T) {
+	resetFlagsForTest()
+	t.Setenv("NODE_NAME", "node")
+	nthConfig, err := config.ParseCliArgs()
+	h.Ok(t, err)
+	h.Equals(t, false, nthConfig.WaitForWorkloadReadiness)
+	h.Equals(t, 300, nthConfig.WorkloadReadinessTimeout)
+	h.Equals(t, config.WorkloadReadinessFallbackComplete, nthConfig.WorkloadReadinessFallback)
+
+	resetFlagsForTest()
+	t.Setenv("WAIT_FOR_WORKLOAD_READINESS", "true")
+	t.Setenv("WORKLOAD_READINESS_FALLBACK", config.WorkloadReadinessFallbackRetry)
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when the workloads are waited for past the drain retry deadline")
+
+	resetFlagsForTest()
+	t.Setenv("DRAIN_RETRY_DEADLINE", "600")
+	nthConfig, err = config.ParseCliArgs()
+	h.Ok(t, err)
+	h.Equals(t, true, nthConfig.WaitForWorkloadReadiness)
+	h.Equals(t, config.WorkloadReadinessFallbackRetry, nthConfig.WorkloadReadinessFallback)
+
+	resetFlagsForTest()
+	t.Setenv("WORKLOAD_READINESS_FALLBACK", "ignore")
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when workload-readiness-fallback is unknown")
+
+	resetFlagsForTest()
+	t.Setenv("WORKLOAD_READINESS_FALLBACK", config.WorkloadReadinessFallbackComplete)
+	t.Setenv("WORKLOAD_READINESS_TIMEOUT", "0")
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when workload-readiness-timeout is 0")
+}
+
+func TestParseCliArgsDisruptionBudget(t *testing.T) {
+	resetFlagsForTest()
+	t.Setenv("NODE_NAME", "node")
+	nthConfig, err := config.ParseCliArgs()
+	h.Ok(t, err)
+	h.Equals(t, "", nthConfig.DisruptionBudget)
+	h.Equals(t, config.DisruptionScopeCluster, nthConfig.DisruptionBudgetScope)
+	h.Equals(t, 600, nthConfig.DisruptionBudgetMaxDeferral)
+	h.Equals(t, 0, nthConfig.StormModeThreshold)
+
+	resetFlagsForTest()
+	t.Setenv("DISRUPTION_BUDGET", "10%")
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when a budget is set in IMDS mode")
+
+	resetFlagsForTest()
+	t.Setenv("ENABLE_SQS_TERMINATION_DRAINING", "true")
+	nthConfig, err = config.ParseCliArgs()
+	h.Ok(t, err)
+	h.Equals(t, "10%", nthConfig.DisruptionBudget)
+
+	resetFlagsForTest()
+	t.Setenv("DISRUPTION_BUDGET_SCOPE", config.DisruptionScopeASG)
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when a percentage budget is scoped per ASG")
+
+	resetFlagsForTest()
+	t.Setenv("DISRUPTION_BUDGET_SCOPE", config.DisruptionScopeLabel)
+	_, err = config.ParseCliArgs()
+	h.Assert(t, err != nil, "Failed to return error when the label scope has no label")
+}
+
+func TestParseDisruptionBudget(t *testing.T) {
+	limit, percent, err := config.ParseDisruptionBudget("")
+	h.Ok(t, err)
+	h.Equals(t, -1, limit)
+	h.Equals(t, false, percent)
+
+	limit, percent, err = config.ParseDisruptionBudget("5")
+	h.Ok(t, err)
+	h.Equals(t, 5, limit)
+	h.Equals(t, false, percent)
+
+	limit, percent, err = config.ParseDisruptionBudget("20%")
+	h.Ok(t, err)
+	h.Equals(t, 20, limit)
+	h.Equals(t, true, percent)
+
+	_, _, err = config.ParseDisruptionBudget("120%")
+	h.Assert(t, err != nil, "Failed to return error when the percentage is over 100")
+
+	_, _, err = config.ParseDisruptionBudget("many")
+	h.Assert(t, err != nil, "Failed to return error when the budget is not a number")
+}
+
+func TestParseEventKindSeverity(t *testing.T) {
+	severities, err := config.ParseEventKindSeverity("")
+	h.Ok(t, err)
+	h.Equals(t, 0, len(severities))
+
+	severities, err = config.ParseEventKindSeverity("SPOT_ITN=100, REBALANCE_RECOMMENDATION=-1")
+	h.Ok(t, err)
+	h.Equals(t, map[string]int{"SPOT_ITN": 100, "REBALANCE_RECOMMENDATION": -1}, severities)
+
+	_, err = config.ParseEventKindSeverity("SPOT_ITN")
+	h.Assert(t, err != nil, "Failed to return error when severity is missing")
+
+	_, err = config.ParseEventKindSeverity("SPOT_ITN=high")
+	h.Assert(t, err != nil, "Failed to return error when severity is not an integer")
+}
+
+func TestParseMarkingPolicy(t *testing.T) {
+	rules, err := config.ParseMarkingPolicy("")
+	h.Ok(t, err)
+	h.Equals(t, 0, len(rules))
+
+	rules, err = config.ParseMarkingPolicy("REBALANCE_RECOMMENDATION:taint/example.com/rebalance={{ .EventID }}:NoSchedule,label/example.com/draining=true; " +
+		"STATE_CHANGE:taint/example.com/terminating=true,annotation/example.com/event={{ .Description }}")
+	h.Ok(t, err)
+	h.Equals(t, 2, len(rules))
+	rebalance := rules["REBALANCE_RECOMMENDATION"]
+	h.Equals(t, 1, len(rebalance.Taints))
+	h.Equals(t, "example.com/rebalance", rebalance.Taints[0].Key)
+	h.Equals(t, "NoSchedule", rebalance.Taints[0].Effect)
+	h.Assert(t, rebalance.Labels["example.com/draining"] != nil, "Expected the draining label")
+	stateChange := rules["STATE_CHANGE"]
+	h.Equals(t, "", stateChange.Taints[0].Effect)
+	h.Assert(t, stateChange.Annotations["example.com/event"] != nil, "Expected the event annotation")
+
+	_, err = config.ParseMarkingPolicy("SPOT_ITN:taint/example.com/spot=true:NoWay")
+	h.Assert(t, err != nil, "Failed to return error when the taint effect is unknown")
+
+	_, err = config.ParseMarkingPolicy("SPOT_ITN:label/example.com/spot={{ .EventID")
+	h.Assert(t, err != nil, "Failed to return error when a value is not a valid template")
+
+	_, err = config.ParseMarkingPolicy("SPOT_ITN:label/not a key=true")
+	h.Assert(t, err != nil, "Failed to return error when a key is invalid")
+
+	_, err = config.ParseMarkingPolicy("SPOT_ITN:selector/example.com/spot=true")
+	h.Assert(t, err != nil, "Failed to return error when a mark is neither a taint, a label nor an annotation")
+
+	_, err = config.ParseMarkingPolicy("SPOT_ITN:label/example.com/a=1;SPOT_ITN:label/example.com/b=2")
+	h.Assert(t, err != nil, "Failed to return error when a kind has two rules")
+}
+
+func TestNodeResolutionStrategies(t *testing.T) {
+	strategies, err := config.Config{}.NodeResolutionStrategies()
+	h.Ok(t, err)
+	h.Equals(t, []string{config.NodeResolutionPrivateDNS, config.NodeResolutionHostnameLabel, config.NodeResolutionInstanceIDLabel, config.NodeResolutionProviderID}, strategies)
+
+	strategies, err = config.Config{UseProviderId: true}.NodeResolutionStrategies()
+	h.Ok(t, err)
+	h.Equals(t, []string{config.NodeResolutionProviderID, config.NodeResolutionPrivateDNS, config.NodeResolutionHostnameLabel, config.NodeResolutionInstanceIDLabel}, strategies)
+
+	strategies, err = config.Config{NodeResolution: "tag, instance-id-label,provider-id", NodeResolutionTag: "Name"}.NodeResolutionStrategies()
+	h.Ok(t, err)
+	h.Equals(t, []string{config.NodeResolutionTag, config.NodeResolutionInstanceIDLabel, config.NodeResolutionProviderID}, strategies)
+
+	_, err = config.Config{NodeResolution: "tag"}.NodeResolutionStrategies()
+	h.Assert(t, err != nil, "Failed to return error when the tag strategy has no tag")
+
+	_, err = config.Config{NodeResolution: "private-dns,ip-address"}.NodeResolutionStrategies()
+	h.Assert(t, err != nil, "Failed to return error when a strategy is unknown")
+
+	_, err = config.Config{NodeResolution: "private-dns,private-dns"}.NodeResolutionStrategies()
+	h.Assert(t, err != nil, "Failed to return error when a strategy is listed twice")
+}
+
 func TestPrint_Human(t *testing.T) {
 	resetFlagsForTest()
 	t.Setenv("NODE_NAME", "node")
@@ -0,0 +1,302 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package disruptionbudget
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	"github.com/aws/aws-node-termination-handler/pkg/interruptioneventstore"
+	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+	"github.com/aws/aws-node-termination-handler/pkg/node"
+)
+
+const (
+	// RecheckInterval is how long an event which exceeds the budget is deferred before the budget is checked again
+	RecheckInterval = 10 * time.Second
+	// zoneLabel is the well-known label holding the availability zone of a node
+	zoneLabel = "topology.kubernetes.io/zone"
+	// stormWindow is the period over which the rate of new events is measured
+	stormWindow = time.Minute
+)
+
+// Budget bounds the number of nodes which are cordoned or drained at the same time, and detects event storms
+type Budget struct {
+	limit          int
+	percent        bool
+	scope          string
+	scopeLabel     string
+	maxDeferral    time.Duration
+	drainTime      time.Duration
+	stormThreshold int
+	clientset      kubernetes.Interface
+	cache          *node.Cache
+	store          *interruptioneventstore.Store
+	mutex          sync.Mutex
+	arrivals       []time.Time
+	storm          bool
+}
+
+// New creates a new Budget set by the NTH config, which observes the events received by the store to detect storms.
+// Nodes are counted from the informer cache once it has synced, and listed from the API server until then.
+func New(nthConfig config.Config, clientset kubernetes.Interface, nodeCache *node.Cache, store *interruptioneventstore.Store) *Budget {
+	// the budget is validated when the config is parsed
+	limit, percent, _ := config.ParseDisruptionBudget(nthConfig.DisruptionBudget)
+	b := &Budget{
+		limit:          limit,
+		percent:        percent,
+		scope:          nthConfig.DisruptionBudgetScope,
+		scopeLabel:     nthConfig.DisruptionBudgetScopeLabel,
+		maxDeferral:    time.Duration(nthConfig.DisruptionBudgetMaxDeferral) * time.Second,
+		drainTime:      time.Duration(nthConfig.NodeTerminationGracePeriod) * time.Second,
+		stormThreshold: nthConfig.StormModeThreshold,
+		clientset:      clientset,
+		cache:          nodeCache,
+		store:          store,
+	}
+	if b.stormThreshold > 0 {
+		store.AddTransitionObserver(func(_ *monitor.InterruptionEvent, transition monitor.Transition, _ error) {
+			if transition.From == "" && transition.To == monitor.StateReceived {
+				b.observeArrival(transition.Time)
+			}
+		})
+	}
+	return b
+}
+
+// Enabled returns true if a budget is set
+func (b *Budget) Enabled() bool {
+	return b.limit >= 0
+}
+
+// StormMode returns true while more new events than the storm mode threshold were received over the last minute,
+// during which nodes are only cordoned
+func (b *Budget) StormMode() bool {
+	if b.stormThreshold <= 0 {
+		return false
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.updateStorm(time.Now())
+	return b.storm
+}
+
+func (b *Budget) observeArrival(now time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.arrivals = append(b.arrivals, now)
+	b.updateStorm(now)
+}
+
+// updateStorm drops the arrivals which left the storm window and logs when storm mode starts or ends, the caller must hold the mutex
+func (b *Budget) updateStorm(now time.Time) {
+	expired := 0
+	for expired < len(b.arrivals) && now.Sub(b.arrivals[expired]) > stormWindow {
+		expired++
+	}
+	b.arrivals = b.arrivals[expired:]
+	storm := len(b.arrivals) > b.stormThreshold
+	if storm == b.storm {
+		return
+	}
+	b.storm = storm
+	if storm {
+		log.Warn().Int("events_per_minute", len(b.arrivals)).Int("threshold", b.stormThreshold).Msg("Event storm detected, nodes are only cordoned until it passes")
+	} else {
+		log.Info().Int("events_per_minute", len(b.arrivals)).Msg("Event storm passed, nodes are drained again")
+	}
+}
+
+// Snapshot counts the disrupted nodes of each scope, which are the unschedulable nodes and the nodes being processed by a worker
+func (b *Budget) Snapshot(ctx context.Context) (*Snapshot, error) {
+	nodes, err := b.nodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{
+		budget:         b,
+		nodeScope:      map[string]string{},
+		disruptedNodes: map[string]bool{},
+		total:          map[string]int{},
+		disrupted:      map[string]int{},
+		now:            time.Now(),
+	}
+	activeNodes := b.store.ActiveNodes()
+	var autoScalingGroups map[string]string
+	if b.scope == config.DisruptionScopeASG {
+		autoScalingGroups = b.store.AutoScalingGroups()
+	}
+	for _, node := range nodes {
+		scope, ok := b.scopeOf(node, autoScalingGroups)
+		if !ok {
+			continue
+		}
+		snapshot.nodeScope[node.Name] = scope
+		snapshot.total[scope]++
+		if _, active := activeNodes[node.Name]; active || node.Spec.Unschedulable {
+			snapshot.disruptedNodes[node.Name] = true
+			snapshot.disrupted[scope]++
+		}
+	}
+	return snapshot, nil
+}
+
+// nodes returns the nodes of the cluster from the informer cache, or from the API server if the cache has not synced
+func (b *Budget) nodes(ctx context.Context) ([]*corev1.Node, error) {
+	if nodes, ok := b.cache.Nodes(); ok {
+		return nodes, nil
+	}
+	nodeList, err := b.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list nodes: %w", err)
+	}
+	nodes := make([]*corev1.Node, 0, len(nodeList.Items))
+	for i := range nodeList.Items {
+		nodes = append(nodes, &nodeList.Items[i])
+	}
+	return nodes, nil
+}
+
+// scopeOf returns the scope a node belongs to, and false if it belongs to none
+func (b *Budget) scopeOf(node *corev1.Node, autoScalingGroups map[string]string) (string, bool) {
+	switch b.scope {
+	case config.DisruptionScopeZone:
+		zone, ok := node.Labels[zoneLabel]
+		return zone, ok
+	case config.DisruptionScopeLabel:
+		value, ok := node.Labels[b.scopeLabel]
+		return value, ok
+	case config.DisruptionScopeASG:
+		asg, ok := autoScalingGroups[node.Name]
+		return asg, ok
+	}
+	return "", true
+}
+
+// Snapshot is the disruption of the cluster at a point in time, which admits events as long as their scope has room left
+type Snapshot struct {
+	budget         *Budget
+	nodeScope      map[string]string
+	disruptedNodes map[string]bool
+	total          map[string]int
+	disrupted      map[string]int
+	now            time.Time
+}
+
+// Admit returns true if the node of the event can be disrupted within the budget, or if the event cannot be deferred,
+// and counts the node as disrupted if it was not already
+func (s *Snapshot) Admit(interruptionEvent *monitor.InterruptionEvent) bool {
+	scope, ok := s.nodeScope[interruptionEvent.NodeName]
+	if s.budget.scope == config.DisruptionScopeASG && interruptionEvent.AutoScalingGroupName != "" {
+		scope, ok = interruptionEvent.AutoScalingGroupName, true
+	}
+	if !ok {
+		// the node is not in the cluster or outside of every scope, so processing it disrupts nothing the budget protects
+		return true
+	}
+	if s.disruptedNodes[interruptionEvent.NodeName] {
+		// the node is already counted
+		return true
+	}
+	if s.disrupted[scope] >= s.limit(scope) && s.budget.deferrable(interruptionEvent, s.now) {
+		return false
+	}
+	s.disruptedNodes[interruptionEvent.NodeName] = true
+	s.disrupted[scope]++
+	return true
+}
+
+// limit returns the number of nodes of a scope which can be disrupted at the same time
+func (s *Snapshot) limit(scope string) int {
+	if !s.budget.percent {
+		return s.budget.limit
+	}
+	// rounded up as for the maxUnavailable of a PodDisruptionBudget
+	return (s.total[scope]*s.budget.limit + 99) / 100
+}
+
+// deferrable returns false for events whose instance goes away whatever NTH does, and for events deferred past their deferral deadline
+func (b *Budget) deferrable(interruptionEvent *monitor.InterruptionEvent, now time.Time) bool {
+	switch interruptionEvent.Kind {
+	case monitor.SpotITNKind, monitor.StateChangeKind:
+		return false
+	}
+	return now.Before(b.deferralDeadline(interruptionEvent))
+}
+
+// deferralDeadline returns when an event stops being deferred, which is the max deferral after its start time,
+// or earlier if the node would not be drained before the deadline of the event otherwise
+func (b *Budget) deferralDeadline(interruptionEvent *monitor.InterruptionEvent) time.Time {
+	deferralDeadline := interruptionEvent.StartTime.Add(b.maxDeferral)
+	if deadline, ok := interruptionEvent.Deadline(); ok && deadline.Add(-b.drainTime).Before(deferralDeadline) {
+		return deadline.Add(-b.drainTime)
+	}
+	return deferralDeadline
+}
+
+// DoNotDisrupt returns the pods of the node of the event with the do-not-disrupt annotation, as namespace/name,
+// if the event can be deferred for them. Only rebalance recommendations and scheduled events are deferred,
+// up to their deferral deadline.
+func (b *Budget) DoNotDisrupt(ctx context.Context, interruptionEvent *monitor.InterruptionEvent) ([]string, error) {
+	switch interruptionEvent.Kind {
+	case monitor.RebalanceRecommendationKind, monitor.ScheduledEventKind:
+	default:
+		return nil, nil
+	}
+	if !time.Now().Before(b.deferralDeadline(interruptionEvent)) {
+		return nil, nil
+	}
+	pods, err := b.pods(ctx, interruptionEvent.NodeName)
+	if err != nil {
+		return nil, err
+	}
+	var blocking []string
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		doNotDisrupt, err := node.DoNotDisrupt(*pod)
+		if err != nil {
+			log.Warn().Err(err).Str("pod_name", pod.Name).Str("pod_namespace", pod.Namespace).Msg("Ignoring invalid pod annotation")
+		}
+		if doNotDisrupt {
+			blocking = append(blocking, pod.Namespace+"/"+pod.Name)
+		}
+	}
+	return blocking, nil
+}
+
+// pods returns the pods of a node from the informer cache, or from the API server if the cache has not synced
+func (b *Budget) pods(ctx context.Context, nodeName string) ([]*corev1.Pod, error) {
+	if pods, ok := b.cache.Pods(nodeName); ok {
+		return pods, nil
+	}
+	podList, err := b.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: "spec.nodeName=" + nodeName})
+	if err != nil {
+		return nil, fmt.Errorf("list pods of node %s: %w", nodeName, err)
+	}
+	pods := make([]*corev1.Pod, 0, len(podList.Items))
+	for i := range podList.Items {
+		pods = append(pods, &podList.Items[i])
+	}
+	return pods, nil
+}
@@ -0,0 +1,178 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package disruptionbudget_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	"github.com/aws/aws-node-termination-handler/pkg/disruptionbudget"
+	"github.com/aws/aws-node-termination-handler/pkg/interruptioneventstore"
+	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+	"github.com/aws/aws-node-termination-handler/pkg/node"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+)
+
+func getNode(name string, zone string, unschedulable bool) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"topology.kubernetes.io/zone": zone}},
+		Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+	}
+}
+
+func getEvent(nodeName string, kind string) *monitor.InterruptionEvent {
+	return &monitor.InterruptionEvent{EventID: nodeName, Kind: kind, NodeName: nodeName, StartTime: time.Now()}
+}
+
+func TestSnapshotAdmit(t *testing.T) {
+	nthConfig := config.Config{DisruptionBudget: "1", DisruptionBudgetScope: config.DisruptionScopeCluster, DisruptionBudgetMaxDeferral: 600, NodeTerminationGracePeriod: 120}
+	clientset := fake.NewSimpleClientset(getNode("node1", "a", true), getNode("node2", "a", false), getNode("node3", "a", false))
+	budget := disruptionbudget.New(nthConfig, clientset, nil, interruptioneventstore.New(nthConfig))
+	h.Equals(t, true, budget.Enabled())
+
+	snapshot, err := budget.Snapshot(context.Background())
+	h.Ok(t, err)
+	// node1 is cordoned already, which uses up the budget
+	h.Equals(t, false, snapshot.Admit(getEvent("node2", monitor.RebalanceRecommendationKind)))
+	h.Equals(t, true, snapshot.Admit(getEvent("node1", monitor.RebalanceRecommendationKind)))
+	// the instance of a spot ITN goes away anyway
+	h.Equals(t, true, snapshot.Admit(getEvent("node3", monitor.SpotITNKind)))
+
+	// an event deferred past the max deferral is processed
+	overdue := getEvent("node2", monitor.ASGLifecycleKind)
+	overdue.StartTime = time.Now().Add(-time.Hour)
+	h.Equals(t, true, snapshot.Admit(overdue))
+
+	// an event is not deferred past the time needed to drain the node before its deadline
+	snapshot, err = budget.Snapshot(context.Background())
+	h.Ok(t, err)
+	urgent := getEvent("node3", monitor.ScheduledEventKind)
+	h.Equals(t, false, snapshot.Admit(urgent))
+	urgent.DrainDeadline = time.Now().Add(time.Minute)
+	h.Equals(t, true, snapshot.Admit(urgent))
+
+	// a node which is not in the cluster disrupts nothing
+	h.Equals(t, true, snapshot.Admit(getEvent("node4", monitor.RebalanceRecommendationKind)))
+}
+
+func TestSnapshotFromCache(t *testing.T) {
+	nthConfig := config.Config{DisruptionBudget: "1", DisruptionBudgetScope: config.DisruptionScopeCluster, DisruptionBudgetMaxDeferral: 600}
+	clientset := fake.NewSimpleClientset(getNode("node1", "a", true), getNode("node2", "a", false))
+	nodeCache, err := node.NewCache(nthConfig, clientset)
+	h.Ok(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h.Ok(t, nodeCache.Start(ctx, 10*time.Second))
+	// the nodes are not listed from the API server once the cache has synced
+	clientset.PrependReactor("list", "nodes", func(_ k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("unexpected list of nodes")
+	})
+	budget := disruptionbudget.New(nthConfig, clientset, nodeCache, interruptioneventstore.New(nthConfig))
+
+	snapshot, err := budget.Snapshot(context.Background())
+	h.Ok(t, err)
+	h.Equals(t, false, snapshot.Admit(getEvent("node2", monitor.RebalanceRecommendationKind)))
+}
+
+func TestSnapshotAdmitPercentagePerZone(t *testing.T) {
+	nthConfig := config.Config{DisruptionBudget: "25%", DisruptionBudgetScope: config.DisruptionScopeZone, DisruptionBudgetMaxDeferral: 600}
+	nodes := []runtime.Object{}
+	for i := 0; i < 8; i++ {
+		zone := "a"
+		if i >= 4 {
+			zone = "b"
+		}
+		nodes = append(nodes, getNode(fmt.Sprintf("node%d", i), zone, false))
+	}
+	budget := disruptionbudget.New(nthConfig, fake.NewSimpleClientset(nodes...), nil, interruptioneventstore.New(nthConfig))
+
+	snapshot, err := budget.Snapshot(context.Background())
+	h.Ok(t, err)
+	// 25% of the 4 nodes of each zone
+	h.Equals(t, true, snapshot.Admit(getEvent("node0", monitor.RebalanceRecommendationKind)))
+	h.Equals(t, false, snapshot.Admit(getEvent("node1", monitor.RebalanceRecommendationKind)))
+	h.Equals(t, true, snapshot.Admit(getEvent("node4", monitor.RebalanceRecommendationKind)))
+	h.Equals(t, false, snapshot.Admit(getEvent("node5", monitor.RebalanceRecommendationKind)))
+}
+
+func TestStormMode(t *testing.T) {
+	nthConfig := config.Config{StormModeThreshold: 2}
+	store := interruptioneventstore.New(nthConfig)
+	budget := disruptionbudget.New(nthConfig, fake.NewSimpleClientset(), nil, store)
+	h.Equals(t, false, budget.Enabled())
+
+	for i := 0; i < 3; i++ {
+		h.Equals(t, false, budget.StormMode())
+		store.AddInterruptionEvent(getEvent(fmt.Sprintf("node%d", i), monitor.SpotITNKind))
+	}
+	h.Equals(t, true, budget.StormMode())
+}
+
+func TestDoNotDisrupt(t *testing.T) {
+	nthConfig := config.Config{DisruptionBudgetMaxDeferral: 600, NodeTerminationGracePeriod: 120}
+	pod := func(name string, doNotDisrupt string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: map[string]string{"aws-node-termination-handler/do-not-disrupt": doNotDisrupt}},
+			Spec:       corev1.PodSpec{NodeName: "node1"},
+		}
+	}
+	clientset := fake.NewSimpleClientset(pod("batch", "true"), pod("web", "false"), pod("invalid", "please"))
+	budget := disruptionbudget.New(nthConfig, clientset, nil, interruptioneventstore.New(nthConfig))
+
+	blocking, err := budget.DoNotDisrupt(context.Background(), getEvent("node1", monitor.RebalanceRecommendationKind))
+	h.Ok(t, err)
+	h.Equals(t, []string{"default/batch"}, blocking)
+
+	// the instance of a spot ITN goes away anyway
+	blocking, err = budget.DoNotDisrupt(context.Background(), getEvent("node1", monitor.SpotITNKind))
+	h.Ok(t, err)
+	h.Equals(t, 0, len(blocking))
+
+	// an event deferred past the max deferral is processed
+	overdue := getEvent("node1", monitor.ScheduledEventKind)
+	overdue.StartTime = time.Now().Add(-time.Hour)
+	blocking, err = budget.DoNotDisrupt(context.Background(), overdue)
+	h.Ok(t, err)
+	h.Equals(t, 0, len(blocking))
+
+	// an event is not deferred past the time needed to drain the node before its deadline
+	urgent := getEvent("node1", monitor.ScheduledEventKind)
+	urgent.DrainDeadline = time.Now().Add(time.Minute)
+	blocking, err = budget.DoNotDisrupt(context.Background(), urgent)
+	h.Ok(t, err)
+	h.Equals(t, 0, len(blocking))
+
+	// the pods are not listed from the API server once the cache has synced
+	nodeCache, err := node.NewCache(nthConfig, clientset)
+	h.Ok(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h.Ok(t, nodeCache.Start(ctx, 10*time.Second))
+	clientset.PrependReactor("list", "pods", func(_ k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("unexpected list of pods")
+	})
+	budget = disruptionbudget.New(nthConfig, clientset, nodeCache, interruptioneventstore.New(nthConfig))
+	blocking, err = budget.DoNotDisrupt(context.Background(), getEvent("node1", monitor.RebalanceRecommendationKind))
+	h.Ok(t, err)
+	h.Equals(t, []string{"default/batch"}, blocking)
+}
@@ -16,7 +16,6 @@ package launch
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/aws/aws-node-termination-handler/pkg/config"
 	"github.com/aws/aws-node-termination-handler/pkg/interruptionevent/internal/common"
@@ -26,20 +25,14 @@ import (
 	"github.com/aws/aws-node-termination-handler/pkg/observability"
 	"github.com/rs/zerolog/log"
 	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/selection"
-	"k8s.io/client-go/kubernetes"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
-const instanceIDLabel = "alpha.eksctl.io/instance-id"
-
 type Handler struct {
 	commonHandler *common.Handler
-	clientset     *kubernetes.Clientset
 }
 
-func New(interruptionEventStore *interruptioneventstore.Store, node node.Node, nthConfig config.Config, metrics observability.Metrics, recorder observability.K8sEventRecorder, clientset *kubernetes.Clientset) *Handler {
+func New(interruptionEventStore *interruptioneventstore.Store, node node.Node, nthConfig config.Config, metrics observability.Metrics, recorder observability.K8sEventRecorder) *Handler {
 	commonHandler := &common.Handler{
 		InterruptionEventStore: interruptionEventStore,
 		Node:                   node,
@@ -50,11 +43,10 @@ func New(interruptionEventStore *interruptioneventstore.Store, node node.Node, n
 
 	return &Handler{
 		commonHandler: commonHandler,
-		clientset:     clientset,
 	}
 }
 
-func (h *Handler) HandleEvent(drainEvent *monitor.InterruptionEvent) error {
+func (h *Handler) HandleEvent(ctx context.Context, drainEvent *monitor.InterruptionEvent) error {
 	if drainEvent == nil {
 		return fmt.Errorf("drainEvent is nil")
 	}
@@ -63,93 +55,49 @@ func (h *Handler) HandleEvent(drainEvent *monitor.InterruptionEvent) error {
 		return nil
 	}
 
-	isNodeReady, err := h.isNodeReady(drainEvent.InstanceID)
+	readyNode, err := h.getReadyNode(ctx, drainEvent)
 	if err != nil {
-		h.commonHandler.InterruptionEventStore.CancelInterruptionEvent(drainEvent.EventID)
-		return fmt.Errorf("check if node (instanceID=%s) is present and ready: %w", drainEvent.InstanceID, err)
+		err = fmt.Errorf("check if node (instanceID=%s) is present and ready: %w", drainEvent.InstanceID, err)
+		h.commonHandler.Fail(ctx, drainEvent, err)
+		return err
 	}
-	if !isNodeReady {
-		h.commonHandler.InterruptionEventStore.CancelInterruptionEvent(drainEvent.EventID)
+	if readyNode == nil {
+		h.commonHandler.Fail(ctx, drainEvent, fmt.Errorf("node (instanceID=%s) is not ready", drainEvent.InstanceID))
 		return nil
 	}
 
-	nodeName, err := h.commonHandler.GetNodeName(drainEvent)
-	if err != nil {
-		return fmt.Errorf("get node name for instanceID=%s: %w", drainEvent.InstanceID, err)
-	}
-
 	if drainEvent.PostDrainTask != nil {
-		h.commonHandler.RunPostDrainTask(nodeName, drainEvent)
+		if err := h.commonHandler.RunPostDrainTask(ctx, readyNode.Name, drainEvent, nil); err != nil {
+			h.commonHandler.Fail(ctx, drainEvent, err)
+			return nil
+		}
 	}
+	h.commonHandler.Transition(drainEvent, monitor.StateCompleted, nil)
 	return nil
 }
 
-func (h *Handler) isNodeReady(instanceID string) (bool, error) {
-	nodes, err := h.getNodesWithInstanceID(instanceID)
-	if err != nil {
-		return false, fmt.Errorf("find node(s) with instanceId=%s: %w", instanceID, err)
-	}
-
-	if len(nodes) == 0 {
+// getReadyNode returns the node of the instance of the event, or nil if the instance has not joined the cluster or its node is not ready yet
+func (h *Handler) getReadyNode(ctx context.Context, drainEvent *monitor.InterruptionEvent) (*v1.Node, error) {
+	instanceID := drainEvent.InstanceID
+	resolvedNode, err := h.commonHandler.Node.NodeResolver().ResolveNode(ctx, node.Instance{
+		InstanceID:     instanceID,
+		ProviderID:     drainEvent.ProviderID,
+		PrivateDNSName: drainEvent.NodeName,
+	})
+	if apierrors.IsNotFound(err) {
 		log.Info().Str("instanceID", instanceID).Msg("EC2 instance not found")
-		return false, nil
-	}
-
-	for _, node := range nodes {
-		conditions := node.Status.Conditions
-		for _, condition := range conditions {
-			if condition.Type == "Ready" && condition.Status != "True" {
-				log.Info().Str("instanceID", instanceID).Msg("EC2 instance found, but not ready")
-				return false, nil
-			}
-		}
+		return nil, nil
 	}
-	log.Info().Str("instanceID", instanceID).Msg("EC2 instance is found and ready")
-	return true, nil
-}
-
-// Gets Nodes connected to K8s cluster
-func (h *Handler) getNodesWithInstanceID(instanceID string) ([]v1.Node, error) {
-	nodes, err := h.getNodesWithInstanceFromLabel(instanceID)
-	if err != nil {
-		return nil, err
-	}
-	if len(nodes) != 0 {
-		return nodes, nil
-	}
-
-	nodes, err = h.getNodesWithInstanceFromProviderID(instanceID)
-	if err != nil {
-		return nil, err
-	}
-	return nodes, nil
-}
-
-func (h *Handler) getNodesWithInstanceFromLabel(instanceID string) ([]v1.Node, error) {
-	instanceIDReq, err := labels.NewRequirement(instanceIDLabel, selection.Equals, []string{instanceID})
-	if err != nil {
-		return nil, fmt.Errorf("construct node search requirement %s=%s: %w", instanceIDLabel, instanceID, err)
-	}
-	selector := labels.NewSelector().Add(*instanceIDReq)
-	nodeList, err := h.clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
-	if err != nil {
-		return nil, fmt.Errorf("list nodes using selector %q: %w", selector.String(), err)
-	}
-	return nodeList.Items, nil
-}
-
-func (h *Handler) getNodesWithInstanceFromProviderID(instanceID string) ([]v1.Node, error) {
-	nodeList, err := h.clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("list all nodes: %w", err)
+		return nil, fmt.Errorf("find node with instanceId=%s: %w", instanceID, err)
 	}
 
-	var filteredNodes []v1.Node
-	for _, node := range nodeList.Items {
-		if !strings.Contains(node.Spec.ProviderID, instanceID) {
-			continue
+	for _, condition := range resolvedNode.Status.Conditions {
+		if condition.Type == v1.NodeReady && condition.Status != v1.ConditionTrue {
+			log.Info().Str("instanceID", instanceID).Msg("EC2 instance found, but not ready")
+			return nil, nil
 		}
-		filteredNodes = append(filteredNodes, node)
 	}
-	return filteredNodes, nil
+	log.Info().Str("instanceID", instanceID).Str("nodeName", resolvedNode.Name).Msg("EC2 instance is found and ready")
+	return resolvedNode, nil
 }
@@ -14,18 +14,20 @@
 package draincordon
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-node-termination-handler/pkg/config"
-	"github.com/aws/aws-node-termination-handler/pkg/ec2metadata"
+	"github.com/aws/aws-node-termination-handler/pkg/disruptionbudget"
 	"github.com/aws/aws-node-termination-handler/pkg/interruptionevent/internal/common"
 	"github.com/aws/aws-node-termination-handler/pkg/interruptioneventstore"
 	"github.com/aws/aws-node-termination-handler/pkg/monitor"
 	"github.com/aws/aws-node-termination-handler/pkg/node"
 	"github.com/aws/aws-node-termination-handler/pkg/observability"
-	"github.com/aws/aws-node-termination-handler/pkg/webhook"
 	"github.com/rs/zerolog/log"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var allowedKinds = []string{
@@ -38,11 +40,11 @@ var allowedKinds = []string{
 }
 
 type Handler struct {
-	commonHandler *common.Handler
-	nodeMetadata  ec2metadata.NodeMetadata
+	commonHandler    *common.Handler
+	disruptionBudget *disruptionbudget.Budget
 }
 
-func New(interruptionEventStore *interruptioneventstore.Store, node node.Node, nthConfig config.Config, nodeMetadata ec2metadata.NodeMetadata, metrics observability.Metrics, recorder observability.K8sEventRecorder) *Handler {
+func New(interruptionEventStore *interruptioneventstore.Store, node node.Node, nthConfig config.Config, metrics observability.Metrics, recorder observability.K8sEventRecorder, disruptionBudget *disruptionbudget.Budget) *Handler {
 	commonHandler := &common.Handler{
 		InterruptionEventStore: interruptionEventStore,
 		Node:                   node,
@@ -52,22 +54,18 @@ func New(interruptionEventStore *interruptioneventstore.Store, node node.Node, n
 	}
 
 	return &Handler{
-		commonHandler: commonHandler,
-		nodeMetadata:  nodeMetadata,
+		commonHandler:    commonHandler,
+		disruptionBudget: disruptionBudget,
 	}
 }
 
-func (h *Handler) HandleEvent(drainEvent *monitor.InterruptionEvent) error {
+func (h *Handler) HandleEvent(ctx context.Context, drainEvent *monitor.InterruptionEvent) error {
 	if !common.IsAllowedKind(drainEvent.Kind, allowedKinds...) {
 		return nil
 	}
 
 	nodeFound := true
-	nodeName, err := h.commonHandler.GetNodeName(drainEvent)
-	if err != nil {
-		return fmt.Errorf("get node name for instanceID=%s: %w", drainEvent.InstanceID, err)
-	}
-
+	nodeName := drainEvent.NodeName
 	nodeLabels, err := h.commonHandler.Node.GetNodeLabels(nodeName)
 	if err != nil {
 		log.Warn().
@@ -81,16 +79,16 @@ func (h *Handler) HandleEvent(drainEvent *monitor.InterruptionEvent) error {
 	}
 
 	if drainEvent.PreDrainTask != nil {
-		if err := h.commonHandler.RunPreDrainTask(nodeName, drainEvent); err != nil {
+		if err := h.commonHandler.RunPreDrainTask(ctx, nodeName, drainEvent); err != nil {
 			log.Err(err).Str("nodeName", nodeName).Msg("Pre-drain task failed; aborting to allow SQS retry")
-			h.commonHandler.InterruptionEventStore.CancelInterruptionEvent(drainEvent.EventID)
 
 			// If the node is missing and the user opted for DeleteSqsMsgIfNodeNotFound then delete the SQS message
 			if !nodeFound && h.commonHandler.NthConfig.DeleteSqsMsgIfNodeNotFound && drainEvent.PostDrainTask != nil {
-				h.commonHandler.RunPostDrainTask(nodeName, drainEvent)
+				h.runPostDrainTask(ctx, nodeName, drainEvent, err)
 				return nil
 			}
-			
+
+			h.commonHandler.Fail(ctx, drainEvent, err)
 			return err
 		}
 	}
@@ -111,40 +109,159 @@ func (h *Handler) HandleEvent(drainEvent *monitor.InterruptionEvent) error {
 		log.Warn().Err(err).Str("nodeName", nodeName).Msg("Failed to log pods")
 	}
 
-	if h.commonHandler.NthConfig.CordonOnly || (!h.commonHandler.NthConfig.EnableSQSTerminationDraining && drainEvent.IsRebalanceRecommendation() && !h.commonHandler.NthConfig.EnableRebalanceDraining) {
-		err = h.cordonNode(nodeName, drainEvent)
+	// events of the node which became drainable meanwhile are processed along with this one rather than by another worker
+	merged := h.mergeEvents(ctx, nodeName, drainEvent)
+	drain := h.shouldDrain(append([]*monitor.InterruptionEvent{drainEvent}, merged...))
+	if drain {
+		err = h.drainNode(ctx, nodeName, drainEvent, merged)
 	} else {
-		err = h.cordonAndDrainNode(nodeName, drainEvent)
+		h.commonHandler.Transition(drainEvent, monitor.StateCordoning, nil)
+		err = h.cordonNode(nodeName, drainEvent)
+		if err == nil {
+			h.commonHandler.Transition(drainEvent, monitor.StateCordoned, nil)
+		}
 	}
-
-	if h.commonHandler.NthConfig.WebhookURL != "" {
-		webhook.Post(h.nodeMetadata, drainEvent, h.commonHandler.NthConfig)
+	for err == nil {
+		late := h.mergeEvents(ctx, nodeName, drainEvent)
+		if len(late) == 0 {
+			break
+		}
+		merged = append(merged, late...)
+		if !drain && h.shouldDrain(late) {
+			// e.g. a spot ITN arrived while a rebalance recommendation was cordoning the node
+			log.Info().Str("node_name", nodeName).Str("event_id", drainEvent.EventID).Msg("Upgrading cordon to a drain for a merged interruption event")
+			drain = true
+			err = h.drainNode(ctx, nodeName, drainEvent, merged)
+		}
 	}
 
 	if err != nil {
-		if drainEvent.CancelDrainTask != nil {
-			h.commonHandler.RunCancelDrainTask(nodeName, drainEvent)
+		for _, event := range append([]*monitor.InterruptionEvent{drainEvent}, merged...) {
+			if !nodeFound && h.commonHandler.NthConfig.DeleteSqsMsgIfNodeNotFound && event.PostDrainTask != nil {
+				h.cancelDrain(ctx, nodeName, event)
+				h.runPostDrainTask(ctx, nodeName, event, err)
+			} else if !h.commonHandler.Fail(ctx, event, err) {
+				// a retried event keeps its tasks running, e.g. the heartbeats of an ASG lifecycle hook
+				h.cancelDrain(ctx, nodeName, event)
+			}
 		}
-		h.commonHandler.InterruptionEventStore.CancelInterruptionEvent(drainEvent.EventID)
-	} else {
-		h.commonHandler.InterruptionEventStore.MarkAllAsProcessed(nodeName)
+		return nil
 	}
 
-	if (err == nil || (!nodeFound && h.commonHandler.NthConfig.DeleteSqsMsgIfNodeNotFound)) && drainEvent.PostDrainTask != nil {
-		h.commonHandler.RunPostDrainTask(nodeName, drainEvent)
+	h.commonHandler.InterruptionEventStore.MarkAllAsProcessed(nodeName)
+	for _, event := range merged {
+		h.completeEvent(ctx, nodeName, event)
+	}
+	if drainEvent.PostDrainTask != nil {
+		if err := h.commonHandler.RunPostDrainTask(ctx, nodeName, drainEvent, nil); err != nil {
+			h.commonHandler.Fail(ctx, drainEvent, err)
+			return nil
+		}
 	}
 
-	// Only add out-of-service taint if ENABLE_OUT_OF_SERVICE_TAINT flag is true, and CORDON_ONLY flag is false
-	if err == nil && h.commonHandler.NthConfig.EnableOutOfServiceTaint && !h.commonHandler.NthConfig.CordonOnly {
+	// Only add out-of-service taint if ENABLE_OUT_OF_SERVICE_TAINT flag is true, and the node was drained rather than only cordoned,
+	// e.g. because CORDON_ONLY flag is true or during an event storm
+	if h.commonHandler.NthConfig.EnableOutOfServiceTaint && drain {
 		err = h.commonHandler.Node.TaintOutOfService(nodeName)
 		if err != nil {
-			return fmt.Errorf("cannot add out-of-service taint on node %s: %w", nodeName, err)
+			err = fmt.Errorf("cannot add out-of-service taint on node %s: %w", nodeName, err)
+			h.commonHandler.Transition(drainEvent, monitor.StateCompleted, err)
+			return err
 		}
 	}
 
+	h.commonHandler.Transition(drainEvent, monitor.StateCompleted, nil)
 	return nil
 }
 
+// mergeEvents merges the drainable events of the node into drainEvent and runs their pre-drain tasks,
+// and returns the merged events whose pre-drain task succeeded
+func (h *Handler) mergeEvents(ctx context.Context, nodeName string, drainEvent *monitor.InterruptionEvent) []*monitor.InterruptionEvent {
+	merged := []*monitor.InterruptionEvent{}
+	for _, event := range h.commonHandler.InterruptionEventStore.MergeInterruptionEvents(drainEvent, allowedKinds...) {
+		if event.PreDrainTask != nil {
+			if err := h.commonHandler.RunPreDrainTask(ctx, nodeName, event); err != nil {
+				h.commonHandler.Fail(ctx, event, err)
+				continue
+			}
+		}
+		merged = append(merged, event)
+	}
+	return merged
+}
+
+// shouldDrain returns true if one of the events asks for the node to be drained rather than only cordoned,
+// which no event does during an event storm
+func (h *Handler) shouldDrain(events []*monitor.InterruptionEvent) bool {
+	nthConfig := h.commonHandler.NthConfig
+	if nthConfig.CordonOnly || h.disruptionBudget.StormMode() {
+		return false
+	}
+	for _, event := range events {
+		if nthConfig.EnableSQSTerminationDraining || !event.IsRebalanceRecommendation() || nthConfig.EnableRebalanceDraining {
+			return true
+		}
+	}
+	return false
+}
+
+// drainNode moves the event through the Draining state, which a cordoned node enters when a merged event asks for a drain.
+// The pods of the node are notified of the disruption before the event leaves its pre-drain phase,
+// and the node is drained before the earliest deadline of the event and the events merged into it.
+func (h *Handler) drainNode(ctx context.Context, nodeName string, drainEvent *monitor.InterruptionEvent, merged []*monitor.InterruptionEvent) error {
+	events := append([]*monitor.InterruptionEvent{drainEvent}, merged...)
+	deadline := drainDeadline(events)
+	h.notifyPods(ctx, nodeName, drainEvent, deadline)
+	h.commonHandler.Transition(drainEvent, monitor.StateDraining, nil)
+	evictions, err := h.cordonAndDrainNode(ctx, nodeName, drainEvent, deadline)
+	h.commonHandler.Metrics.PodEvictionsRecord(evictions)
+	for _, event := range events {
+		event.Evictions = evictions
+	}
+	if err == nil {
+		h.commonHandler.Transition(drainEvent, monitor.StateDrained, nil)
+	}
+	return err
+}
+
+// notifyPods notifies the pods of the node of the disruption, a failure is only logged since it does not hold back the drain
+func (h *Handler) notifyPods(ctx context.Context, nodeName string, drainEvent *monitor.InterruptionEvent, deadline time.Time) {
+	notice := node.DisruptionNotice{Kind: drainEvent.Kind, EventID: drainEvent.EventID}
+	if !deadline.IsZero() {
+		notice.Deadline = &metav1.Time{Time: deadline}
+	}
+	if err := h.commonHandler.Node.NotifyPodsOfDisruption(ctx, nodeName, notice); err != nil {
+		log.Warn().Err(err).Str("node_name", nodeName).Str("event_id", drainEvent.EventID).Msg("Unable to notify the pods of the node of the disruption")
+	}
+}
+
+// cancelDrain runs the early exit task of an event which is done with its drain without completing it
+func (h *Handler) cancelDrain(ctx context.Context, nodeName string, drainEvent *monitor.InterruptionEvent) {
+	if drainEvent.CancelDrainTask != nil {
+		h.commonHandler.RunCancelDrainTask(ctx, nodeName, drainEvent)
+	}
+}
+
+// completeEvent runs the post-drain task of an event which was merged into the one which cordoned or drained its node,
+// and moves it to the Completed state, or to the Failed state if the task failed
+func (h *Handler) completeEvent(ctx context.Context, nodeName string, drainEvent *monitor.InterruptionEvent) {
+	if drainEvent.PostDrainTask == nil {
+		h.commonHandler.Transition(drainEvent, monitor.StateCompleted, nil)
+		return
+	}
+	h.runPostDrainTask(ctx, nodeName, drainEvent, nil)
+}
+
+// runPostDrainTask runs the post-drain task of an event, which may be one that could not be processed since its node is gone,
+// and moves the event to the Completed state, or to the Failed state if the task failed
+func (h *Handler) runPostDrainTask(ctx context.Context, nodeName string, drainEvent *monitor.InterruptionEvent, cause error) {
+	if err := h.commonHandler.RunPostDrainTask(ctx, nodeName, drainEvent, cause); err != nil {
+		h.commonHandler.Fail(ctx, drainEvent, err)
+		return
+	}
+	h.commonHandler.Transition(drainEvent, monitor.StateCompleted, nil)
+}
+
 func (h *Handler) cordonNode(nodeName string, drainEvent *monitor.InterruptionEvent) error {
 	err := h.commonHandler.Node.Cordon(nodeName, drainEvent.Description)
 	if err != nil {
@@ -152,32 +269,36 @@ func (h *Handler) cordonNode(nodeName string, drainEvent *monitor.InterruptionEv
 			log.Err(err).Msgf("node '%s' not found in the cluster", nodeName)
 		} else {
 			log.Err(err).Msg("There was a problem while trying to cordon the node")
-			h.commonHandler.Recorder.Emit(nodeName, observability.Warning, observability.CordonErrReason, observability.CordonErrMsgFmt, err.Error())
 		}
 		return err
 	} else {
 		log.Info().Str("node_name", nodeName).Str("reason", drainEvent.Description).Msg("Node successfully cordoned")
-		h.commonHandler.Metrics.NodeActionsInc("cordon", nodeName, drainEvent.EventID, err)
-		h.commonHandler.Recorder.Emit(nodeName, observability.Normal, observability.CordonReason, observability.CordonMsg)
 	}
 	return nil
 }
 
-func (h *Handler) cordonAndDrainNode(nodeName string, drainEvent *monitor.InterruptionEvent) error {
-	err := h.commonHandler.Node.CordonAndDrain(nodeName, drainEvent.Description, h.commonHandler.Recorder.EventRecorder)
+// drainDeadline returns the earliest deadline of the events, or the zero time if none has a deadline
+func drainDeadline(events []*monitor.InterruptionEvent) time.Time {
+	var earliest time.Time
+	for _, event := range events {
+		if deadline, ok := event.Deadline(); ok && (earliest.IsZero() || deadline.Before(earliest)) {
+			earliest = deadline
+		}
+	}
+	return earliest
+}
+
+func (h *Handler) cordonAndDrainNode(ctx context.Context, nodeName string, drainEvent *monitor.InterruptionEvent, deadline time.Time) ([]node.PodEvictionResult, error) {
+	evictions, err := h.commonHandler.Node.CordonAndDrain(ctx, nodeName, drainEvent.Description, deadline, h.commonHandler.Recorder.EventRecorder)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			log.Err(err).Msgf("node '%s' not found in the cluster", nodeName)
 		} else {
 			log.Err(err).Msg("There was a problem while trying to cordon and drain the node")
-			h.commonHandler.Metrics.NodeActionsInc("cordon-and-drain", nodeName, drainEvent.EventID, err)
-			h.commonHandler.Recorder.Emit(nodeName, observability.Warning, observability.CordonAndDrainErrReason, observability.CordonAndDrainErrMsgFmt, err.Error())
 		}
-		return err
+		return evictions, err
 	} else {
 		log.Info().Str("node_name", nodeName).Str("reason", drainEvent.Description).Msg("Node successfully cordoned and drained")
-		h.commonHandler.Metrics.NodeActionsInc("cordon-and-drain", nodeName, drainEvent.EventID, err)
-		h.commonHandler.Recorder.Emit(nodeName, observability.Normal, observability.CordonAndDrainReason, observability.CordonAndDrainMsg)
 	}
-	return nil
+	return evictions, nil
 }
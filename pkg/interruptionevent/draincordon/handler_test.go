@@ -0,0 +1,99 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package draincordon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	"github.com/aws/aws-node-termination-handler/pkg/disruptionbudget"
+	"github.com/aws/aws-node-termination-handler/pkg/interruptioneventstore"
+	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+	"github.com/aws/aws-node-termination-handler/pkg/node"
+	"github.com/aws/aws-node-termination-handler/pkg/observability"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+	"github.com/rs/zerolog/log"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/kubectl/pkg/drain"
+)
+
+const nodeName = "NAME"
+
+func TestHandleEventRetriesFailedASGEventWithoutCancellingIt(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}})
+	// the node cannot be cordoned, with an error which is worth retrying
+	client.PrependReactor("update", "nodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewTooManyRequests("throttled", 1)
+	})
+	nthConfig := config.Config{
+		NodeName:                     nodeName,
+		EnableSQSTerminationDraining: true,
+		DrainRetryMaxAttempts:        3,
+		DrainRetryDeadline:           3600,
+	}
+	drainHelper := &drain.Helper{
+		Ctx:     context.TODO(),
+		Client:  client,
+		Force:   true,
+		Timeout: 10 * time.Second,
+		Out:     log.Logger,
+		ErrOut:  log.Logger,
+	}
+	tNode, err := node.NewWithValues(nthConfig, drainHelper, nil)
+	h.Ok(t, err)
+	store := interruptioneventstore.New(nthConfig)
+	handler := New(store, *tNode, nthConfig, observability.Metrics{}, observability.K8sEventRecorder{}, disruptionbudget.New(nthConfig, client, nil, store))
+
+	preDrains := 0
+	cancelHeartbeatCh := make(chan struct{})
+	event := &monitor.InterruptionEvent{
+		EventID:   "asg-lifecycle-term-1",
+		Kind:      monitor.ASGLifecycleKind,
+		Monitor:   "SQS_MONITOR",
+		NodeName:  nodeName,
+		StartTime: time.Now(),
+		PreDrainTask: func(context.Context, monitor.InterruptionEvent, node.Node) error {
+			preDrains++
+			return nil
+		},
+		// closing the channel twice panics, as it would for the heartbeats of a lifecycle hook
+		CancelDrainTask: func(context.Context, monitor.InterruptionEvent, node.Node) error {
+			close(cancelHeartbeatCh)
+			return nil
+		},
+	}
+	store.AddInterruptionEvent(event)
+
+	for attempt := 1; attempt <= nthConfig.DrainRetryMaxAttempts; attempt++ {
+		store.MarkScheduled(event)
+		h.Ok(t, handler.HandleEvent(context.Background(), event))
+		store.ReleaseNode(event)
+		h.Equals(t, attempt, preDrains)
+		select {
+		case <-cancelHeartbeatCh:
+			h.Assert(t, attempt == nthConfig.DrainRetryMaxAttempts, "Cancelled the drain of an event which is retried")
+		default:
+			h.Assert(t, attempt < nthConfig.DrainRetryMaxAttempts, "Failed to cancel the drain of an event which is not retried")
+			h.Equals(t, monitor.StateReceived, event.Lifecycle.State)
+		}
+	}
+	h.Equals(t, monitor.StateFailed, event.Lifecycle.State)
+}
@@ -14,7 +14,8 @@
 package common
 
 import (
-	"fmt"
+	"context"
+	"errors"
 
 	"github.com/aws/aws-node-termination-handler/pkg/config"
 	"github.com/aws/aws-node-termination-handler/pkg/interruptioneventstore"
@@ -22,6 +23,8 @@ import (
 	"github.com/aws/aws-node-termination-handler/pkg/node"
 	"github.com/aws/aws-node-termination-handler/pkg/observability"
 	"github.com/rs/zerolog/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 )
 
 type Handler struct {
@@ -32,32 +35,66 @@ type Handler struct {
 	Recorder               observability.K8sEventRecorder
 }
 
-func (h *Handler) GetNodeName(drainEvent *monitor.InterruptionEvent) (string, error) {
-	if !h.NthConfig.UseProviderId {
-		return drainEvent.NodeName, nil
+// Transition moves the event to the given lifecycle state, an illegal transition is logged since the event was cancelled meanwhile
+func (h *Handler) Transition(drainEvent *monitor.InterruptionEvent, state monitor.EventState, err error) {
+	if transitionErr := h.InterruptionEventStore.Transition(drainEvent, state, err); transitionErr != nil {
+		log.Warn().Err(transitionErr).Msg("Unable to transition interruption event")
 	}
+}
 
-	nodeName, err := h.Node.GetNodeNameFromProviderID(drainEvent.ProviderID)
-	if err != nil {
-		return "", fmt.Errorf("parse node name from providerID=%q: %w", drainEvent.ProviderID, err)
+// Fail moves the event to the Failed state, and hands it back to the queue if err is retryable and the retry policy allows it.
+// It returns true if the event is retried, in which case its tasks run again and must not be cancelled.
+func (h *Handler) Fail(ctx context.Context, drainEvent *monitor.InterruptionEvent, err error) bool {
+	h.Transition(drainEvent, monitor.StateFailed, err)
+	if ctx.Err() != nil {
+		// NTH is shutting down, the event is released to its source instead
+		return false
+	}
+	if !IsRetryable(err) {
+		log.Info().Err(err).Str("event_id", drainEvent.EventID).Msg("Interruption event failed with a terminal error, not retrying")
+		h.InterruptionEventStore.GiveUpInterruptionEvent(drainEvent)
+		return false
 	}
-	return nodeName, nil
+	_, retried := h.InterruptionEventStore.RetryInterruptionEvent(drainEvent)
+	return retried
 }
 
-func (h *Handler) RunPreDrainTask(nodeName string, drainEvent *monitor.InterruptionEvent) error {
-	err := drainEvent.PreDrainTask(*drainEvent, h.Node)
+// IsRetryable returns false for errors which processing the event again cannot fix, such as a missing node,
+// and true for transient ones such as API throttling, conflicts or evictions blocked by a PodDisruptionBudget
+func IsRetryable(err error) bool {
+	var aggregate utilerrors.Aggregate
+	if errors.As(err, &aggregate) {
+		for _, aggregatedErr := range aggregate.Errors() {
+			if IsRetryable(aggregatedErr) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, context.Canceled):
+		// NTH is shutting down, the event is released to its source instead
+		return false
+	case apierrors.IsNotFound(err), apierrors.IsForbidden(err), apierrors.IsUnauthorized(err),
+		apierrors.IsInvalid(err), apierrors.IsBadRequest(err), apierrors.IsMethodNotSupported(err):
+		return false
+	}
+	return true
+}
+
+func (h *Handler) RunPreDrainTask(ctx context.Context, nodeName string, drainEvent *monitor.InterruptionEvent) error {
+	h.Transition(drainEvent, monitor.StatePreDrain, nil)
+	err := drainEvent.PreDrainTask(ctx, *drainEvent, h.Node)
 	if err != nil {
-		log.Err(err).Msg("There was a problem executing the pre-drain task")
-		h.Recorder.Emit(nodeName, observability.Warning, observability.PreDrainErrReason, observability.PreDrainErrMsgFmt, err.Error())
-	} else {
-		h.Recorder.Emit(nodeName, observability.Normal, observability.PreDrainReason, observability.PreDrainMsg)
+		log.Err(err).Str("node_name", nodeName).Msg("There was a problem executing the pre-drain task")
 	}
-	h.Metrics.NodeActionsInc("pre-drain", nodeName, drainEvent.EventID, err)
 	return err
 }
 
-func (h *Handler) RunCancelDrainTask(nodeName string, drainEvent *monitor.InterruptionEvent) {
-	err := drainEvent.CancelDrainTask(*drainEvent, h.Node)
+func (h *Handler) RunCancelDrainTask(ctx context.Context, nodeName string, drainEvent *monitor.InterruptionEvent) {
+	err := drainEvent.CancelDrainTask(ctx, *drainEvent, h.Node)
 	if err != nil {
 		log.Err(err).Msg("There was a problem executing the early exit task")
 		h.Recorder.Emit(nodeName, observability.Warning, observability.CancelDrainErrReason, observability.CancelDrainErrMsgFmt, err.Error())
@@ -66,15 +103,14 @@ func (h *Handler) RunCancelDrainTask(nodeName string, drainEvent *monitor.Interr
 	}
 }
 
-func (h *Handler) RunPostDrainTask(nodeName string, drainEvent *monitor.InterruptionEvent) {
-	err := drainEvent.PostDrainTask(*drainEvent, h.Node)
+// RunPostDrainTask moves the event to the PostDrain state, recording cause as its last error, and runs the post-drain task
+func (h *Handler) RunPostDrainTask(ctx context.Context, nodeName string, drainEvent *monitor.InterruptionEvent, cause error) error {
+	h.Transition(drainEvent, monitor.StatePostDrain, cause)
+	err := drainEvent.PostDrainTask(ctx, *drainEvent, h.Node)
 	if err != nil {
-		log.Err(err).Msg("There was a problem executing the post-drain task")
-		h.Recorder.Emit(nodeName, observability.Warning, observability.PostDrainErrReason, observability.PostDrainErrMsgFmt, err.Error())
-	} else {
-		h.Recorder.Emit(nodeName, observability.Normal, observability.PostDrainReason, observability.PostDrainMsg)
+		log.Err(err).Str("node_name", nodeName).Msg("There was a problem executing the post-drain task")
 	}
-	h.Metrics.NodeActionsInc("post-drain", nodeName, drainEvent.EventID, err)
+	return err
 }
 
 func IsAllowedKind(kind string, allowedKinds ...string) bool {
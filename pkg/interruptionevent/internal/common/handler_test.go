@@ -0,0 +1,70 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package common_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	"github.com/aws/aws-node-termination-handler/pkg/interruptionevent/internal/common"
+	"github.com/aws/aws-node-termination-handler/pkg/interruptioneventstore"
+	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+)
+
+func TestIsRetryable(t *testing.T) {
+	nodes := schema.GroupResource{Resource: "nodes"}
+	notFound := apierrors.NewNotFound(nodes, "node-1")
+	throttled := apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 10)
+
+	for name, tc := range map[string]struct {
+		err       error
+		retryable bool
+	}{
+		"throttling":         {throttled, true},
+		"conflict":           {apierrors.NewConflict(nodes, "node-1", errors.New("modified")), true},
+		"drain timeout":      {errors.New("global timeout reached: 2m0s"), true},
+		"node not found":     {notFound, false},
+		"wrapped not found":  {fmt.Errorf("cordon: %w", notFound), false},
+		"forbidden":          {apierrors.NewForbidden(nodes, "node-1", errors.New("rbac")), false},
+		"shutdown":           {fmt.Errorf("drain: %w", context.Canceled), false},
+		"aggregate terminal": {utilerrors.NewAggregate([]error{notFound, notFound}), false},
+		"aggregate mixed":    {utilerrors.NewAggregate([]error{notFound, throttled}), true},
+	} {
+		h.Assert(t, common.IsRetryable(tc.err) == tc.retryable, "%s: expected retryable=%t", name, tc.retryable)
+	}
+}
+
+func TestFailGivesUpOnTerminalErrors(t *testing.T) {
+	store := interruptioneventstore.New(config.Config{DrainRetryMaxAttempts: 3, DrainRetryInitialBackoff: 1, DrainRetryMaxBackoff: 1, DrainRetryDeadline: 120})
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{EventID: "123", StartTime: time.Now(), NodeName: "node-1"})
+	drainEvent, ok := store.GetActiveEvent()
+	h.Equals(t, true, ok)
+	store.MarkScheduled(drainEvent)
+	h.Ok(t, store.Transition(drainEvent, monitor.StateDraining, nil))
+
+	handler := common.Handler{InterruptionEventStore: store}
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Resource: "nodes"}, "node-1", errors.New("rbac"))
+	h.Equals(t, false, handler.Fail(context.Background(), drainEvent, forbidden))
+	h.Equals(t, true, drainEvent.Lifecycle.Exhausted)
+	h.Assert(t, drainEvent.Lifecycle.NextAttempt.IsZero(), "Expected no retry to be scheduled")
+}
@@ -0,0 +1,94 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package interruptioneventstore
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	configMapStateKey = "state.json"
+	// maxConfigMapStateSize is the size of the largest state a ConfigMap can hold, whose data is limited to 1MiB
+	maxConfigMapStateSize = 1024*1024 - len(configMapStateKey)
+)
+
+// ConfigMapBackend persists the store state in a ConfigMap, it is meant for the Queue Processor
+type ConfigMapBackend struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// NewConfigMapBackend creates a new ConfigMapBackend
+func NewConfigMapBackend(client kubernetes.Interface, namespace string, name string) *ConfigMapBackend {
+	return &ConfigMapBackend{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// Load returns the state saved in the ConfigMap
+func (b *ConfigMapBackend) Load(ctx context.Context) (State, error) {
+	configMap, err := b.client.CoreV1().ConfigMaps(b.namespace).Get(ctx, b.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return State{}, nil
+	}
+	if err != nil {
+		return State{}, fmt.Errorf("unable to get ConfigMap %s/%s: %w", b.namespace, b.name, err)
+	}
+	return decodeState([]byte(configMap.Data[configMapStateKey]))
+}
+
+// Save writes the state to the ConfigMap, creating it if needed, and returns ErrStateTooLarge if it does not fit
+func (b *ConfigMapBackend) Save(ctx context.Context, state State) error {
+	data, err := encodeState(state)
+	if err != nil {
+		return err
+	}
+	if len(data) > maxConfigMapStateSize {
+		return fmt.Errorf("%w: %d bytes for %d events, a ConfigMap holds up to %d bytes", ErrStateTooLarge, len(data), len(state.Events), maxConfigMapStateSize)
+	}
+	configMaps := b.client.CoreV1().ConfigMaps(b.namespace)
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := configMaps.Get(ctx, b.name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: b.name, Namespace: b.namespace},
+				Data:       map[string]string{configMapStateKey: string(data)},
+			}, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		configMap.Data[configMapStateKey] = string(data)
+		_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to save ConfigMap %s/%s: %w", b.namespace, b.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package interruptioneventstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FileBackend persists the store state in a local file, it is meant for IMDS mode DaemonSet pods
+type FileBackend struct {
+	path string
+}
+
+// NewFileBackend creates a new FileBackend
+func NewFileBackend(path string) *FileBackend {
+	return &FileBackend{path: path}
+}
+
+// Load returns the state saved in the file
+func (b *FileBackend) Load(_ context.Context) (State, error) {
+	data, err := os.ReadFile(b.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return State{}, nil
+	}
+	if err != nil {
+		return State{}, fmt.Errorf("unable to read %s: %w", b.path, err)
+	}
+	return decodeState(data)
+}
+
+// Save atomically replaces the file with the state so that a crash never leaves a partially written file behind
+func (b *FileBackend) Save(_ context.Context, state State) error {
+	data, err := encodeState(state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0o755); err != nil {
+		return fmt.Errorf("unable to create the directory of %s: %w", b.path, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), filepath.Base(b.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("unable to create a temporary file for %s: %w", b.path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to sync %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to close %s: %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), b.path); err != nil {
+		return fmt.Errorf("unable to replace %s: %w", b.path, err)
+	}
+	return nil
+}
@@ -14,6 +14,8 @@
 package interruptioneventstore
 
 import (
+	"container/heap"
+	"fmt"
 	"sync"
 	"time"
 
@@ -29,6 +31,21 @@ type Store struct {
 	NthConfig              config.Config
 	interruptionEventStore map[string]*monitor.InterruptionEvent
 	ignoredEvents          map[string]struct{}
+	queue                  eventQueue
+	queuedEvents           map[string]*queueItem
+	kindSeverity           map[string]int
+	restoredEvents         map[string]struct{}
+	resumedEvents          map[string]struct{}
+	activeNodes            map[string]*monitor.InterruptionEvent
+	retryPolicy            RetryPolicy
+	observers              []TransitionObserver
+	backend                Backend
+	persistRequests        chan struct{}
+	stopWriter             chan struct{}
+	closeOnce              sync.Once
+	saveMutex              sync.Mutex
+	stateLoaded            bool
+	abandoned              bool
 	atLeastOneEvent        bool
 	Workers                chan int
 	callsSinceLastClean    int
@@ -37,12 +54,56 @@ type Store struct {
 	loggingPeriod          int
 }
 
+// defaultKindSeverity breaks ties between events with the same drain deadline, higher severity events are drained first
+var defaultKindSeverity = map[string]int{
+	monitor.SpotITNKind:                 60,
+	monitor.ASGLifecycleKind:            50,
+	monitor.SQSTerminateKind:            50,
+	monitor.StateChangeKind:             40,
+	monitor.ScheduledEventKind:          30,
+	monitor.RebalanceRecommendationKind: 10,
+	monitor.ASGLaunchLifecycleKind:      0,
+}
+
+// TransitionObserver is notified of every lifecycle transition of an interruption event, with a copy of the event taken right after it
+type TransitionObserver func(interruptionEvent *monitor.InterruptionEvent, transition monitor.Transition, err error)
+
+// transitionNotice is a transition which has not been passed to the observers yet
+type transitionNotice struct {
+	event      monitor.InterruptionEvent
+	transition monitor.Transition
+	err        error
+}
+
 // New Creates a new interruption event store
 func New(nthConfig config.Config) *Store {
+	return NewWithBackend(nthConfig, nil)
+}
+
+// NewWithBackend creates a new interruption event store which persists its state with backend, a nil backend keeps it in memory only
+func NewWithBackend(nthConfig config.Config, backend Backend) *Store {
+	kindSeverity := make(map[string]int, len(defaultKindSeverity))
+	for kind, severity := range defaultKindSeverity {
+		kindSeverity[kind] = severity
+	}
+	// the severity overrides are validated when the config is parsed
+	overrides, _ := config.ParseEventKindSeverity(nthConfig.EventKindSeverity)
+	for kind, severity := range overrides {
+		kindSeverity[kind] = severity
+	}
 	store := &Store{
 		NthConfig:              nthConfig,
 		interruptionEventStore: make(map[string]*monitor.InterruptionEvent),
 		ignoredEvents:          make(map[string]struct{}),
+		queuedEvents:           make(map[string]*queueItem),
+		kindSeverity:           kindSeverity,
+		restoredEvents:         make(map[string]struct{}),
+		resumedEvents:          make(map[string]struct{}),
+		activeNodes:            make(map[string]*monitor.InterruptionEvent),
+		retryPolicy:            NewRetryPolicy(nthConfig),
+		backend:                backend,
+		persistRequests:        make(chan struct{}, 1),
+		stopWriter:             make(chan struct{}),
 		Workers:                make(chan int, nthConfig.Workers),
 		cleaningPeriod:         7200,
 		loggingPeriod:          1800,
@@ -53,43 +114,246 @@ func New(nthConfig config.Config) *Store {
 	return store
 }
 
-// CancelInterruptionEvent removes an interruption event from the internal store
+// AddTransitionObserver registers an observer of the lifecycle transitions of interruption events
+//
+// Observers are called synchronously, without the store lock held, by the goroutine which made the transition.
+func (s *Store) AddTransitionObserver(observer TransitionObserver) {
+	s.Lock()
+	defer s.Unlock()
+	s.observers = append(s.observers, observer)
+}
+
+// Transition moves an interruption event to the given lifecycle state and notifies the observers
+//
+// err is recorded as the last error of the event. An illegal transition leaves the event unchanged and returns an error.
+func (s *Store) Transition(interruptionEvent *monitor.InterruptionEvent, state monitor.EventState, err error) error {
+	var notices []transitionNotice
+	defer func() { s.notify(notices) }()
+	defer s.persist()
+	s.Lock()
+	defer s.Unlock()
+	notice, transitionErr := s.transition(interruptionEvent, state, err)
+	if transitionErr != nil {
+		return transitionErr
+	}
+	notices = append(notices, notice)
+	return nil
+}
+
+// LifecycleState returns the lifecycle state of an event, which may be changed concurrently by other goroutines
+func (s *Store) LifecycleState(interruptionEvent *monitor.InterruptionEvent) monitor.EventState {
+	s.RLock()
+	defer s.RUnlock()
+	return interruptionEvent.Lifecycle.State
+}
+
+// transition advances the lifecycle of an event, the caller must hold the write lock and pass the notice to notify once it is released
+func (s *Store) transition(interruptionEvent *monitor.InterruptionEvent, state monitor.EventState, err error) (transitionNotice, error) {
+	transition, transitionErr := interruptionEvent.Lifecycle.Advance(state, err, time.Now())
+	if transitionErr != nil {
+		return transitionNotice{}, fmt.Errorf("event %s: %w", interruptionEvent.EventID, transitionErr)
+	}
+	log.Info().
+		Str("event_id", interruptionEvent.EventID).
+		Str("node_name", interruptionEvent.NodeName).
+		Str("from", string(transition.From)).
+		Str("to", string(transition.To)).
+		Int("attempts", interruptionEvent.Lifecycle.Attempts).
+		Str("error", transition.Error).
+		Msg("Interruption event transitioned")
+	return transitionNotice{event: *interruptionEvent, transition: transition, err: err}, nil
+}
+
+// notify passes transitions to the observers, it must be called without the store lock held
+func (s *Store) notify(notices []transitionNotice) {
+	if len(notices) == 0 {
+		return
+	}
+	s.RLock()
+	observers := s.observers
+	s.RUnlock()
+	for i := range notices {
+		for _, observer := range observers {
+			event := notices[i].event
+			observer(&event, notices[i].transition, notices[i].err)
+		}
+	}
+}
+
+// CancelInterruptionEvent moves an interruption event to the Cancelled state, the record is kept until the store is cleaned
 func (s *Store) CancelInterruptionEvent(eventID string) {
+	var notices []transitionNotice
+	defer func() { s.notify(notices) }()
+	defer s.persist()
 	s.Lock()
 	defer s.Unlock()
-	delete(s.interruptionEventStore, eventID)
+	delete(s.restoredEvents, eventID)
+	s.dequeue(eventID)
+	interruptionEvent, ok := s.interruptionEventStore[eventID]
+	if !ok || !interruptionEvent.Lifecycle.State.CanTransitionTo(monitor.StateCancelled) {
+		return
+	}
+	if notice, err := s.transition(interruptionEvent, monitor.StateCancelled, nil); err == nil {
+		notices = append(notices, notice)
+	}
 }
 
 // AddInterruptionEvent adds an interruption event to the internal store
+//
+// An event which is already stored is dropped, unless it was restored, has failed and may be retried, or was cancelled,
+// in which case the reported event replaces it and is received again with the lifecycle of the previous one.
+// A restored event whose drain was resumed is replaced unless a worker is processing it, and is processed again
+// with its drain tasks if it was completed without them.
 func (s *Store) AddInterruptionEvent(interruptionEvent *monitor.InterruptionEvent) {
 	s.RLock()
-	_, ok := s.interruptionEventStore[interruptionEvent.EventID]
+	previous, ok := s.interruptionEventStore[interruptionEvent.EventID]
+	replaceable := ok && s.isReplaceable(previous)
 	s.RUnlock()
-	if ok {
+	if ok && !replaceable {
 		return
 	}
 
+	var notices []transitionNotice
+	defer func() { s.notify(notices) }()
+	defer s.persist()
 	s.Lock()
 	defer s.Unlock()
-	log.Info().Interface("event", interruptionEvent).Msg("Adding new event to the event store")
+	previous, ok = s.interruptionEventStore[interruptionEvent.EventID]
+	if ok && !s.isReplaceable(previous) {
+		return
+	}
+	if ok {
+		// the monitor reported a restored, failed or cancelled event again, so it comes back with its drain tasks and can be processed
+		log.Info().Str("event_id", interruptionEvent.EventID).Str("state", string(previous.Lifecycle.State)).Msg("Replacing stored event with the one reported by its monitor")
+		delete(s.restoredEvents, interruptionEvent.EventID)
+		delete(s.resumedEvents, interruptionEvent.EventID)
+		s.dequeue(interruptionEvent.EventID)
+		if previous.Lifecycle.State != monitor.StateCompleted {
+			interruptionEvent.Lifecycle = previous.Lifecycle
+		}
+	}
+	if interruptionEvent.Lifecycle.State != monitor.StateReceived {
+		notice, err := s.transition(interruptionEvent, monitor.StateReceived, nil)
+		if err != nil {
+			log.Warn().Err(err).Msg("Unable to add interruption event to the event store")
+			return
+		}
+		notices = append(notices, notice)
+	}
 	s.interruptionEventStore[interruptionEvent.EventID] = interruptionEvent
-	if _, ignored := s.ignoredEvents[interruptionEvent.EventID]; !ignored {
+	_, ignored := s.ignoredEvents[interruptionEvent.EventID]
+	if !ignored {
 		s.atLeastOneEvent = true
 	}
+	if ignored {
+		log.Info().Interface("event", interruptionEvent).Msg("Adding new event to the event store")
+		return
+	}
+	item := s.enqueue(interruptionEvent)
+	log.Info().Interface("event", interruptionEvent).Int("queue_position", s.queuePosition(item)).Msg("Adding new event to the event store")
 }
 
-// GetActiveEvent returns true if there are interruption events in the internal store
+// GetActiveEvent returns the most urgent drainable event in the queue, and true if there is one
+//
+// Events are ordered by drain deadline (StartTime minus the node termination grace period), ties are broken by Kind severity.
+// Events of a node which is being processed by a worker are skipped, they stay queued until the node is released.
 func (s *Store) GetActiveEvent() (*monitor.InterruptionEvent, bool) {
 	s.cleanPeriodically()
 	s.logPeriodically()
+	s.Lock()
+	defer s.Unlock()
+	var busyNodeItems []*queueItem
+	defer func() {
+		for _, item := range busyNodeItems {
+			heap.Push(&s.queue, item)
+		}
+	}()
+	for s.queue.Len() > 0 {
+		item := s.queue[0]
+		if _, ignored := s.ignoredEvents[item.event.EventID]; ignored || item.event.Lifecycle.State != monitor.StateReceived {
+			s.dequeue(item.event.EventID)
+			continue
+		}
+		if !s.shouldEventDrain(item.event) {
+			break
+		}
+		if s.isNodeBusy(item.event) {
+			// set aside until the more urgent events are looked at, the item stays in queuedEvents
+			busyNodeItems = append(busyNodeItems, heap.Pop(&s.queue).(*queueItem))
+			continue
+		}
+		return item.event, true
+	}
+	return &monitor.InterruptionEvent{}, false
+}
+
+// MarkScheduled moves the event to the Scheduled state and removes it from the queue once it has been handed to a worker,
+// and returns how long it was waiting for one
+//
+// The node of the event is busy until ReleaseNode is called for the event.
+func (s *Store) MarkScheduled(interruptionEvent *monitor.InterruptionEvent) time.Duration {
+	var notices []transitionNotice
+	defer func() { s.notify(notices) }()
+	defer s.persist()
+	s.Lock()
+	defer s.Unlock()
+	if notice, err := s.transition(interruptionEvent, monitor.StateScheduled, nil); err != nil {
+		log.Warn().Err(err).Msg("Unable to schedule interruption event")
+	} else {
+		notices = append(notices, notice)
+	}
+	s.acquireNode(interruptionEvent)
+	item, ok := s.queuedEvents[interruptionEvent.EventID]
+	if !ok {
+		return 0
+	}
+	s.dequeue(interruptionEvent.EventID)
+	return item.waitTime(time.Now())
+}
+
+// DeferInterruptionEvent postpones handing a received event to a worker until the given time
+func (s *Store) DeferInterruptionEvent(interruptionEvent *monitor.InterruptionEvent, until time.Time) {
+	defer s.persist()
+	s.Lock()
+	defer s.Unlock()
+	if interruptionEvent.Lifecycle.State != monitor.StateReceived {
+		return
+	}
+	interruptionEvent.Lifecycle.NextAttempt = until
+	if _, queued := s.queuedEvents[interruptionEvent.EventID]; queued {
+		// the queue is ordered by drain deadline, which the next attempt postpones
+		s.dequeue(interruptionEvent.EventID)
+		s.enqueue(interruptionEvent)
+	}
+}
+
+// QueueDepth returns the number of drainable events waiting for a free worker
+func (s *Store) QueueDepth() int {
+	s.RLock()
+	defer s.RUnlock()
+	depth := 0
+	for _, item := range s.queue {
+		if s.shouldEventDrain(item.event) {
+			depth++
+		}
+	}
+	return depth
+}
+
+// PendingEvents returns the events in the internal store which have been received but not handed to a worker
+func (s *Store) PendingEvents() []*monitor.InterruptionEvent {
 	s.RLock()
 	defer s.RUnlock()
+	pendingEvents := []*monitor.InterruptionEvent{}
 	for _, interruptionEvent := range s.interruptionEventStore {
-		if s.shouldEventDrain(interruptionEvent) {
-			return interruptionEvent, true
+		if _, ignored := s.ignoredEvents[interruptionEvent.EventID]; ignored {
+			continue
+		}
+		if interruptionEvent.Lifecycle.State == monitor.StateReceived {
+			pendingEvents = append(pendingEvents, interruptionEvent)
 		}
 	}
-	return &monitor.InterruptionEvent{}, false
+	return pendingEvents
 }
 
 // ShouldDrainNode returns true if there are drainable events in the internal store
@@ -106,7 +370,8 @@ func (s *Store) ShouldDrainNode() bool {
 
 func (s *Store) shouldEventDrain(interruptionEvent *monitor.InterruptionEvent) bool {
 	_, ignored := s.ignoredEvents[interruptionEvent.EventID]
-	if !ignored && !interruptionEvent.InProgress && !interruptionEvent.NodeProcessed && s.TimeUntilDrain(interruptionEvent) <= 0 {
+	_, restored := s.restoredEvents[interruptionEvent.EventID]
+	if !ignored && !restored && interruptionEvent.Lifecycle.State == monitor.StateReceived && s.TimeUntilDrain(interruptionEvent) <= 0 {
 		return true
 	}
 	return false
@@ -114,18 +379,73 @@ func (s *Store) shouldEventDrain(interruptionEvent *monitor.InterruptionEvent) b
 
 // TimeUntilDrain returns the duration until a node drain should occur (can return a negative duration)
 func (s *Store) TimeUntilDrain(interruptionEvent *monitor.InterruptionEvent) time.Duration {
+	return time.Until(s.drainDeadline(interruptionEvent))
+}
+
+// drainDeadline returns when the event should be drained, which a failed event postpones until its next attempt
+func (s *Store) drainDeadline(interruptionEvent *monitor.InterruptionEvent) time.Time {
 	nodeTerminationGracePeriod := time.Duration(s.NthConfig.NodeTerminationGracePeriod) * time.Second
-	drainTime := interruptionEvent.StartTime.Add(-1 * nodeTerminationGracePeriod)
-	return time.Until(drainTime)
+	drainDeadline := interruptionEvent.StartTime.Add(-1 * nodeTerminationGracePeriod)
+	if interruptionEvent.Lifecycle.NextAttempt.After(drainDeadline) {
+		return interruptionEvent.Lifecycle.NextAttempt
+	}
+	return drainDeadline
+}
+
+// enqueue adds an event to the queue, the caller must hold the write lock
+func (s *Store) enqueue(interruptionEvent *monitor.InterruptionEvent) *queueItem {
+	item := &queueItem{
+		event:         interruptionEvent,
+		drainDeadline: s.drainDeadline(interruptionEvent),
+		severity:      s.kindSeverity[interruptionEvent.Kind],
+		enqueuedAt:    time.Now(),
+	}
+	heap.Push(&s.queue, item)
+	s.queuedEvents[interruptionEvent.EventID] = item
+	return item
+}
+
+// dequeue removes an event from the queue, the caller must hold the write lock
+func (s *Store) dequeue(eventID string) {
+	item, ok := s.queuedEvents[eventID]
+	if !ok {
+		return
+	}
+	heap.Remove(&s.queue, item.index)
+	delete(s.queuedEvents, eventID)
+}
+
+// queuePosition returns the number of queued events which will be handed to a worker before item
+func (s *Store) queuePosition(item *queueItem) int {
+	position := 0
+	for _, other := range s.queue {
+		if other != item && other.before(item) {
+			position++
+		}
+	}
+	return position
 }
 
 // MarkAllAsProcessed should be called after the node has been drained to prevent further unnecessary drain calls to the k8s api
+//
+// The received and failed events of the node are completed, events which are handed to a worker complete on their own.
 func (s *Store) MarkAllAsProcessed(nodeName string) {
+	var notices []transitionNotice
+	defer func() { s.notify(notices) }()
+	defer s.persist()
 	s.Lock()
 	defer s.Unlock()
 	for _, interruptionEvent := range s.interruptionEventStore {
-		if interruptionEvent.NodeName == nodeName {
-			interruptionEvent.NodeProcessed = true
+		if interruptionEvent.NodeName != nodeName {
+			continue
+		}
+		delete(s.restoredEvents, interruptionEvent.EventID)
+		s.dequeue(interruptionEvent.EventID)
+		if state := interruptionEvent.Lifecycle.State; state != monitor.StateReceived && state != monitor.StateFailed {
+			continue
+		}
+		if notice, err := s.transition(interruptionEvent, monitor.StateCompleted, nil); err == nil {
+			notices = append(notices, notice)
 		}
 	}
 }
@@ -136,12 +456,15 @@ func (s *Store) IgnoreEvent(eventID string) {
 	if eventID == "" {
 		return
 	}
+	defer s.persist()
 	s.Lock()
 	defer s.Unlock()
 	s.ignoredEvents[eventID] = struct{}{}
+	s.dequeue(eventID)
 }
 
-// ShouldUncordonNode returns true if there was a interruption event but it was canceled and the store is now empty or only consists of ignored events
+// ShouldUncordonNode returns true if there was a interruption event but it was canceled and the store is now empty or only consists of
+// ignored, failed or cancelled events
 func (s *Store) ShouldUncordonNode(nodeName string) bool {
 	s.RLock()
 	defer s.RUnlock()
@@ -153,7 +476,10 @@ func (s *Store) ShouldUncordonNode(nodeName string) bool {
 	}
 
 	for _, interruptionEvent := range s.interruptionEventStore {
-		if _, ignored := s.ignoredEvents[interruptionEvent.EventID]; !ignored && interruptionEvent.NodeName == nodeName {
+		if _, ignored := s.ignoredEvents[interruptionEvent.EventID]; ignored || interruptionEvent.NodeName != nodeName {
+			continue
+		}
+		if state := interruptionEvent.Lifecycle.State; state != monitor.StateFailed && state != monitor.StateCancelled {
 			return false
 		}
 	}
@@ -163,25 +489,56 @@ func (s *Store) ShouldUncordonNode(nodeName string) bool {
 
 // cleanPeriodically removes old events from the store every N times it is called
 //
-// Cleaning consists of removing events with NodeProcessed=true
+// Cleaning consists of removing completed, failed and cancelled events, and restored events which were not reported again by their monitor
 func (s *Store) cleanPeriodically() {
 	s.Lock()
-	defer s.Unlock()
 	s.callsSinceLastClean++
 	if s.callsSinceLastClean < s.cleaningPeriod {
+		s.Unlock()
 		return
 	}
 	log.Info().Msg("Garbage-collecting the interruption event store")
 	toDelete := []string{}
 	for _, e := range s.interruptionEventStore {
-		if e.NodeProcessed {
+		if _, restored := s.restoredEvents[e.EventID]; restored || isFinished(e.Lifecycle.State) {
 			toDelete = append(toDelete, e.EventID)
 		}
 	}
 	for _, id := range toDelete {
 		delete(s.interruptionEventStore, id)
+		delete(s.restoredEvents, id)
+		delete(s.resumedEvents, id)
+		s.dequeue(id)
 	}
 	s.callsSinceLastClean = 0
+	s.Unlock()
+	if len(toDelete) > 0 {
+		s.persist()
+	}
+}
+
+// isReplaceable returns true if a stored event is replaced when its monitor reports it again, the caller must hold the lock.
+// A failed event is only replaced while the retry policy allows it to be retried.
+func (s *Store) isReplaceable(interruptionEvent *monitor.InterruptionEvent) bool {
+	if interruptionEvent.Lifecycle.Exhausted {
+		return false
+	}
+	if _, restored := s.restoredEvents[interruptionEvent.EventID]; restored {
+		return true
+	}
+	state := interruptionEvent.Lifecycle.State
+	if _, resumed := s.resumedEvents[interruptionEvent.EventID]; resumed && !state.InFlight() {
+		return true
+	}
+	if state == monitor.StateFailed {
+		return s.retryPolicy.allowsRetry(interruptionEvent, time.Now())
+	}
+	return state == monitor.StateCancelled
+}
+
+// isFinished returns true if a worker is done with an event in the given state
+func isFinished(state monitor.EventState) bool {
+	return state == monitor.StateCompleted || state == monitor.StateFailed || state == monitor.StateCancelled
 }
 
 // logPeriodically logs statistics about the store every N times it is called.
@@ -193,14 +550,18 @@ func (s *Store) logPeriodically() {
 		return
 	}
 	drainableEventCount := 0
+	stateCounts := map[monitor.EventState]int{}
 	for _, interruptionEvent := range s.interruptionEventStore {
 		if s.shouldEventDrain(interruptionEvent) {
 			drainableEventCount += 1
 		}
+		stateCounts[interruptionEvent.Lifecycle.State]++
 	}
 	log.Info().
 		Int("size", len(s.interruptionEventStore)).
 		Int("drainable-events", drainableEventCount).
+		Int("queued-events", s.queue.Len()).
+		Interface("events-by-state", stateCounts).
 		Msg("event store statistics")
 	s.callsSinceLastLog = 0
 }
@@ -78,6 +78,11 @@ func TestCancelInterruptionEvent(t *testing.T) {
 	h.Equals(t, false, isActive)
 	h.Assert(t, event.EventID != storedEvent.EventID,
 		fmt.Sprintf("Event has not been canceled. Expected EventID '', but got %q", storedEvent.EventID))
+	h.Equals(t, monitor.StateCancelled, event.Lifecycle.State)
+
+	// cancelling again is a no-op
+	store.CancelInterruptionEvent(event.EventID)
+	h.Equals(t, monitor.StateCancelled, event.Lifecycle.State)
 }
 
 func TestShouldDrainNode(t *testing.T) {
@@ -102,26 +107,122 @@ func TestShouldDrainNode(t *testing.T) {
 func TestMarkAllAsProcessed(t *testing.T) {
 	store := interruptioneventstore.New(config.Config{})
 	event1 := &monitor.InterruptionEvent{
-		EventID:       "1",
-		StartTime:     time.Now().Add(time.Second * 20),
-		NodeProcessed: false,
-		NodeName:      node1,
+		EventID:   "1",
+		StartTime: time.Now().Add(time.Second * 20),
+		NodeName:  node1,
 	}
 	event2 := &monitor.InterruptionEvent{
-		EventID:       "2",
-		StartTime:     time.Now().Add(time.Second * 20),
-		NodeProcessed: false,
-		NodeName:      node1,
+		EventID:   "2",
+		StartTime: time.Now().Add(time.Second * 20),
+		NodeName:  node1,
 	}
 
 	store.AddInterruptionEvent(event1)
 	store.AddInterruptionEvent(event2)
 	store.MarkAllAsProcessed(node1)
 
-	// When events are marked as NodeProcessed=true, then they are no longer
+	// When events are completed, then they are no longer
 	// returned by the GetActiveEvent func, so we expect false
 	_, isActive := store.GetActiveEvent()
 	h.Equals(t, false, isActive)
+	h.Equals(t, monitor.StateCompleted, event1.Lifecycle.State)
+	h.Equals(t, monitor.StateCompleted, event2.Lifecycle.State)
+}
+
+func TestPendingEvents(t *testing.T) {
+	store := interruptioneventstore.New(config.Config{})
+	h.Equals(t, 0, len(store.PendingEvents()))
+
+	pendingEvent := &monitor.InterruptionEvent{
+		EventID:   "pending",
+		StartTime: time.Now(),
+		NodeName:  node1,
+	}
+	inProgressEvent := &monitor.InterruptionEvent{
+		EventID:   "in-progress",
+		StartTime: time.Now(),
+		NodeName:  node1,
+	}
+	processedEvent := &monitor.InterruptionEvent{
+		EventID:   "processed",
+		StartTime: time.Now(),
+		NodeName:  node1,
+	}
+	ignoredEvent := &monitor.InterruptionEvent{
+		EventID:   "ignored",
+		StartTime: time.Now(),
+		NodeName:  node1,
+	}
+	store.AddInterruptionEvent(pendingEvent)
+	store.AddInterruptionEvent(inProgressEvent)
+	store.AddInterruptionEvent(processedEvent)
+	store.AddInterruptionEvent(ignoredEvent)
+	store.IgnoreEvent(ignoredEvent.EventID)
+	store.MarkScheduled(inProgressEvent)
+	store.MarkScheduled(processedEvent)
+	h.Ok(t, store.Transition(processedEvent, monitor.StateCompleted, nil))
+
+	pendingEvents := store.PendingEvents()
+	h.Equals(t, 1, len(pendingEvents))
+	h.Equals(t, pendingEvent.EventID, pendingEvents[0].EventID)
+}
+
+func TestGetActiveEventOrdering(t *testing.T) {
+	store := interruptioneventstore.New(config.Config{NodeTerminationGracePeriod: 120})
+	now := time.Now()
+	rebalanceEvent := &monitor.InterruptionEvent{
+		EventID:   "rebalance",
+		Kind:      monitor.RebalanceRecommendationKind,
+		StartTime: now.Add(60 * time.Second),
+		NodeName:  "node-rebalance",
+	}
+	spotITNEvent := &monitor.InterruptionEvent{
+		EventID:   "spot-itn",
+		Kind:      monitor.SpotITNKind,
+		StartTime: now,
+		NodeName:  "node-spot-itn",
+	}
+	scheduledEvent := &monitor.InterruptionEvent{
+		EventID:   "scheduled",
+		Kind:      monitor.ScheduledEventKind,
+		StartTime: now.Add(60 * time.Second),
+		NodeName:  "node-scheduled",
+	}
+	store.AddInterruptionEvent(rebalanceEvent)
+	store.AddInterruptionEvent(spotITNEvent)
+	store.AddInterruptionEvent(scheduledEvent)
+	h.Equals(t, 3, store.QueueDepth())
+
+	// the spot ITN has the earliest drain deadline, and the scheduled event has a higher severity than the rebalance recommendation
+	for _, expectedEventID := range []string{spotITNEvent.EventID, scheduledEvent.EventID, rebalanceEvent.EventID} {
+		event, ok := store.GetActiveEvent()
+		h.Equals(t, true, ok)
+		h.Equals(t, expectedEventID, event.EventID)
+		h.Assert(t, store.MarkScheduled(event) >= 0, "Expected a non-negative queue wait time")
+		h.Equals(t, monitor.StateScheduled, event.Lifecycle.State)
+	}
+	_, ok := store.GetActiveEvent()
+	h.Equals(t, false, ok)
+	h.Equals(t, 0, store.QueueDepth())
+}
+
+func TestGetActiveEventKindSeverityOverride(t *testing.T) {
+	store := interruptioneventstore.New(config.Config{EventKindSeverity: "REBALANCE_RECOMMENDATION=100"})
+	startTime := time.Now()
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{
+		EventID:   "spot-itn",
+		Kind:      monitor.SpotITNKind,
+		StartTime: startTime,
+	})
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{
+		EventID:   "rebalance",
+		Kind:      monitor.RebalanceRecommendationKind,
+		StartTime: startTime,
+	})
+
+	event, ok := store.GetActiveEvent()
+	h.Equals(t, true, ok)
+	h.Equals(t, "rebalance", event.EventID)
 }
 
 func TestShouldUncordonNode(t *testing.T) {
@@ -160,6 +261,79 @@ func TestIgnoreEvent(t *testing.T) {
 	h.Equals(t, false, store.ShouldDrainNode())
 }
 
+func TestTransition(t *testing.T) {
+	store := interruptioneventstore.New(config.Config{})
+	transitions := []monitor.Transition{}
+	store.AddTransitionObserver(func(event *monitor.InterruptionEvent, transition monitor.Transition, err error) {
+		transitions = append(transitions, transition)
+	})
+	event := &monitor.InterruptionEvent{
+		EventID:   "123",
+		StartTime: time.Now(),
+		NodeName:  node1,
+	}
+	store.AddInterruptionEvent(event)
+	h.Equals(t, monitor.StateReceived, event.Lifecycle.State)
+
+	err := store.Transition(event, monitor.StateDrained, nil)
+	h.Assert(t, err != nil, "Failed to return error for an illegal transition")
+	h.Equals(t, monitor.StateReceived, event.Lifecycle.State)
+
+	store.MarkScheduled(event)
+	h.Ok(t, store.Transition(event, monitor.StateDraining, nil))
+	h.Ok(t, store.Transition(event, monitor.StateFailed, fmt.Errorf("drain timed out")))
+	h.Equals(t, 1, event.Lifecycle.Attempts)
+	h.Equals(t, "drain timed out", event.Lifecycle.LastError)
+
+	expectedStates := []monitor.EventState{monitor.StateReceived, monitor.StateScheduled, monitor.StateDraining, monitor.StateFailed}
+	h.Equals(t, len(expectedStates), len(transitions))
+	for i, state := range expectedStates {
+		h.Equals(t, state, transitions[i].To)
+		h.Equals(t, state, event.Lifecycle.Transitions[i].To)
+	}
+	h.Equals(t, "drain timed out", transitions[3].Error)
+}
+
+func TestAddInterruptionEventAfterFailure(t *testing.T) {
+	store := interruptioneventstore.New(retryConfig)
+	event := &monitor.InterruptionEvent{
+		EventID:   "123",
+		StartTime: time.Now(),
+		NodeName:  node1,
+	}
+	store.AddInterruptionEvent(event)
+	activeEvent, ok := store.GetActiveEvent()
+	h.Equals(t, true, ok)
+	store.MarkScheduled(activeEvent)
+	h.Ok(t, store.Transition(activeEvent, monitor.StatePreDrain, nil))
+	h.Ok(t, store.Transition(activeEvent, monitor.StateFailed, fmt.Errorf("pre-drain failed")))
+	store.ReleaseNode(activeEvent)
+	_, ok = store.GetActiveEvent()
+	h.Equals(t, false, ok)
+	h.Equals(t, true, store.ShouldUncordonNode(node1))
+
+	// the monitor reports the failed event again, so it is retried with its previous lifecycle
+	redelivered := &monitor.InterruptionEvent{
+		EventID:   "123",
+		StartTime: time.Now(),
+		NodeName:  node1,
+	}
+	store.AddInterruptionEvent(redelivered)
+	activeEvent, ok = store.GetActiveEvent()
+	h.Equals(t, true, ok)
+	h.Assert(t, activeEvent == redelivered, "Expected the failed event to be replaced by the reported one")
+	h.Equals(t, monitor.StateReceived, activeEvent.Lifecycle.State)
+	h.Equals(t, 1, activeEvent.Lifecycle.Attempts)
+	h.Equals(t, "pre-drain failed", activeEvent.Lifecycle.LastError)
+	h.Equals(t, false, store.ShouldUncordonNode(node1))
+
+	// an event which is not finished is not replaced
+	store.MarkScheduled(activeEvent)
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{EventID: "123", NodeName: node1})
+	h.Equals(t, monitor.StateScheduled, store.LifecycleState(activeEvent))
+	h.Equals(t, 2, activeEvent.Lifecycle.Attempts)
+}
+
 // BenchmarkDrainEventStore tests concurrent read/write patterns. We don't really care about the timings as long as deadlock doesn't occur
 func BenchmarkDrainEventStore(b *testing.B) {
 	// too many logs can break the Travis build, so we'll disable logging for this test
@@ -0,0 +1,147 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package interruptioneventstore
+
+import (
+	"sort"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+)
+
+// MergeInterruptionEvents hands the drainable events of the node of an in-flight event to the worker processing it,
+// and returns them in queue order.
+//
+// Only events of the given kinds are merged. The merged events are moved to the Scheduled state and are not handed to
+// another worker, the worker moves them along with its own event. Nothing is merged unless the event holds its node.
+func (s *Store) MergeInterruptionEvents(interruptionEvent *monitor.InterruptionEvent, kinds ...string) []*monitor.InterruptionEvent {
+	var notices []transitionNotice
+	defer func() { s.notify(notices) }()
+	defer s.persist()
+	s.Lock()
+	defer s.Unlock()
+	if interruptionEvent.NodeName == "" || s.activeNodes[interruptionEvent.NodeName] != interruptionEvent {
+		return nil
+	}
+
+	items := []*queueItem{}
+	for _, item := range s.queue {
+		if item.event == interruptionEvent || item.event.NodeName != interruptionEvent.NodeName || !s.shouldEventDrain(item.event) || !isKind(item.event.Kind, kinds) {
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].before(items[j]) })
+
+	merged := []*monitor.InterruptionEvent{}
+	for _, item := range items {
+		notice, err := s.transition(item.event, monitor.StateScheduled, nil)
+		if err != nil {
+			log.Warn().Err(err).Msg("Unable to merge interruption event")
+			continue
+		}
+		notices = append(notices, notice)
+		s.dequeue(item.event.EventID)
+		item.event.Lifecycle.MergedInto = interruptionEvent.EventID
+		log.Info().
+			Str("event_id", item.event.EventID).
+			Str("merged_into", interruptionEvent.EventID).
+			Str("node_name", interruptionEvent.NodeName).
+			Msg("Merging interruption event into the in-flight event of its node")
+		merged = append(merged, item.event)
+	}
+	return merged
+}
+
+// MergedEvents returns the events which were merged into an in-flight event and have not been received again since
+func (s *Store) MergedEvents(interruptionEvent *monitor.InterruptionEvent) []*monitor.InterruptionEvent {
+	s.RLock()
+	defer s.RUnlock()
+	merged := []*monitor.InterruptionEvent{}
+	for _, other := range s.interruptionEventStore {
+		if other != interruptionEvent && other.Lifecycle.MergedInto == interruptionEvent.EventID {
+			merged = append(merged, other)
+		}
+	}
+	return merged
+}
+
+// ReleaseNode frees the node of an event once its worker is done with it, so that the queued events of the node can be handed out
+func (s *Store) ReleaseNode(interruptionEvent *monitor.InterruptionEvent) {
+	s.Lock()
+	defer s.Unlock()
+	if interruptionEvent.NodeName != "" && s.activeNodes[interruptionEvent.NodeName] == interruptionEvent {
+		delete(s.activeNodes, interruptionEvent.NodeName)
+	}
+}
+
+// ActiveNodes returns the nodes which are being processed by a worker, and the events holding them
+func (s *Store) ActiveNodes() map[string]*monitor.InterruptionEvent {
+	s.RLock()
+	defer s.RUnlock()
+	activeNodes := make(map[string]*monitor.InterruptionEvent, len(s.activeNodes))
+	for nodeName, interruptionEvent := range s.activeNodes {
+		activeNodes[nodeName] = interruptionEvent
+	}
+	return activeNodes
+}
+
+// AutoScalingGroups returns the ASG of the nodes with a stored event which carries one
+func (s *Store) AutoScalingGroups() map[string]string {
+	s.RLock()
+	defer s.RUnlock()
+	autoScalingGroups := map[string]string{}
+	for _, interruptionEvent := range s.interruptionEventStore {
+		if interruptionEvent.NodeName != "" && interruptionEvent.AutoScalingGroupName != "" {
+			autoScalingGroups[interruptionEvent.NodeName] = interruptionEvent.AutoScalingGroupName
+		}
+	}
+	return autoScalingGroups
+}
+
+// acquireNode marks the node of an event busy, the caller must hold the write lock
+//
+// Events without a node name, such as ASG launch events of instances which have not joined the cluster yet, do not hold a node.
+func (s *Store) acquireNode(interruptionEvent *monitor.InterruptionEvent) {
+	if interruptionEvent.NodeName == "" {
+		return
+	}
+	if active, busy := s.activeNodes[interruptionEvent.NodeName]; busy && active != interruptionEvent {
+		log.Warn().
+			Str("event_id", interruptionEvent.EventID).
+			Str("active_event_id", active.EventID).
+			Str("node_name", interruptionEvent.NodeName).
+			Msg("Interruption event was handed to a worker while its node is busy")
+	}
+	s.activeNodes[interruptionEvent.NodeName] = interruptionEvent
+}
+
+// isNodeBusy returns true if another event holds the node of an event, the caller must hold the lock
+func (s *Store) isNodeBusy(interruptionEvent *monitor.InterruptionEvent) bool {
+	if interruptionEvent.NodeName == "" {
+		return false
+	}
+	active, busy := s.activeNodes[interruptionEvent.NodeName]
+	return busy && active != interruptionEvent
+}
+
+func isKind(kind string, kinds []string) bool {
+	for _, k := range kinds {
+		if kind == k {
+			return true
+		}
+	}
+	return false
+}
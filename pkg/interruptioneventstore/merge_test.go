@@ -0,0 +1,118 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package interruptioneventstore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	"github.com/aws/aws-node-termination-handler/pkg/interruptioneventstore"
+	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+)
+
+func TestGetActiveEventSkipsBusyNode(t *testing.T) {
+	store := interruptioneventstore.New(config.Config{})
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{
+		EventID:   "rebalance",
+		Kind:      monitor.RebalanceRecommendationKind,
+		StartTime: time.Now().Add(-time.Minute),
+		NodeName:  node1,
+	})
+	rebalance, ok := store.GetActiveEvent()
+	h.Equals(t, true, ok)
+	store.MarkScheduled(rebalance)
+
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{
+		EventID:   "spot-itn",
+		Kind:      monitor.SpotITNKind,
+		StartTime: time.Now(),
+		NodeName:  node1,
+	})
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{
+		EventID:   "other-node",
+		Kind:      monitor.RebalanceRecommendationKind,
+		StartTime: time.Now(),
+		NodeName:  "node2",
+	})
+
+	// the spot ITN is more urgent, but its node is busy
+	event, ok := store.GetActiveEvent()
+	h.Equals(t, true, ok)
+	h.Equals(t, "other-node", event.EventID)
+	store.MarkScheduled(event)
+	_, ok = store.GetActiveEvent()
+	h.Equals(t, false, ok)
+	h.Equals(t, 1, store.QueueDepth())
+
+	store.ReleaseNode(rebalance)
+	event, ok = store.GetActiveEvent()
+	h.Equals(t, true, ok)
+	h.Equals(t, "spot-itn", event.EventID)
+}
+
+func TestMergeInterruptionEvents(t *testing.T) {
+	store := interruptioneventstore.New(config.Config{})
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{
+		EventID:   "rebalance",
+		Kind:      monitor.RebalanceRecommendationKind,
+		StartTime: time.Now(),
+		NodeName:  node1,
+	})
+	rebalance, ok := store.GetActiveEvent()
+	h.Equals(t, true, ok)
+
+	// nothing is merged into an event which does not hold its node
+	h.Equals(t, 0, len(store.MergeInterruptionEvents(rebalance, monitor.SpotITNKind)))
+	store.MarkScheduled(rebalance)
+
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{
+		EventID:   "spot-itn",
+		Kind:      monitor.SpotITNKind,
+		StartTime: time.Now(),
+		NodeName:  node1,
+	})
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{
+		EventID:   "scheduled",
+		Kind:      monitor.ScheduledEventKind,
+		StartTime: time.Now().Add(time.Hour),
+		NodeName:  node1,
+	})
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{
+		EventID:   "launch",
+		Kind:      monitor.ASGLaunchLifecycleKind,
+		StartTime: time.Now(),
+		NodeName:  node1,
+	})
+
+	// only drainable events of the given kinds are merged
+	merged := store.MergeInterruptionEvents(rebalance, monitor.SpotITNKind, monitor.ScheduledEventKind)
+	h.Equals(t, 1, len(merged))
+	h.Equals(t, "spot-itn", merged[0].EventID)
+	h.Equals(t, monitor.StateScheduled, merged[0].Lifecycle.State)
+	h.Equals(t, "rebalance", merged[0].Lifecycle.MergedInto)
+	h.Equals(t, 1, len(store.MergedEvents(rebalance)))
+
+	// a merged event is not handed to another worker once the node is released
+	store.ReleaseNode(rebalance)
+	event, ok := store.GetActiveEvent()
+	h.Equals(t, true, ok)
+	h.Equals(t, "launch", event.EventID)
+
+	// a merged event which is received again is processed on its own
+	h.Ok(t, store.Transition(merged[0], monitor.StateReceived, nil))
+	h.Equals(t, "", merged[0].Lifecycle.MergedInto)
+	h.Equals(t, 0, len(store.MergedEvents(rebalance)))
+}
@@ -0,0 +1,308 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package interruptioneventstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+)
+
+const (
+	// stateVersion 2 replaced the InProgress and NodeProcessed flags of events with their lifecycle
+	stateVersion   = 2
+	persistTimeout = 10 * time.Second
+	// persistInterval is the shortest time between two saves of the state, the changes made in between are saved together
+	persistInterval = time.Second
+)
+
+// ErrStateTooLarge is returned by a backend which cannot hold the state of the store
+var ErrStateTooLarge = errors.New("the interruption event store state is too large")
+
+// State is the bookkeeping of the store which is persisted across restarts
+//
+// Drain tasks are not persisted, restored events get them back once their monitor reports them again.
+// Neither are the pods, evictions and labels of the node of an event, which are only needed by the worker processing it.
+type State struct {
+	Version       int                         `json:"version"`
+	Events        []monitor.InterruptionEvent `json:"events"`
+	IgnoredEvents []string                    `json:"ignoredEvents"`
+}
+
+// Backend persists the state of the store
+type Backend interface {
+	// Load returns the last saved state, or an empty state if nothing was saved yet
+	Load(ctx context.Context) (State, error)
+	// Save replaces the saved state
+	Save(ctx context.Context, state State) error
+}
+
+// NewBackend returns the Backend selected by the NTH config, or nil if the store is kept in memory only
+func NewBackend(nthConfig config.Config, clientset kubernetes.Interface) (Backend, error) {
+	switch nthConfig.StoreBackend {
+	case config.StoreBackendConfigMap:
+		return NewConfigMapBackend(clientset, nthConfig.StoreConfigMapNamespace, nthConfig.StoreConfigMapName), nil
+	case config.StoreBackendFile:
+		return NewFileBackend(nthConfig.StoreFilePath), nil
+	case config.StoreBackendMemory, "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown store backend %q", nthConfig.StoreBackend)
+	}
+}
+
+// NodeMarker reports whether NTH already labeled or tainted a node for an event, and removes the marks of events NTH no longer acts on
+type NodeMarker interface {
+	IsMarkedForEvent(nodeName string, eventID string, kind string, data interface{}) (bool, error)
+	Unmark(nodeName string) error
+}
+
+// Restore reloads the persisted state and reconciles restored events with the labels and taints of their nodes
+//
+// Restored unfinished events whose node is marked resume their drain right away, the other ones are not handed to workers
+// until their monitor reports them again, since drain tasks are not persisted.
+// The marks of restored cancelled events are removed, in case NTH stopped before it removed them.
+// Nothing is persisted before Restore is called, so that an early change cannot overwrite the saved state.
+// Restore starts the background writer which saves the changes of the store until Close or Abandon is called.
+func (s *Store) Restore(ctx context.Context, nodeMarker NodeMarker) error {
+	if s.backend == nil {
+		return nil
+	}
+	state, err := s.backend.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	for _, eventID := range state.IgnoredEvents {
+		s.ignoredEvents[eventID] = struct{}{}
+	}
+	unfinishedEvents := []*monitor.InterruptionEvent{}
+	cancelledEvents := []*monitor.InterruptionEvent{}
+	for i := range state.Events {
+		interruptionEvent := &state.Events[i]
+		if _, ok := s.interruptionEventStore[interruptionEvent.EventID]; ok {
+			continue
+		}
+		s.interruptionEventStore[interruptionEvent.EventID] = interruptionEvent
+		_, ignored := s.ignoredEvents[interruptionEvent.EventID]
+		if !ignored {
+			s.atLeastOneEvent = true
+		}
+		if !ignored && !isFinished(interruptionEvent.Lifecycle.State) {
+			s.restoredEvents[interruptionEvent.EventID] = struct{}{}
+			unfinishedEvents = append(unfinishedEvents, interruptionEvent)
+		}
+		if !ignored && interruptionEvent.Lifecycle.State == monitor.StateCancelled && interruptionEvent.NodeName != "" {
+			cancelledEvents = append(cancelledEvents, interruptionEvent)
+		}
+	}
+	s.Unlock()
+
+	for _, interruptionEvent := range unfinishedEvents {
+		s.reconcile(interruptionEvent, nodeMarker)
+	}
+	for _, interruptionEvent := range cancelledEvents {
+		s.unmarkCancelled(interruptionEvent, nodeMarker)
+	}
+	log.Info().
+		Int("events", len(state.Events)).
+		Int("ignored_events", len(state.IgnoredEvents)).
+		Int("unfinished_events", len(unfinishedEvents)).
+		Msg("Restored the interruption event store")
+
+	s.saveMutex.Lock()
+	s.stateLoaded = true
+	s.saveMutex.Unlock()
+	go s.writeState()
+	s.persist()
+	return nil
+}
+
+// Close stops the background writer and saves the changes it has not saved yet
+func (s *Store) Close() {
+	if s.backend == nil {
+		return
+	}
+	s.closeOnce.Do(func() { close(s.stopWriter) })
+	s.save()
+}
+
+// Abandon stops the background writer without saving, once NTH is no longer entitled to write the state,
+// e.g. after it lost its leadership. It waits for a save in progress, and nothing is saved afterwards.
+func (s *Store) Abandon() {
+	if s.backend == nil {
+		return
+	}
+	s.closeOnce.Do(func() { close(s.stopWriter) })
+	s.saveMutex.Lock()
+	s.abandoned = true
+	s.saveMutex.Unlock()
+}
+
+// reconcile updates a restored unfinished event with what NTH already did to its node
+func (s *Store) reconcile(interruptionEvent *monitor.InterruptionEvent, nodeMarker NodeMarker) {
+	marked := false
+	var err error
+	if interruptionEvent.NodeName != "" {
+		marked, err = nodeMarker.IsMarkedForEvent(interruptionEvent.NodeName, interruptionEvent.EventID, interruptionEvent.Kind, *interruptionEvent)
+	}
+
+	var notices []transitionNotice
+	defer func() { s.notify(notices) }()
+	s.Lock()
+	defer s.Unlock()
+	// the worker which had the event in flight is gone, the event is processed again once its monitor reports it
+	if interruptionEvent.Lifecycle.State != monitor.StateReceived {
+		if notice, err := s.transition(interruptionEvent, monitor.StateReceived, nil); err == nil {
+			notices = append(notices, notice)
+		}
+	}
+	switch {
+	case apierrors.IsNotFound(err):
+		log.Info().Str("event_id", interruptionEvent.EventID).Str("node_name", interruptionEvent.NodeName).Msg("Node of restored event no longer exists, completing the event")
+		if notice, err := s.transition(interruptionEvent, monitor.StateCompleted, nil); err == nil {
+			notices = append(notices, notice)
+		}
+		delete(s.restoredEvents, interruptionEvent.EventID)
+	case err != nil:
+		log.Warn().Err(err).Str("event_id", interruptionEvent.EventID).Str("node_name", interruptionEvent.NodeName).Msg("Unable to reconcile restored event with its node")
+	case marked:
+		// the drain is resumed without its drain tasks, which the event gets back if its monitor reports it again
+		log.Info().Str("event_id", interruptionEvent.EventID).Str("node_name", interruptionEvent.NodeName).Msg("Node was already marked for restored event, resuming its drain")
+		delete(s.restoredEvents, interruptionEvent.EventID)
+		s.resumedEvents[interruptionEvent.EventID] = struct{}{}
+		s.enqueue(interruptionEvent)
+	}
+}
+
+// unmarkCancelled removes the marks left on the node of a restored cancelled event, unless another event of the node is unfinished
+func (s *Store) unmarkCancelled(interruptionEvent *monitor.InterruptionEvent, nodeMarker NodeMarker) {
+	if !s.ShouldUncordonNode(interruptionEvent.NodeName) {
+		return
+	}
+	marked, err := nodeMarker.IsMarkedForEvent(interruptionEvent.NodeName, interruptionEvent.EventID, interruptionEvent.Kind, *interruptionEvent)
+	if err != nil || !marked {
+		return
+	}
+	log.Info().Str("event_id", interruptionEvent.EventID).Str("node_name", interruptionEvent.NodeName).Msg("Node is still marked for restored cancelled event, removing the marks")
+	if err := nodeMarker.Unmark(interruptionEvent.NodeName); err != nil {
+		log.Warn().Err(err).Str("event_id", interruptionEvent.EventID).Str("node_name", interruptionEvent.NodeName).Msg("Unable to remove the marks of restored cancelled event")
+	}
+}
+
+// persist asks the background writer to save the store with the backend
+//
+// Saves are coalesced, so that a burst of changes is written once. It never blocks, so callers may hold the store lock.
+func (s *Store) persist() {
+	if s.backend == nil {
+		return
+	}
+	select {
+	case s.persistRequests <- struct{}{}:
+	default:
+		// a save is already pending, which will include this change
+	}
+}
+
+// writeState saves the store whenever it changes, at most once per persistInterval, until Close or Abandon is called
+func (s *Store) writeState() {
+	for {
+		select {
+		case <-s.stopWriter:
+			return
+		case <-s.persistRequests:
+		}
+		s.save()
+		select {
+		case <-s.stopWriter:
+			return
+		case <-time.After(persistInterval):
+		}
+	}
+}
+
+// save writes a snapshot of the store with the backend, once the saved state has been restored and until the store is abandoned
+func (s *Store) save() {
+	s.saveMutex.Lock()
+	defer s.saveMutex.Unlock()
+	if !s.stateLoaded || s.abandoned {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), persistTimeout)
+	defer cancel()
+	err := s.backend.Save(ctx, s.snapshot())
+	switch {
+	case errors.Is(err, ErrStateTooLarge):
+		log.Error().Err(err).Msg("Unable to persist the interruption event store, its events are lost if NTH restarts until it shrinks")
+	case err != nil:
+		log.Err(err).Msg("Unable to persist the interruption event store")
+	}
+}
+
+// snapshot returns the state of the store, with only what a restored event needs to resume
+func (s *Store) snapshot() State {
+	s.RLock()
+	defer s.RUnlock()
+	state := State{
+		Events:        make([]monitor.InterruptionEvent, 0, len(s.interruptionEventStore)),
+		IgnoredEvents: make([]string, 0, len(s.ignoredEvents)),
+	}
+	for _, interruptionEvent := range s.interruptionEventStore {
+		persistedEvent := *interruptionEvent
+		persistedEvent.NodeLabels = nil
+		persistedEvent.Pods = nil
+		persistedEvent.Evictions = nil
+		state.Events = append(state.Events, persistedEvent)
+	}
+	for eventID := range s.ignoredEvents {
+		state.IgnoredEvents = append(state.IgnoredEvents, eventID)
+	}
+	sort.Slice(state.Events, func(i, j int) bool { return state.Events[i].EventID < state.Events[j].EventID })
+	sort.Strings(state.IgnoredEvents)
+	return state
+}
+
+func encodeState(state State) ([]byte, error) {
+	state.Version = stateVersion
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode the interruption event store state: %w", err)
+	}
+	return data, nil
+}
+
+func decodeState(data []byte) (State, error) {
+	state := State{}
+	if len(data) == 0 {
+		return state, nil
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return State{}, fmt.Errorf("unable to decode the interruption event store state: %w", err)
+	}
+	if state.Version > stateVersion {
+		return State{}, fmt.Errorf("unsupported interruption event store state version %d", state.Version)
+	}
+	return state, nil
+}
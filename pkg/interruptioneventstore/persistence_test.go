@@ -0,0 +1,292 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package interruptioneventstore_test
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	"github.com/aws/aws-node-termination-handler/pkg/interruptioneventstore"
+	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+	"github.com/aws/aws-node-termination-handler/pkg/node"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+)
+
+type mockNodeMarker struct {
+	markedEvents  map[string]bool
+	missingNodes  map[string]bool
+	unmarkedNodes map[string]bool
+}
+
+func (m mockNodeMarker) IsMarkedForEvent(nodeName string, eventID string, _ string, _ interface{}) (bool, error) {
+	if m.missingNodes[nodeName] {
+		return false, errors.NewNotFound(schema.GroupResource{Resource: "nodes"}, nodeName)
+	}
+	return m.markedEvents[eventID], nil
+}
+
+func (m mockNodeMarker) Unmark(nodeName string) error {
+	m.unmarkedNodes[nodeName] = true
+	return nil
+}
+
+func TestFileBackend(t *testing.T) {
+	backend := interruptioneventstore.NewFileBackend(filepath.Join(t.TempDir(), "nth", "state.json"))
+
+	state, err := backend.Load(context.Background())
+	h.Ok(t, err)
+	h.Equals(t, 0, len(state.Events))
+
+	err = backend.Save(context.Background(), interruptioneventstore.State{
+		Events:        []monitor.InterruptionEvent{{EventID: "1", NodeName: node1, Lifecycle: monitor.Lifecycle{State: monitor.StateDraining, Attempts: 1}}},
+		IgnoredEvents: []string{"2"},
+	})
+	h.Ok(t, err)
+
+	state, err = backend.Load(context.Background())
+	h.Ok(t, err)
+	h.Equals(t, 1, len(state.Events))
+	h.Equals(t, "1", state.Events[0].EventID)
+	h.Equals(t, monitor.StateDraining, state.Events[0].Lifecycle.State)
+	h.Equals(t, 1, state.Events[0].Lifecycle.Attempts)
+	h.Equals(t, []string{"2"}, state.IgnoredEvents)
+}
+
+func TestConfigMapBackend(t *testing.T) {
+	backend := interruptioneventstore.NewConfigMapBackend(fake.NewSimpleClientset(), "kube-system", "nth-state")
+
+	state, err := backend.Load(context.Background())
+	h.Ok(t, err)
+	h.Equals(t, 0, len(state.Events))
+
+	for _, eventID := range []string{"1", "2"} {
+		err = backend.Save(context.Background(), interruptioneventstore.State{
+			Events: []monitor.InterruptionEvent{{EventID: eventID, NodeName: node1}},
+		})
+		h.Ok(t, err)
+	}
+
+	state, err = backend.Load(context.Background())
+	h.Ok(t, err)
+	h.Equals(t, 1, len(state.Events))
+	h.Equals(t, "2", state.Events[0].EventID)
+}
+
+func TestRestore(t *testing.T) {
+	backend := interruptioneventstore.NewFileBackend(filepath.Join(t.TempDir(), "state.json"))
+	startTime := time.Now()
+	err := backend.Save(context.Background(), interruptioneventstore.State{
+		Events: []monitor.InterruptionEvent{
+			{EventID: "processed", NodeName: node1, StartTime: startTime, Lifecycle: monitor.Lifecycle{State: monitor.StateCompleted}},
+			{EventID: "in-progress", NodeName: node1, StartTime: startTime, Lifecycle: monitor.Lifecycle{State: monitor.StateDraining, Attempts: 1}},
+			{EventID: "waiting", NodeName: "waiting", StartTime: startTime, Lifecycle: monitor.Lifecycle{State: monitor.StateScheduled, Attempts: 1}},
+			{EventID: "node-gone", NodeName: "gone", StartTime: startTime, Lifecycle: monitor.Lifecycle{State: monitor.StateScheduled, Attempts: 1}},
+			{EventID: "cancelled", NodeName: "cancelled", StartTime: startTime, Lifecycle: monitor.Lifecycle{State: monitor.StateCancelled}},
+		},
+		IgnoredEvents: []string{"ignored"},
+	})
+	h.Ok(t, err)
+
+	store := interruptioneventstore.NewWithBackend(config.Config{}, backend)
+	// changes made before the state is restored must not overwrite it
+	store.IgnoreEvent("early")
+	nodeMarker := mockNodeMarker{
+		markedEvents:  map[string]bool{"in-progress": true, "cancelled": true},
+		missingNodes:  map[string]bool{"gone": true},
+		unmarkedNodes: map[string]bool{},
+	}
+	err = store.Restore(context.Background(), nodeMarker)
+	h.Ok(t, err)
+	// the marks of the cancelled event are removed
+	h.Equals(t, map[string]bool{"cancelled": true}, nodeMarker.unmarkedNodes)
+
+	// the drain of the event whose node is marked resumes right away
+	event, ok := store.GetActiveEvent()
+	h.Equals(t, true, ok)
+	h.Equals(t, "in-progress", event.EventID)
+	h.Assert(t, event.PreDrainTask == nil, "Expected the resumed event to have no drain tasks")
+
+	// until it is handed to a worker, the resumed event gets its drain tasks back when its monitor reports it again
+	reported := &monitor.InterruptionEvent{EventID: "in-progress", NodeName: node1, StartTime: startTime}
+	reported.PreDrainTask = func(context.Context, monitor.InterruptionEvent, node.Node) error { return nil }
+	store.AddInterruptionEvent(reported)
+	event, ok = store.GetActiveEvent()
+	h.Equals(t, true, ok)
+	h.Equals(t, reported, event)
+	store.MarkScheduled(event)
+
+	// the other restored unfinished events wait for their monitor to report them again
+	_, ok = store.GetActiveEvent()
+	h.Equals(t, false, ok)
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{EventID: "processed", NodeName: node1, StartTime: startTime})
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{EventID: "ignored", NodeName: node1, StartTime: startTime})
+	_, ok = store.GetActiveEvent()
+	h.Equals(t, false, ok)
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{EventID: "waiting", NodeName: "waiting", StartTime: startTime})
+	event, ok = store.GetActiveEvent()
+	h.Equals(t, true, ok)
+	h.Equals(t, "waiting", event.EventID)
+	store.Close()
+
+	state, err := backend.Load(context.Background())
+	h.Ok(t, err)
+	h.Equals(t, []string{"early", "ignored"}, state.IgnoredEvents)
+	h.Equals(t, 6, len(state.Events))
+	for _, persistedEvent := range state.Events {
+		switch persistedEvent.EventID {
+		case "in-progress":
+			// the restored event went back to Received and was scheduled again
+			h.Equals(t, monitor.StateScheduled, persistedEvent.Lifecycle.State)
+			h.Equals(t, 2, persistedEvent.Lifecycle.Attempts)
+		case "node-gone", "processed":
+			h.Equals(t, monitor.StateCompleted, persistedEvent.Lifecycle.State)
+		case "ignored", "waiting":
+			h.Equals(t, monitor.StateReceived, persistedEvent.Lifecycle.State)
+		case "cancelled":
+			h.Equals(t, monitor.StateCancelled, persistedEvent.Lifecycle.State)
+		default:
+			t.Errorf("unexpected persisted event %s", persistedEvent.EventID)
+		}
+	}
+}
+
+func TestRestoreResumedEventCompletedWithoutDrainTasks(t *testing.T) {
+	backend := interruptioneventstore.NewFileBackend(filepath.Join(t.TempDir(), "state.json"))
+	startTime := time.Now()
+	err := backend.Save(context.Background(), interruptioneventstore.State{
+		Events: []monitor.InterruptionEvent{
+			{EventID: "in-progress", NodeName: node1, StartTime: startTime, Lifecycle: monitor.Lifecycle{State: monitor.StateDraining, Attempts: 1}},
+		},
+	})
+	h.Ok(t, err)
+	store := interruptioneventstore.NewWithBackend(config.Config{}, backend)
+	h.Ok(t, store.Restore(context.Background(), mockNodeMarker{markedEvents: map[string]bool{"in-progress": true}}))
+	defer store.Close()
+
+	event, ok := store.GetActiveEvent()
+	h.Equals(t, true, ok)
+	store.MarkScheduled(event)
+	// the monitor reporting the event while it is processed is dropped
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{EventID: "in-progress", NodeName: node1, StartTime: startTime})
+	h.Ok(t, store.Transition(event, monitor.StateCompleted, nil))
+	store.ReleaseNode(event)
+	_, ok = store.GetActiveEvent()
+	h.Equals(t, false, ok)
+
+	// once completed, the event is processed again so that its drain tasks run
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{EventID: "in-progress", NodeName: node1, StartTime: startTime})
+	event, ok = store.GetActiveEvent()
+	h.Equals(t, true, ok)
+	h.Equals(t, monitor.StateReceived, event.Lifecycle.State)
+	h.Equals(t, 0, event.Lifecycle.Attempts)
+
+	// and only once
+	store.MarkScheduled(event)
+	h.Ok(t, store.Transition(event, monitor.StateCompleted, nil))
+	store.ReleaseNode(event)
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{EventID: "in-progress", NodeName: node1, StartTime: startTime})
+	_, ok = store.GetActiveEvent()
+	h.Equals(t, false, ok)
+}
+
+type countingBackend struct {
+	sync.Mutex
+	saves int
+	state interruptioneventstore.State
+}
+
+func (b *countingBackend) Load(_ context.Context) (interruptioneventstore.State, error) {
+	return interruptioneventstore.State{}, nil
+}
+
+func (b *countingBackend) Save(_ context.Context, state interruptioneventstore.State) error {
+	b.Lock()
+	defer b.Unlock()
+	b.saves++
+	b.state = state
+	return nil
+}
+
+func TestPersistCoalescesChanges(t *testing.T) {
+	backend := &countingBackend{}
+	store := interruptioneventstore.NewWithBackend(config.Config{}, backend)
+	h.Ok(t, store.Restore(context.Background(), mockNodeMarker{}))
+	for i := 0; i < 50; i++ {
+		store.AddInterruptionEvent(&monitor.InterruptionEvent{
+			EventID:    fmt.Sprintf("event-%d", i),
+			NodeName:   node1,
+			StartTime:  time.Now(),
+			NodeLabels: map[string]string{"label": "value"},
+			Pods:       []string{"pod"},
+		})
+	}
+	store.Close()
+
+	backend.Lock()
+	defer backend.Unlock()
+	h.Assert(t, backend.saves < 10, "Expected the changes to be saved together, got %d saves", backend.saves)
+	h.Equals(t, 50, len(backend.state.Events))
+	// only what a restored event needs to resume is persisted
+	h.Assert(t, backend.state.Events[0].NodeLabels == nil, "Expected the node labels not to be persisted")
+	h.Assert(t, backend.state.Events[0].Pods == nil, "Expected the pods not to be persisted")
+}
+
+func TestAbandonStopsSaving(t *testing.T) {
+	backend := &countingBackend{}
+	store := interruptioneventstore.NewWithBackend(config.Config{}, backend)
+	h.Ok(t, store.Restore(context.Background(), mockNodeMarker{}))
+	store.Abandon()
+	backend.Lock()
+	saves := backend.saves
+	backend.Unlock()
+
+	// the changes made once the store is abandoned are not saved, not even when it is closed
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{EventID: "123", NodeName: node1, StartTime: time.Now()})
+	store.Close()
+
+	backend.Lock()
+	defer backend.Unlock()
+	h.Equals(t, saves, backend.saves)
+	h.Equals(t, 0, len(backend.state.Events))
+}
+
+func TestConfigMapBackendStateTooLarge(t *testing.T) {
+	backend := interruptioneventstore.NewConfigMapBackend(fake.NewSimpleClientset(), "kube-system", "nth-state")
+	state := interruptioneventstore.State{}
+	for i := 0; i < 10000; i++ {
+		state.Events = append(state.Events, monitor.InterruptionEvent{EventID: fmt.Sprintf("event-%d", i), NodeName: node1, Description: strings.Repeat("x", 100)})
+	}
+	err := backend.Save(context.Background(), state)
+	h.Assert(t, goerrors.Is(err, interruptioneventstore.ErrStateTooLarge), "Expected ErrStateTooLarge, got %v", err)
+}
+
+func TestNewBackend(t *testing.T) {
+	backend, err := interruptioneventstore.NewBackend(config.Config{StoreBackend: config.StoreBackendMemory}, nil)
+	h.Ok(t, err)
+	h.Assert(t, backend == nil, "Expected no backend for the memory store")
+
+	_, err = interruptioneventstore.NewBackend(config.Config{StoreBackend: "unknown"}, nil)
+	h.Assert(t, err != nil, "Failed to return error for an unknown store backend")
+}
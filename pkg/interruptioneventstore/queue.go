@@ -0,0 +1,83 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package interruptioneventstore
+
+import (
+	"time"
+
+	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+)
+
+// queueItem is an event waiting in the queue for a free worker
+type queueItem struct {
+	event         *monitor.InterruptionEvent
+	drainDeadline time.Time
+	severity      int
+	enqueuedAt    time.Time
+	index         int
+}
+
+// eventQueue implements heap.Interface, ordering events by drain deadline and then by severity
+type eventQueue []*queueItem
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool {
+	return q[i].before(q[j])
+}
+
+func (q eventQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *eventQueue) Push(x any) {
+	item := x.(*queueItem)
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+func (q *eventQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*q = old[:n-1]
+	return item
+}
+
+// before returns true if item should be handed to a worker before other
+func (item *queueItem) before(other *queueItem) bool {
+	if !item.drainDeadline.Equal(other.drainDeadline) {
+		return item.drainDeadline.Before(other.drainDeadline)
+	}
+	if item.severity != other.severity {
+		return item.severity > other.severity
+	}
+	return item.event.EventID < other.event.EventID
+}
+
+// waitTime returns how long the item has been drainable without being handed to a worker
+func (item *queueItem) waitTime(now time.Time) time.Duration {
+	waitingSince := item.enqueuedAt
+	if item.drainDeadline.After(waitingSince) {
+		waitingSince = item.drainDeadline
+	}
+	if now.Before(waitingSince) {
+		return 0
+	}
+	return now.Sub(waitingSince)
+}
@@ -0,0 +1,132 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package interruptioneventstore
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+)
+
+// RetryPolicy decides whether and when a failed event is handed to a worker again
+type RetryPolicy struct {
+	// MaxAttempts is the number of times an event is handed to a worker before it is left failed
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, it doubles with every failed attempt
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries
+	MaxBackoff time.Duration
+	// Deadline is the period after the start time of an event past which it is no longer retried
+	Deadline time.Duration
+}
+
+// NewRetryPolicy returns the retry policy set by the NTH config
+func NewRetryPolicy(nthConfig config.Config) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    nthConfig.DrainRetryMaxAttempts,
+		InitialBackoff: time.Duration(nthConfig.DrainRetryInitialBackoff) * time.Second,
+		MaxBackoff:     time.Duration(nthConfig.DrainRetryMaxBackoff) * time.Second,
+		Deadline:       time.Duration(nthConfig.DrainRetryDeadline) * time.Second,
+	}
+}
+
+// Backoff returns the delay before the next attempt of an event which failed the given number of attempts
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempts && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	// equal jitter keeps at least half of the backoff while spreading the retries of nodes which failed together
+	half := backoff / 2
+	if half <= 0 {
+		return backoff
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// RetryInterruptionEvent hands a failed event back to the queue once its backoff expires, and returns the backoff and true,
+// or returns false if the event has used up its attempts or the retry would start past its deadline.
+//
+// Either way, an event which its monitor reports again is not handed to a worker before the backoff expires.
+func (s *Store) RetryInterruptionEvent(interruptionEvent *monitor.InterruptionEvent) (time.Duration, bool) {
+	var notices []transitionNotice
+	defer func() { s.notify(notices) }()
+	defer s.persist()
+	s.Lock()
+	defer s.Unlock()
+	if interruptionEvent.Lifecycle.State != monitor.StateFailed {
+		return 0, false
+	}
+	now := time.Now()
+	attempts := interruptionEvent.Lifecycle.Attempts
+	backoff := s.retryPolicy.Backoff(attempts)
+	interruptionEvent.Lifecycle.NextAttempt = now.Add(backoff)
+	logger := log.With().Str("event_id", interruptionEvent.EventID).Int("attempts", attempts).Logger()
+	if attempts >= s.retryPolicy.MaxAttempts {
+		logger.Warn().Int("max_attempts", s.retryPolicy.MaxAttempts).Msg("Interruption event used up its attempts, not retrying")
+		interruptionEvent.Lifecycle.Exhausted = true
+		return 0, false
+	}
+	if retryDeadline := interruptionEvent.StartTime.Add(s.retryPolicy.Deadline); interruptionEvent.Lifecycle.NextAttempt.After(retryDeadline) {
+		logger.Warn().Time("retry_deadline", retryDeadline).Msg("Interruption event would be retried past its deadline, not retrying")
+		interruptionEvent.Lifecycle.Exhausted = true
+		return 0, false
+	}
+	if s.interruptionEventStore[interruptionEvent.EventID] != interruptionEvent {
+		// the event was replaced or cleaned meanwhile
+		return 0, false
+	}
+
+	notice, err := s.transition(interruptionEvent, monitor.StateReceived, nil)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Unable to retry interruption event")
+		return 0, false
+	}
+	notices = append(notices, notice)
+	if _, ignored := s.ignoredEvents[interruptionEvent.EventID]; !ignored {
+		s.enqueue(interruptionEvent)
+	}
+	logger.Info().Dur("backoff", backoff).Msg("Retrying failed interruption event")
+	return backoff, true
+}
+
+// GiveUpInterruptionEvent leaves a failed event failed for good, e.g. after a terminal error,
+// so that it is not processed again when its monitor reports it again
+func (s *Store) GiveUpInterruptionEvent(interruptionEvent *monitor.InterruptionEvent) {
+	defer s.persist()
+	s.Lock()
+	defer s.Unlock()
+	if interruptionEvent.Lifecycle.State == monitor.StateFailed {
+		interruptionEvent.Lifecycle.Exhausted = true
+	}
+}
+
+// allowsRetry returns true if a failed event may be handed to a worker again, which is once its backoff expires
+func (p RetryPolicy) allowsRetry(interruptionEvent *monitor.InterruptionEvent, now time.Time) bool {
+	if interruptionEvent.Lifecycle.Exhausted || interruptionEvent.Lifecycle.Attempts >= p.MaxAttempts {
+		return false
+	}
+	nextAttempt := now
+	if interruptionEvent.Lifecycle.NextAttempt.After(now) {
+		nextAttempt = interruptionEvent.Lifecycle.NextAttempt
+	}
+	return !nextAttempt.After(interruptionEvent.StartTime.Add(p.Deadline))
+}
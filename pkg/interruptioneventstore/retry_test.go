@@ -0,0 +1,140 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package interruptioneventstore_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	"github.com/aws/aws-node-termination-handler/pkg/interruptioneventstore"
+	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+)
+
+var retryConfig = config.Config{
+	DrainRetryMaxAttempts:    2,
+	DrainRetryInitialBackoff: 1,
+	DrainRetryMaxBackoff:     4,
+	DrainRetryDeadline:       120,
+}
+
+// failActiveEvent hands the active event to a worker which fails to drain it
+func failActiveEvent(t *testing.T, store *interruptioneventstore.Store) *monitor.InterruptionEvent {
+	event, ok := store.GetActiveEvent()
+	h.Equals(t, true, ok)
+	store.MarkScheduled(event)
+	h.Ok(t, store.Transition(event, monitor.StateDraining, nil))
+	h.Ok(t, store.Transition(event, monitor.StateFailed, errors.New("too many requests")))
+	return event
+}
+
+func TestRetryInterruptionEvent(t *testing.T) {
+	store := interruptioneventstore.New(retryConfig)
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{
+		EventID:   "123",
+		StartTime: time.Now(),
+		NodeName:  node1,
+	})
+
+	event := failActiveEvent(t, store)
+	backoff, ok := store.RetryInterruptionEvent(event)
+	h.Equals(t, true, ok)
+	h.Assert(t, backoff >= 500*time.Millisecond && backoff <= time.Second, "Expected the first backoff to be within the initial backoff")
+	h.Equals(t, monitor.StateReceived, event.Lifecycle.State)
+	h.Equals(t, "too many requests", event.Lifecycle.LastError)
+
+	// the retry waits for its backoff
+	_, ok = store.GetActiveEvent()
+	h.Equals(t, false, ok)
+	h.Equals(t, 1, len(store.PendingEvents()))
+	time.Sleep(backoff)
+	retriedEvent, ok := store.GetActiveEvent()
+	h.Equals(t, true, ok)
+	h.Assert(t, retriedEvent == event, "Expected the failed event to be retried")
+
+	// the event used up its attempts
+	event = failActiveEvent(t, store)
+	_, ok = store.RetryInterruptionEvent(event)
+	h.Equals(t, false, ok)
+	h.Equals(t, monitor.StateFailed, event.Lifecycle.State)
+	h.Equals(t, 2, event.Lifecycle.Attempts)
+
+	// an event reported again by its monitor still honours the backoff
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{
+		EventID:   "123",
+		StartTime: time.Now(),
+		NodeName:  node1,
+	})
+	_, ok = store.GetActiveEvent()
+	h.Equals(t, false, ok)
+}
+
+func TestRetryInterruptionEventPastDeadline(t *testing.T) {
+	store := interruptioneventstore.New(retryConfig)
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{
+		EventID:   "123",
+		StartTime: time.Now().Add(-2 * time.Minute),
+		NodeName:  node1,
+	})
+
+	event := failActiveEvent(t, store)
+	_, ok := store.RetryInterruptionEvent(event)
+	h.Equals(t, false, ok)
+	h.Equals(t, monitor.StateFailed, event.Lifecycle.State)
+}
+
+func TestAddInterruptionEventAfterGivingUp(t *testing.T) {
+	store := interruptioneventstore.New(config.Config{
+		DrainRetryMaxAttempts:    1,
+		DrainRetryInitialBackoff: 1,
+		DrainRetryMaxBackoff:     1,
+		DrainRetryDeadline:       120,
+	})
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{EventID: "exhausted", StartTime: time.Now().Add(-time.Second), NodeName: node1})
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{EventID: "terminal", StartTime: time.Now(), NodeName: "node2"})
+
+	// the first event used up its attempts
+	exhausted := failActiveEvent(t, store)
+	h.Equals(t, "exhausted", exhausted.EventID)
+	_, ok := store.RetryInterruptionEvent(exhausted)
+	h.Equals(t, false, ok)
+	h.Equals(t, true, exhausted.Lifecycle.Exhausted)
+	store.ReleaseNode(exhausted)
+
+	// the second event failed with a terminal error
+	terminal := failActiveEvent(t, store)
+	h.Equals(t, "terminal", terminal.EventID)
+	store.GiveUpInterruptionEvent(terminal)
+	h.Equals(t, true, terminal.Lifecycle.Exhausted)
+	store.ReleaseNode(terminal)
+
+	// neither is processed again when their monitor reports them again, even once their backoff expired
+	time.Sleep(time.Second)
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{EventID: "exhausted", StartTime: time.Now(), NodeName: node1})
+	store.AddInterruptionEvent(&monitor.InterruptionEvent{EventID: "terminal", StartTime: time.Now(), NodeName: "node2"})
+	_, ok = store.GetActiveEvent()
+	h.Equals(t, false, ok)
+	h.Equals(t, monitor.StateFailed, store.LifecycleState(exhausted))
+	h.Equals(t, monitor.StateFailed, store.LifecycleState(terminal))
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := interruptioneventstore.NewRetryPolicy(config.Config{DrainRetryInitialBackoff: 2, DrainRetryMaxBackoff: 10})
+	for attempts, maxBackoff := range map[int]time.Duration{1: 2 * time.Second, 2: 4 * time.Second, 3: 8 * time.Second, 10: 10 * time.Second} {
+		backoff := policy.Backoff(attempts)
+		h.Assert(t, backoff >= maxBackoff/2 && backoff <= maxBackoff, "Expected backoff of attempt %d within [%v, %v] but got %v", attempts, maxBackoff/2, maxBackoff, backoff)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package leaderelection
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	k8sleaderelection "k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+)
+
+// Elector campaigns for a coordination.k8s.io Lease so that only one replica processes events at a time
+type Elector struct {
+	identity string
+	elector  *k8sleaderelection.LeaderElector
+	leading  chan struct{}
+	lost     chan struct{}
+	done     chan struct{}
+}
+
+// New creates a new Elector for the lease configured in nthConfig
+func New(nthConfig config.Config, clientset kubernetes.Interface) (*Elector, error) {
+	identity := nthConfig.PodName
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("determining leader election identity: %w", err)
+		}
+		identity = hostname
+	}
+
+	e := &Elector{
+		identity: identity,
+		leading:  make(chan struct{}),
+		lost:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      nthConfig.LeaderElectionLeaseName,
+			Namespace: nthConfig.LeaderElectionNamespace,
+		},
+		Client: clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+
+	elector, err := k8sleaderelection.NewLeaderElector(k8sleaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   time.Duration(nthConfig.LeaderElectionLeaseDuration) * time.Second,
+		RenewDeadline:   time.Duration(nthConfig.LeaderElectionRenewDeadline) * time.Second,
+		RetryPeriod:     time.Duration(nthConfig.LeaderElectionRetryPeriod) * time.Second,
+		ReleaseOnCancel: true,
+		Name:            nthConfig.LeaderElectionLeaseName,
+		Callbacks: k8sleaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				log.Info().Str("identity", identity).Msg("Acquired leader election lease")
+				close(e.leading)
+			},
+			OnStoppedLeading: func() {
+				log.Info().Str("identity", identity).Msg("Stopped leading")
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					log.Info().Str("leader", leader).Msg("Observed new leader, standing by")
+				}
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating leader elector for lease %s/%s: %w", nthConfig.LeaderElectionNamespace, nthConfig.LeaderElectionLeaseName, err)
+	}
+	e.elector = elector
+	return e, nil
+}
+
+// Run campaigns for and then holds the lease until ctx is done or the lease could not be renewed.
+// The lease is released when ctx is done so that a standby can take over without waiting for it to expire.
+func (e *Elector) Run(ctx context.Context) {
+	defer close(e.done)
+	log.Info().Str("identity", e.identity).Msg("Waiting to acquire leader election lease")
+	e.elector.Run(ctx)
+	if ctx.Err() == nil {
+		close(e.lost)
+	}
+}
+
+// Leading is closed once this replica has acquired the lease
+func (e *Elector) Leading() <-chan struct{} {
+	return e.leading
+}
+
+// Lost is closed if this replica stopped holding the lease before Run's ctx was done
+func (e *Elector) Lost() <-chan struct{} {
+	return e.lost
+}
+
+// Done is closed once Run has returned and the lease has been released
+func (e *Elector) Done() <-chan struct{} {
+	return e.done
+}
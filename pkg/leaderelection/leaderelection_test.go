@@ -0,0 +1,119 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package leaderelection_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	"github.com/aws/aws-node-termination-handler/pkg/leaderelection"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+)
+
+const (
+	leaseName      = "aws-node-termination-handler"
+	leaseNamespace = "kube-system"
+	podName        = "aws-node-termination-handler-0"
+)
+
+func getConfig() config.Config {
+	return config.Config{
+		PodName:                     podName,
+		LeaderElectionLeaseName:     leaseName,
+		LeaderElectionNamespace:     leaseNamespace,
+		LeaderElectionLeaseDuration: 15,
+		LeaderElectionRenewDeadline: 10,
+		LeaderElectionRetryPeriod:   2,
+	}
+}
+
+func TestNew_InvalidDurations(t *testing.T) {
+	nthConfig := getConfig()
+	nthConfig.LeaderElectionRenewDeadline = nthConfig.LeaderElectionRetryPeriod
+	_, err := leaderelection.New(nthConfig, fake.NewSimpleClientset())
+	h.Assert(t, err != nil, "Expected an error when renew deadline is not greater than the retry period")
+}
+
+func TestRun_AcquireAndRelease(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	elector, err := leaderelection.New(getConfig(), client)
+	h.Ok(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go elector.Run(ctx)
+
+	select {
+	case <-elector.Leading():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the lease to be acquired")
+	}
+	lease, err := client.CoordinationV1().Leases(leaseNamespace).Get(context.Background(), leaseName, metav1.GetOptions{})
+	h.Ok(t, err)
+	h.Equals(t, podName, *lease.Spec.HolderIdentity)
+
+	cancel()
+	select {
+	case <-elector.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the elector to stop once its context was canceled")
+	}
+	lease, err = client.CoordinationV1().Leases(leaseNamespace).Get(context.Background(), leaseName, metav1.GetOptions{})
+	h.Ok(t, err)
+	h.Equals(t, "", *lease.Spec.HolderIdentity)
+
+	select {
+	case <-elector.Lost():
+		t.Fatal("Canceling the elector should not be reported as a lost lease")
+	default:
+	}
+}
+
+func TestRun_Standby(t *testing.T) {
+	holder := "aws-node-termination-handler-1"
+	leaseDurationSeconds := int32(15)
+	now := metav1.NewMicroTime(time.Now())
+	client := fake.NewSimpleClientset(&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: leaseName, Namespace: leaseNamespace},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &leaseDurationSeconds,
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	})
+	elector, err := leaderelection.New(getConfig(), client)
+	h.Ok(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go elector.Run(ctx)
+
+	select {
+	case <-elector.Leading():
+		t.Fatal("Expected to stand by while another replica holds the lease")
+	case <-time.After(1 * time.Second):
+	}
+
+	cancel()
+	<-elector.Done()
+	lease, err := client.CoordinationV1().Leases(leaseNamespace).Get(context.Background(), leaseName, metav1.GetOptions{})
+	h.Ok(t, err)
+	h.Equals(t, holder, *lease.Spec.HolderIdentity)
+}
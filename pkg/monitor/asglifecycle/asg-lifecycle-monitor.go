@@ -14,6 +14,7 @@
 package asglifecycle
 
 import (
+	"context"
 	"crypto/sha256"
 	"fmt"
 	"time"
@@ -45,13 +46,17 @@ func NewASGLifecycleMonitor(imds *ec2metadata.Service, interruptionChan chan<- m
 }
 
 // Monitor continuously monitors metadata for ASG target lifecycle state and sends interruption events to the passed in channel
-func (m ASGLifecycleMonitor) Monitor() error {
+func (m ASGLifecycleMonitor) Monitor(ctx context.Context) error {
 	interruptionEvent, err := m.checkForASGTargetLifecycleStateNotice()
 	if err != nil {
 		return err
 	}
 	if interruptionEvent != nil && interruptionEvent.Kind == monitor.ASGLifecycleKind {
-		m.InterruptionChan <- *interruptionEvent
+		select {
+		case m.InterruptionChan <- *interruptionEvent:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 	return nil
 }
@@ -93,10 +98,10 @@ func (m ASGLifecycleMonitor) checkForASGTargetLifecycleStateNotice() (*monitor.I
 	}, nil
 }
 
-func setInterruptionTaint(interruptionEvent monitor.InterruptionEvent, n node.Node) error {
-	err := n.TaintASGLifecycleTermination(interruptionEvent.NodeName, interruptionEvent.EventID)
+func setInterruptionTaint(_ context.Context, interruptionEvent monitor.InterruptionEvent, n node.Node) error {
+	err := monitor.MarkNode(n, interruptionEvent.NodeName, interruptionEvent, n.TaintASGLifecycleTermination)
 	if err != nil {
-		return fmt.Errorf("unable to taint node with taint %s:%s: %w", node.ASGLifecycleTerminationTaint, interruptionEvent.EventID, err)
+		return fmt.Errorf("unable to mark node for event %s: %w", interruptionEvent.EventID, err)
 	}
 
 	return nil
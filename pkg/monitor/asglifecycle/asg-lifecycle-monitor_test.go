@@ -14,6 +14,7 @@
 package asglifecycle_test
 
 import (
+	"context"
 	"github.com/aws/aws-node-termination-handler/pkg/monitor/asglifecycle"
 	"net/http"
 	"net/http/httptest"
@@ -58,7 +59,7 @@ func TestMonitor_Success(t *testing.T) {
 	}()
 
 	asgLifecycleMonitor := asglifecycle.NewASGLifecycleMonitor(imds, drainChan, cancelChan, nodeName)
-	err := asgLifecycleMonitor.Monitor()
+	err := asgLifecycleMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 }
 
@@ -76,7 +77,7 @@ func TestMonitor_MetadataParseFailure(t *testing.T) {
 	imds := ec2metadata.New(server.URL, 1)
 
 	asgLifecycleMonitor := asglifecycle.NewASGLifecycleMonitor(imds, drainChan, cancelChan, nodeName)
-	err := asgLifecycleMonitor.Monitor()
+	err := asgLifecycleMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 }
 
@@ -98,7 +99,7 @@ func TestMonitor_404Response(t *testing.T) {
 	imds := ec2metadata.New(server.URL, 1)
 
 	asgLifecycleMonitor := asglifecycle.NewASGLifecycleMonitor(imds, drainChan, cancelChan, nodeName)
-	err := asgLifecycleMonitor.Monitor()
+	err := asgLifecycleMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 }
 
@@ -120,6 +121,6 @@ func TestMonitor_500Response(t *testing.T) {
 	imds := ec2metadata.New(server.URL, 1)
 
 	asgLifecycleMonitor := asglifecycle.NewASGLifecycleMonitor(imds, drainChan, cancelChan, nodeName)
-	err := asgLifecycleMonitor.Monitor()
+	err := asgLifecycleMonitor.Monitor(context.Background())
 	h.Assert(t, err != nil, "Failed to return error when 500 response")
 }
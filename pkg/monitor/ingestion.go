@@ -0,0 +1,56 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package monitor
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+)
+
+// ErrIngestionQueueFull is returned when an event does not fit in a full ingestion queue and the overflow policy rejects it
+var ErrIngestionQueueFull = errors.New("ingestion queue is full")
+
+// Send hands an event to the ingestion queue between the monitors and the event store
+//
+// When the queue is full, the block overflow policy waits for room or for ctx to be done, while the reject policy
+// returns ErrIngestionQueueFull right away so that the monitor can hand the event back to its source.
+func Send(ctx context.Context, queue chan<- InterruptionEvent, event InterruptionEvent, overflowPolicy string) error {
+	if overflowPolicy == config.IngestionOverflowReject {
+		select {
+		case queue <- event:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			return ErrIngestionQueueFull
+		}
+	}
+	select {
+	case queue <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Headroom returns the number of events the ingestion queue takes without blocking, and false if the queue is unbuffered,
+// in which case each event waits for the store to take it in
+func Headroom(queue chan<- InterruptionEvent) (int, bool) {
+	if cap(queue) == 0 {
+		return 0, false
+	}
+	return cap(queue) - len(queue), true
+}
@@ -0,0 +1,47 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package monitor_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+)
+
+func TestSend(t *testing.T) {
+	queue := make(chan monitor.InterruptionEvent, 1)
+	h.Ok(t, monitor.Send(context.Background(), queue, monitor.InterruptionEvent{EventID: "1"}, config.IngestionOverflowReject))
+
+	headroom, bounded := monitor.Headroom(queue)
+	h.Equals(t, true, bounded)
+	h.Equals(t, 0, headroom)
+
+	err := monitor.Send(context.Background(), queue, monitor.InterruptionEvent{EventID: "2"}, config.IngestionOverflowReject)
+	h.Equals(t, monitor.ErrIngestionQueueFull, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = monitor.Send(ctx, queue, monitor.InterruptionEvent{EventID: "2"}, config.IngestionOverflowBlock)
+	h.Equals(t, context.DeadlineExceeded, err)
+	h.Equals(t, "1", (<-queue).EventID)
+}
+
+func TestHeadroomUnbuffered(t *testing.T) {
+	_, bounded := monitor.Headroom(make(chan monitor.InterruptionEvent))
+	h.Equals(t, false, bounded)
+}
@@ -0,0 +1,153 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package monitor
+
+import (
+	"fmt"
+	"time"
+)
+
+// EventState is a step in the lifecycle of an interruption event
+type EventState string
+
+const (
+	// StateReceived means the event was reported by a monitor and waits for its drain deadline and a free worker
+	StateReceived EventState = "Received"
+	// StateScheduled means the event was handed to a worker
+	StateScheduled EventState = "Scheduled"
+	// StatePreDrain means the pre-drain task is running
+	StatePreDrain EventState = "PreDrain"
+	// StateCordoning means the node is being cordoned without being drained
+	StateCordoning EventState = "Cordoning"
+	// StateCordoned means the node was cordoned without being drained
+	StateCordoned EventState = "Cordoned"
+	// StateDraining means the node is being cordoned and drained
+	StateDraining EventState = "Draining"
+	// StateDrained means the node was cordoned and drained
+	StateDrained EventState = "Drained"
+	// StatePostDrain means the post-drain task is running
+	StatePostDrain EventState = "PostDrain"
+	// StateCompleted means NTH is done with the event
+	StateCompleted EventState = "Completed"
+	// StateFailed means a step failed, the event is received again if its monitor reports it again
+	StateFailed EventState = "Failed"
+	// StateCancelled means the source of the event cancelled it
+	StateCancelled EventState = "Cancelled"
+)
+
+// maxTransitions bounds the transition history kept for an event which keeps failing
+const maxTransitions = 32
+
+// legalTransitions lists the states each state can move to.
+// Every in-flight state can go back to Received, since the event is handed back when NTH restarts.
+// A cordoned node is drained when an event which asks for a drain is merged into the event which cordoned it,
+// and a merged event goes from its pre-drain task straight to its end, since the node is cordoned or drained for it.
+var legalTransitions = map[EventState][]EventState{
+	"":             {StateReceived},
+	StateReceived:  {StateScheduled, StateCompleted, StateCancelled},
+	StateScheduled: {StatePreDrain, StateCordoning, StateDraining, StatePostDrain, StateCompleted, StateFailed, StateCancelled, StateReceived},
+	StatePreDrain:  {StateCordoning, StateDraining, StatePostDrain, StateCompleted, StateFailed, StateCancelled, StateReceived},
+	StateCordoning: {StateCordoned, StatePostDrain, StateFailed, StateCancelled, StateReceived},
+	StateCordoned:  {StateDraining, StatePostDrain, StateCompleted, StateCancelled, StateReceived},
+	StateDraining:  {StateDrained, StatePostDrain, StateFailed, StateCancelled, StateReceived},
+	StateDrained:   {StatePostDrain, StateCompleted, StateReceived},
+	StatePostDrain: {StateCompleted, StateFailed, StateReceived},
+	StateCompleted: {},
+	StateFailed:    {StateReceived, StateCompleted, StateCancelled},
+	StateCancelled: {StateReceived},
+}
+
+// CanTransitionTo returns true if an event can move from s to next
+func (s EventState) CanTransitionTo(next EventState) bool {
+	for _, state := range legalTransitions[s] {
+		if state == next {
+			return true
+		}
+	}
+	return false
+}
+
+// InFlight returns true while a worker is processing the event
+func (s EventState) InFlight() bool {
+	switch s {
+	case StateScheduled, StatePreDrain, StateCordoning, StateCordoned, StateDraining, StateDrained, StatePostDrain:
+		return true
+	}
+	return false
+}
+
+// NodeProcessed returns true once the node of the event was cordoned or drained, or NTH is done with the event
+func (s EventState) NodeProcessed() bool {
+	switch s {
+	case StateCordoned, StateDrained, StatePostDrain, StateCompleted:
+		return true
+	}
+	return false
+}
+
+// Transition is a recorded change of state of an interruption event
+type Transition struct {
+	From  EventState `json:"from"`
+	To    EventState `json:"to"`
+	Time  time.Time  `json:"time"`
+	Error string     `json:"error,omitempty"`
+}
+
+// Lifecycle tracks the progress of an interruption event
+type Lifecycle struct {
+	State EventState `json:"state"`
+	// Attempts is the number of times the event was handed to a worker
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"lastError,omitempty"`
+	// NextAttempt is the earliest time a failed event is handed to a worker again
+	NextAttempt time.Time `json:"nextAttempt"`
+	// Exhausted is true once a failed event is left failed, because it used up its retries or failed with a terminal error
+	Exhausted bool `json:"exhausted,omitempty"`
+	// MergedInto is the ID of the in-flight event of the same node which processes this event along with its own
+	MergedInto  string       `json:"mergedInto,omitempty"`
+	Transitions []Transition `json:"transitions,omitempty"`
+}
+
+// Advance moves the lifecycle to next, recording err as the last error if it is not nil
+func (l *Lifecycle) Advance(next EventState, err error, now time.Time) (Transition, error) {
+	if !l.State.CanTransitionTo(next) {
+		return Transition{}, fmt.Errorf("illegal interruption event transition from %q to %q", l.State, next)
+	}
+	transition := Transition{From: l.State, To: next, Time: now}
+	if err != nil {
+		transition.Error = err.Error()
+		l.LastError = err.Error()
+	}
+	if next == StateScheduled {
+		l.Attempts++
+	}
+	if next == StateReceived {
+		// a received event is processed on its own again
+		l.MergedInto = ""
+	}
+	l.State = next
+	l.Transitions = append(l.Transitions, transition)
+	if len(l.Transitions) > maxTransitions {
+		l.Transitions = l.Transitions[len(l.Transitions)-maxTransitions:]
+	}
+	return transition, nil
+}
+
+// Since returns when the lifecycle entered its current state
+func (l *Lifecycle) Since() time.Time {
+	if len(l.Transitions) == 0 {
+		return time.Time{}
+	}
+	return l.Transitions[len(l.Transitions)-1].Time
+}
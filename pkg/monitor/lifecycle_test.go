@@ -0,0 +1,84 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package monitor_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+)
+
+func TestLifecycleAdvance(t *testing.T) {
+	lifecycle := monitor.Lifecycle{}
+	now := time.Now()
+
+	_, err := lifecycle.Advance(monitor.StateScheduled, nil, now)
+	h.Assert(t, err != nil, "Failed to return error for a transition which skips Received")
+
+	for _, state := range []monitor.EventState{monitor.StateReceived, monitor.StateScheduled, monitor.StateDraining} {
+		_, err = lifecycle.Advance(state, nil, now)
+		h.Ok(t, err)
+	}
+	transition, err := lifecycle.Advance(monitor.StateFailed, errors.New("eviction failed"), now)
+	h.Ok(t, err)
+	h.Equals(t, monitor.Transition{From: monitor.StateDraining, To: monitor.StateFailed, Time: now, Error: "eviction failed"}, transition)
+	h.Equals(t, monitor.StateFailed, lifecycle.State)
+	h.Equals(t, "eviction failed", lifecycle.LastError)
+	h.Equals(t, 1, lifecycle.Attempts)
+	h.Equals(t, now, lifecycle.Since())
+
+	_, err = lifecycle.Advance(monitor.StateDrained, nil, now)
+	h.Assert(t, err != nil, "Failed to return error for a transition out of Failed which skips Received")
+	h.Equals(t, monitor.StateFailed, lifecycle.State)
+}
+
+func TestLifecycleUpgradeCordonToDrain(t *testing.T) {
+	lifecycle := monitor.Lifecycle{}
+	for _, state := range []monitor.EventState{monitor.StateReceived, monitor.StateScheduled, monitor.StateCordoning, monitor.StateCordoned, monitor.StateDraining, monitor.StateDrained} {
+		_, err := lifecycle.Advance(state, nil, time.Now())
+		h.Ok(t, err)
+	}
+	h.Equals(t, monitor.StateDrained, lifecycle.State)
+}
+
+func TestLifecycleTransitionHistoryIsBounded(t *testing.T) {
+	lifecycle := monitor.Lifecycle{}
+	_, err := lifecycle.Advance(monitor.StateReceived, nil, time.Now())
+	h.Ok(t, err)
+	for i := 0; i < 50; i++ {
+		for _, state := range []monitor.EventState{monitor.StateScheduled, monitor.StateFailed, monitor.StateReceived} {
+			_, err = lifecycle.Advance(state, nil, time.Now())
+			h.Ok(t, err)
+		}
+	}
+	h.Equals(t, 50, lifecycle.Attempts)
+	h.Equals(t, 32, len(lifecycle.Transitions))
+	h.Equals(t, monitor.StateReceived, lifecycle.Transitions[31].To)
+}
+
+func TestEventStateNodeProcessed(t *testing.T) {
+	for state, expected := range map[monitor.EventState]bool{
+		monitor.StateReceived:  false,
+		monitor.StateDraining:  false,
+		monitor.StateFailed:    false,
+		monitor.StateCordoned:  true,
+		monitor.StateDrained:   true,
+		monitor.StateCompleted: true,
+	} {
+		h.Equals(t, expected, state.NodeProcessed())
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package monitor
+
+import (
+	"github.com/aws/aws-node-termination-handler/pkg/node"
+)
+
+// MarkNode sets the taints, labels and annotations of the marking policy rule of the kind of the event on its node,
+// whose values are rendered with the event. If the policy has no rule for the kind, defaultMark is called instead, if not nil.
+func MarkNode(n node.Node, nodeName string, event InterruptionEvent, defaultMark func(nodeName string, eventID string) error) error {
+	rule, ok := n.MarkingRule(event.Kind)
+	if !ok {
+		if defaultMark == nil {
+			return nil
+		}
+		return defaultMark(nodeName, event.EventID)
+	}
+	return n.Mark(nodeName, rule, event)
+}
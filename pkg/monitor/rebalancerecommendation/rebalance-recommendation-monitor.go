@@ -14,6 +14,7 @@
 package rebalancerecommendation
 
 import (
+	"context"
 	"crypto/sha256"
 	"fmt"
 	"time"
@@ -43,13 +44,17 @@ func NewRebalanceRecommendationMonitor(imds *ec2metadata.Service, interruptionCh
 }
 
 // Monitor continuously monitors metadata for rebalance recommendations and sends interruption events to the passed in channel
-func (m RebalanceRecommendationMonitor) Monitor() error {
+func (m RebalanceRecommendationMonitor) Monitor(ctx context.Context) error {
 	interruptionEvent, err := m.checkForRebalanceRecommendation()
 	if err != nil {
 		return err
 	}
 	if interruptionEvent != nil && interruptionEvent.Kind == monitor.RebalanceRecommendationKind {
-		m.InterruptionChan <- *interruptionEvent
+		select {
+		case m.InterruptionChan <- *interruptionEvent:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 	return nil
 }
@@ -93,10 +98,10 @@ func (m RebalanceRecommendationMonitor) checkForRebalanceRecommendation() (*moni
 	}, nil
 }
 
-func setInterruptionTaint(interruptionEvent monitor.InterruptionEvent, n node.Node) error {
-	err := n.TaintRebalanceRecommendation(interruptionEvent.NodeName, interruptionEvent.EventID)
+func setInterruptionTaint(_ context.Context, interruptionEvent monitor.InterruptionEvent, n node.Node) error {
+	err := monitor.MarkNode(n, interruptionEvent.NodeName, interruptionEvent, n.TaintRebalanceRecommendation)
 	if err != nil {
-		return fmt.Errorf("unable to taint node with taint %s:%s: %w", node.RebalanceRecommendationTaint, interruptionEvent.EventID, err)
+		return fmt.Errorf("unable to mark node for event %s: %w", interruptionEvent.EventID, err)
 	}
 
 	return nil
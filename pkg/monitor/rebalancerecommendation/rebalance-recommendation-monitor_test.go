@@ -14,6 +14,7 @@
 package rebalancerecommendation_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -63,7 +64,7 @@ func TestMonitor_Success(t *testing.T) {
 	}()
 
 	rebalanceNoticeMonitor := rebalancerecommendation.NewRebalanceRecommendationMonitor(imds, drainChan, nodeName)
-	err := rebalanceNoticeMonitor.Monitor()
+	err := rebalanceNoticeMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 }
 
@@ -81,7 +82,7 @@ func TestMonitor_MetadataParseFailure(t *testing.T) {
 	nodeName := "test-node"
 
 	rebalanceNoticeMonitor := rebalancerecommendation.NewRebalanceRecommendationMonitor(imds, drainChan, nodeName)
-	err := rebalanceNoticeMonitor.Monitor()
+	err := rebalanceNoticeMonitor.Monitor(context.Background())
 	h.Assert(t, err != nil, "Failed to return error metadata parse fails")
 }
 
@@ -103,7 +104,7 @@ func TestMonitor_404Response(t *testing.T) {
 	nodeName := "test-node"
 
 	rebalanceNoticeMonitor := rebalancerecommendation.NewRebalanceRecommendationMonitor(imds, drainChan, nodeName)
-	err := rebalanceNoticeMonitor.Monitor()
+	err := rebalanceNoticeMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 }
 
@@ -125,7 +126,7 @@ func TestMonitor_500Response(t *testing.T) {
 	nodeName := "test-node"
 
 	rebalanceNoticeMonitor := rebalancerecommendation.NewRebalanceRecommendationMonitor(imds, drainChan, nodeName)
-	err := rebalanceNoticeMonitor.Monitor()
+	err := rebalanceNoticeMonitor.Monitor(context.Background())
 	h.Assert(t, err != nil, "Failed to return error when 500 response")
 }
 
@@ -148,6 +149,6 @@ func TestMonitor_NoticeTimeParseFailure(t *testing.T) {
 	nodeName := "test-node"
 
 	rebalanceNoticeMonitor := rebalancerecommendation.NewRebalanceRecommendationMonitor(imds, drainChan, nodeName)
-	err := rebalanceNoticeMonitor.Monitor()
+	err := rebalanceNoticeMonitor.Monitor(context.Background())
 	h.Assert(t, err != nil, "Failed to return error when failed to parse time")
 }
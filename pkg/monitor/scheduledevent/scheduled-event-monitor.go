@@ -14,6 +14,7 @@
 package scheduledevent
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -54,16 +55,20 @@ func NewScheduledEventMonitor(imds *ec2metadata.Service, interruptionChan chan<-
 }
 
 // Monitor continuously monitors metadata for scheduled events and sends interruption events to the passed in channel
-func (m ScheduledEventMonitor) Monitor() error {
+func (m ScheduledEventMonitor) Monitor(ctx context.Context) error {
 	interruptionEvents, err := m.checkForScheduledEvents()
 	if err != nil {
 		return err
 	}
 	for _, interruptionEvent := range interruptionEvents {
+		eventChan := m.InterruptionChan
 		if isStateCanceledOrCompleted(interruptionEvent.State) {
-			m.CancelChan <- interruptionEvent
-		} else {
-			m.InterruptionChan <- interruptionEvent
+			eventChan = m.CancelChan
+		}
+		select {
+		case eventChan <- interruptionEvent:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
 	return nil
@@ -83,7 +88,7 @@ func (m ScheduledEventMonitor) checkForScheduledEvents() ([]monitor.Interruption
 
 	events := make([]monitor.InterruptionEvent, 0)
 	for _, scheduledEvent := range scheduledEvents {
-		var preDrainFunc monitor.DrainTask
+		preDrainFunc := markPreDrain
 		if isRestartEvent(scheduledEvent.Code) && !isStateCanceledOrCompleted(scheduledEvent.State) {
 			preDrainFunc = uncordonAfterRebootPreDrain
 		}
@@ -100,30 +105,32 @@ func (m ScheduledEventMonitor) checkForScheduledEvents() ([]monitor.Interruption
 			}
 		}
 		events = append(events, monitor.InterruptionEvent{
-			EventID:      scheduledEvent.EventID,
-			Kind:         monitor.ScheduledEventKind,
-			Monitor:      ScheduledEventMonitorKind,
-			Description:  fmt.Sprintf("%s will occur between %s and %s because %s\n", scheduledEvent.Code, scheduledEvent.NotBefore, scheduledEvent.NotAfter, scheduledEvent.Description),
-			State:        scheduledEvent.State,
-			NodeName:     m.NodeName,
-			StartTime:    time.Now(),
-			EndTime:      notAfter,
-			PreDrainTask: preDrainFunc,
+			EventID:     scheduledEvent.EventID,
+			Kind:        monitor.ScheduledEventKind,
+			Monitor:     ScheduledEventMonitorKind,
+			Description: fmt.Sprintf("%s will occur between %s and %s because %s\n", scheduledEvent.Code, scheduledEvent.NotBefore, scheduledEvent.NotAfter, scheduledEvent.Description),
+			State:       scheduledEvent.State,
+			NodeName:    m.NodeName,
+			StartTime:   time.Now(),
+			EndTime:     notAfter,
+			// the instance may be stopped or rebooted as soon as the maintenance window opens
+			DrainDeadline: notBefore,
+			PreDrainTask:  preDrainFunc,
 		})
 	}
 	return events, nil
 }
 
-func uncordonAfterRebootPreDrain(interruptionEvent monitor.InterruptionEvent, n node.Node) error {
+func uncordonAfterRebootPreDrain(_ context.Context, interruptionEvent monitor.InterruptionEvent, n node.Node) error {
 	nodeName := interruptionEvent.NodeName
 	err := n.MarkWithEventID(nodeName, interruptionEvent.EventID)
 	if err != nil {
 		return fmt.Errorf("unable to mark node with event ID: %w", err)
 	}
 
-	err = n.TaintScheduledMaintenance(nodeName, interruptionEvent.EventID)
+	err = monitor.MarkNode(n, nodeName, interruptionEvent, n.TaintScheduledMaintenance)
 	if err != nil {
-		return fmt.Errorf("unable to taint node with taint %s:%s: %w", node.ScheduledMaintenanceTaint, interruptionEvent.EventID, err)
+		return fmt.Errorf("unable to mark node for event %s: %w", interruptionEvent.EventID, err)
 	}
 
 	// if the node is already marked as unschedulable, then don't do anything
@@ -142,6 +149,15 @@ func uncordonAfterRebootPreDrain(interruptionEvent monitor.InterruptionEvent, n
 	return nil
 }
 
+// markPreDrain marks the node of a scheduled event which does not restart the instance, which has no taint of its own
+func markPreDrain(_ context.Context, interruptionEvent monitor.InterruptionEvent, n node.Node) error {
+	err := monitor.MarkNode(n, interruptionEvent.NodeName, interruptionEvent, nil)
+	if err != nil {
+		return fmt.Errorf("unable to mark node for event %s: %w", interruptionEvent.EventID, err)
+	}
+	return nil
+}
+
 func isStateCanceledOrCompleted(state string) bool {
 	return state == scheduledEventStateCanceled ||
 		state == scheduledEventStateCompleted
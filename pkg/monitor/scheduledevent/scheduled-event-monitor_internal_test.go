@@ -73,14 +73,14 @@ func TestUncordonAfterRebootPreDrainSuccess(t *testing.T) {
 	tNode, err := node.NewWithValues(nthConfig, getDrainHelper(client), uptime.Uptime)
 	h.Ok(t, err)
 
-	err = uncordonAfterRebootPreDrain(drainEvent, *tNode)
+	err = uncordonAfterRebootPreDrain(context.Background(), drainEvent, *tNode)
 
 	h.Ok(t, err)
 }
 
 func TestUncordonAfterRebootPreDrainMarkWithEventIDFailure(t *testing.T) {
 	tNode := getNode(t, getDrainHelper(fake.NewSimpleClientset()))
-	err := uncordonAfterRebootPreDrain(monitor.InterruptionEvent{}, *tNode)
+	err := uncordonAfterRebootPreDrain(context.Background(), monitor.InterruptionEvent{}, *tNode)
 	h.Assert(t, err != nil, "Failed to return error on MarkWithEventID failing to fetch node")
 }
 
@@ -106,6 +106,6 @@ func TestUncordonAfterRebootPreDrainNodeAlreadyMarkedSuccess(t *testing.T) {
 	tNode, err := node.NewWithValues(nthConfig, getDrainHelper(client), uptime.Uptime)
 	h.Ok(t, err)
 
-	err = uncordonAfterRebootPreDrain(monitor.InterruptionEvent{}, *tNode)
+	err = uncordonAfterRebootPreDrain(context.Background(), monitor.InterruptionEvent{}, *tNode)
 	h.Ok(t, err)
 }
@@ -14,6 +14,7 @@
 package scheduledevent_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -80,6 +81,7 @@ func TestMonitor_Success(t *testing.T) {
 		h.Equals(t, scheduledEventState, result.State)
 		h.TimeWithinRange(t, result.StartTime, oneSecondAgo(), time.Now())
 		h.Equals(t, expScheduledEventEndTimeFmt, result.EndTime.String())
+		h.Equals(t, expScheduledEventStartTimeFmt, result.DrainDeadline.String())
 
 		h.Assert(t, strings.Contains(result.Description, scheduledEventCode),
 			"Expected description to contain \""+scheduledEventCode+
@@ -98,7 +100,7 @@ func TestMonitor_Success(t *testing.T) {
 
 	scheduledEventMonitor := scheduledevent.NewScheduledEventMonitor(imds, drainChan, cancelChan, nodeName)
 
-	err := scheduledEventMonitor.Monitor()
+	err := scheduledEventMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 }
 
@@ -153,7 +155,7 @@ func TestMonitor_CanceledEvent(t *testing.T) {
 
 	scheduledEventMonitor := scheduledevent.NewScheduledEventMonitor(imds, drainChan, cancelChan, nodeName)
 
-	err := scheduledEventMonitor.Monitor()
+	err := scheduledEventMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 }
 
@@ -174,7 +176,7 @@ func TestMonitor_MetadataParseFailure(t *testing.T) {
 	imds := ec2metadata.New("bad url", 0)
 	scheduledEventMonitor := scheduledevent.NewScheduledEventMonitor(imds, drainChan, cancelChan, nodeName)
 
-	err := scheduledEventMonitor.Monitor()
+	err := scheduledEventMonitor.Monitor(context.Background())
 	h.Assert(t, err != nil, "Failed to return error when metadata parse fails")
 }
 
@@ -197,7 +199,7 @@ func TestMonitor_404Response(t *testing.T) {
 
 	scheduledEventMonitor := scheduledevent.NewScheduledEventMonitor(imds, drainChan, cancelChan, nodeName)
 
-	err := scheduledEventMonitor.Monitor()
+	err := scheduledEventMonitor.Monitor(context.Background())
 	h.Assert(t, err != nil, "Failed to return error when 404 response")
 }
 
@@ -226,7 +228,7 @@ func TestMonitor_StartTimeParseFail(t *testing.T) {
 	imds := ec2metadata.New(server.URL, 1)
 
 	scheduledEventMonitor := scheduledevent.NewScheduledEventMonitor(imds, drainChan, cancelChan, nodeName)
-	err := scheduledEventMonitor.Monitor()
+	err := scheduledEventMonitor.Monitor(context.Background())
 	h.Assert(t, err != nil, "Failed to return error when failed to parse start time")
 }
 
@@ -277,6 +279,6 @@ func TestMonitor_EndTimeParseFail(t *testing.T) {
 
 	scheduledEventMonitor := scheduledevent.NewScheduledEventMonitor(imds, drainChan, cancelChan, nodeName)
 
-	err := scheduledEventMonitor.Monitor()
+	err := scheduledEventMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 }
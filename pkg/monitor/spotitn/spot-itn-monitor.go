@@ -14,6 +14,7 @@
 package spotitn
 
 import (
+	"context"
 	"crypto/sha256"
 	"fmt"
 	"time"
@@ -45,13 +46,17 @@ func NewSpotInterruptionMonitor(imds *ec2metadata.Service, interruptionChan chan
 }
 
 // Monitor continuously monitors metadata for spot ITNs and sends interruption events to the passed in channel
-func (m SpotInterruptionMonitor) Monitor() error {
+func (m SpotInterruptionMonitor) Monitor(ctx context.Context) error {
 	interruptionEvent, err := m.checkForSpotInterruptionNotice()
 	if err != nil {
 		return err
 	}
 	if interruptionEvent != nil && interruptionEvent.Kind == monitor.SpotITNKind {
-		m.InterruptionChan <- *interruptionEvent
+		select {
+		case m.InterruptionChan <- *interruptionEvent:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 	return nil
 }
@@ -85,20 +90,21 @@ func (m SpotInterruptionMonitor) checkForSpotInterruptionNotice() (*monitor.Inte
 	}
 
 	return &monitor.InterruptionEvent{
-		EventID:      fmt.Sprintf("spot-itn-%x", hash.Sum(nil)),
-		Kind:         monitor.SpotITNKind,
-		Monitor:      SpotITNMonitorKind,
-		StartTime:    interruptionTime,
-		NodeName:     nodeName,
-		Description:  fmt.Sprintf("Spot ITN received. Instance will be interrupted at %s \n", instanceAction.Time),
-		PreDrainTask: setInterruptionTaint,
+		EventID:       fmt.Sprintf("spot-itn-%x", hash.Sum(nil)),
+		Kind:          monitor.SpotITNKind,
+		Monitor:       SpotITNMonitorKind,
+		StartTime:     interruptionTime,
+		DrainDeadline: interruptionTime,
+		NodeName:      nodeName,
+		Description:   fmt.Sprintf("Spot ITN received. Instance will be interrupted at %s \n", instanceAction.Time),
+		PreDrainTask:  setInterruptionTaint,
 	}, nil
 }
 
-func setInterruptionTaint(interruptionEvent monitor.InterruptionEvent, n node.Node) error {
-	err := n.TaintSpotItn(interruptionEvent.NodeName, interruptionEvent.EventID)
+func setInterruptionTaint(_ context.Context, interruptionEvent monitor.InterruptionEvent, n node.Node) error {
+	err := monitor.MarkNode(n, interruptionEvent.NodeName, interruptionEvent, n.TaintSpotItn)
 	if err != nil {
-		return fmt.Errorf("unable to taint node with taint %s:%s: %w", node.SpotInterruptionTaint, interruptionEvent.EventID, err)
+		return fmt.Errorf("unable to mark node for event %s: %w", interruptionEvent.EventID, err)
 	}
 
 	return nil
@@ -62,7 +62,7 @@ func TestSetInterruptionTaint(t *testing.T) {
 	tNode, err := node.NewWithValues(nthConfig, getSpotDrainHelper(client), uptime.Uptime)
 	h.Ok(t, err)
 
-	err = setInterruptionTaint(drainEvent, *tNode)
+	err = setInterruptionTaint(context.Background(), drainEvent, *tNode)
 
 	h.Ok(t, err)
 }
@@ -93,7 +93,7 @@ func TestInterruptionTaintAlreadyPresent(t *testing.T) {
 	tNode, err := node.NewWithValues(nthConfig, getSpotDrainHelper(client), uptime.Uptime)
 	h.Ok(t, err)
 
-	err = setInterruptionTaint(drainEvent, *tNode)
+	err = setInterruptionTaint(context.Background(), drainEvent, *tNode)
 
 	h.Ok(t, err)
 }
@@ -14,6 +14,7 @@
 package spotitn_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -65,7 +66,7 @@ func TestMonitor_Success(t *testing.T) {
 	}()
 
 	spotITNMonitor := spotitn.NewSpotInterruptionMonitor(imds, drainChan, cancelChan, nodeName)
-	err := spotITNMonitor.Monitor()
+	err := spotITNMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 }
 
@@ -84,7 +85,7 @@ func TestMonitor_MetadataParseFailure(t *testing.T) {
 	nodeName := "test-node"
 
 	spotITNMonitor := spotitn.NewSpotInterruptionMonitor(imds, drainChan, cancelChan, nodeName)
-	err := spotITNMonitor.Monitor()
+	err := spotITNMonitor.Monitor(context.Background())
 	h.Assert(t, err != nil, "Failed to return error metadata parse fails")
 }
 
@@ -107,7 +108,7 @@ func TestMonitor_404Response(t *testing.T) {
 	nodeName := "test-node"
 
 	spotITNMonitor := spotitn.NewSpotInterruptionMonitor(imds, drainChan, cancelChan, nodeName)
-	err := spotITNMonitor.Monitor()
+	err := spotITNMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 }
 
@@ -130,7 +131,7 @@ func TestMonitor_500Response(t *testing.T) {
 	nodeName := "test-node"
 
 	spotITNMonitor := spotitn.NewSpotInterruptionMonitor(imds, drainChan, cancelChan, nodeName)
-	err := spotITNMonitor.Monitor()
+	err := spotITNMonitor.Monitor(context.Background())
 	h.Assert(t, err != nil, "Failed to return error when 500 response")
 }
 
@@ -154,7 +155,7 @@ func TestMonitor_InstanceActionDecodeFailure(t *testing.T) {
 	nodeName := "test-node"
 
 	spotITNMonitor := spotitn.NewSpotInterruptionMonitor(imds, drainChan, cancelChan, nodeName)
-	err := spotITNMonitor.Monitor()
+	err := spotITNMonitor.Monitor(context.Background())
 	h.Assert(t, err != nil, "Failed to return error when failed to decode instance action")
 }
 
@@ -178,6 +179,6 @@ func TestMonitor_TimeParseFailure(t *testing.T) {
 	nodeName := "test-node"
 
 	spotITNMonitor := spotitn.NewSpotInterruptionMonitor(imds, drainChan, cancelChan, nodeName)
-	err := spotITNMonitor.Monitor()
+	err := spotITNMonitor.Monitor(context.Background())
 	h.Assert(t, err != nil, "Failed to return error when failed to parse time")
 }
@@ -14,11 +14,14 @@
 package sqsevent
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/aws/aws-node-termination-handler/pkg/config"
 	"github.com/aws/aws-node-termination-handler/pkg/monitor"
 	"github.com/aws/aws-node-termination-handler/pkg/node"
 	"github.com/aws/aws-sdk-go/aws"
@@ -97,11 +100,36 @@ func (m SQSMonitor) asgTerminationToInterruptionEvent(event *EventBridgeEvent, m
 		InstanceType:         nodeInfo.InstanceType,
 		Description:          fmt.Sprintf("ASG Lifecycle Termination event received. Instance will be interrupted at %s \n", event.getTime()),
 	}
+	interruptionEvent.DrainDeadline = m.lifecycleActionDeadline(lifecycleDetail, interruptionEvent.StartTime)
 
 	stopHeartbeatCh := make(chan struct{})
 	cancelHeartbeatCh := make(chan struct{})
+	// the tasks of a retried event run again, while its heartbeats are started and stopped once
+	var startHeartbeats, stopHeartbeats, cancelHeartbeats sync.Once
 
-	interruptionEvent.PostDrainTask = func(interruptionEvent monitor.InterruptionEvent, _ node.Node) error {
+	// the pods evicted by every attempt, since a retried event drains a node whose pods were evicted by the previous attempts
+	var evictions []node.PodEvictionResult
+
+	interruptionEvent.PostDrainTask = func(ctx context.Context, interruptionEvent monitor.InterruptionEvent, n node.Node) error {
+		nthConfig := n.GetNthConfig()
+		if nthConfig.WaitForWorkloadReadiness {
+			// heartbeats keep the lifecycle action alive until it completes
+			evictions = append(evictions, interruptionEvent.Evictions...)
+			err := n.WaitForWorkloadsReady(ctx, evictions, time.Duration(nthConfig.WorkloadReadinessTimeout)*time.Second)
+			if err != nil && nthConfig.WorkloadReadinessFallback == config.WorkloadReadinessFallbackRetry {
+				return fmt.Errorf("waiting for the workloads of the evicted pods: %w", err)
+			}
+			if err != nil {
+				log.Warn().Err(err).Str("instanceID", lifecycleDetail.EC2InstanceID).Msg("Completing ASG Lifecycle Hook before the workloads of the evicted pods are ready")
+			}
+		}
+		if nthConfig.WaitForVolumeDetachment {
+			// the instance is terminated once the lifecycle action completes, so its volumes are detached cleanly beforehand
+			err := n.WaitForVolumeDetachment(ctx, interruptionEvent.NodeName, time.Duration(nthConfig.VolumeDetachmentTimeout)*time.Second)
+			if err != nil {
+				log.Warn().Err(err).Str("instanceID", lifecycleDetail.EC2InstanceID).Msg("Completing ASG Lifecycle Hook before the volumes of the node are detached")
+			}
+		}
 
 		_, err = m.continueLifecycleAction(lifecycleDetail)
 		if err != nil {
@@ -109,37 +137,42 @@ func (m SQSMonitor) asgTerminationToInterruptionEvent(event *EventBridgeEvent, m
 		}
 		log.Info().Str("lifecycleHookName", lifecycleDetail.LifecycleHookName).Str("instanceID", lifecycleDetail.EC2InstanceID).Msg("Completed ASG Lifecycle Hook")
 
-		close(stopHeartbeatCh)
+		stopHeartbeats.Do(func() { close(stopHeartbeatCh) })
 		return m.deleteMessage(message)
 	}
 	
-	interruptionEvent.CancelDrainTask = func(_ monitor.InterruptionEvent, _ node.Node) error {
-		close(cancelHeartbeatCh)
+	interruptionEvent.CancelDrainTask = func(_ context.Context, _ monitor.InterruptionEvent, _ node.Node) error {
+		cancelHeartbeats.Do(func() { close(cancelHeartbeatCh) })
 		return nil
 	}
 
-	interruptionEvent.PreDrainTask = func(interruptionEvent monitor.InterruptionEvent, n node.Node) error {
-		nthConfig := n.GetNthConfig()
+	startHeartbeating := func(nthConfig config.Config) {
 		// If only HeartbeatInterval is set, HeartbeatUntil will default to 172800.
 		if nthConfig.HeartbeatInterval != -1 && nthConfig.HeartbeatUntil != -1 {
-			go m.checkHeartbeatTimeout(nthConfig.HeartbeatInterval, lifecycleDetail)
-			go m.SendHeartbeats(nthConfig.HeartbeatInterval, nthConfig.HeartbeatUntil, lifecycleDetail, stopHeartbeatCh, cancelHeartbeatCh)
+			startHeartbeats.Do(func() {
+				go m.checkHeartbeatTimeout(nthConfig.HeartbeatInterval, lifecycleDetail)
+				go func() {
+					m.SendHeartbeats(nthConfig.HeartbeatInterval, nthConfig.HeartbeatUntil, lifecycleDetail, stopHeartbeatCh, cancelHeartbeatCh)
+					if m.HeartbeatsStopped != nil {
+						m.HeartbeatsStopped()
+					}
+				}()
+			})
 		}
+	}
 
-		// Use provider ID to resolve the actual Kubernetes node name if UseProviderId is configured
-		nodeName := interruptionEvent.NodeName
-		if nthConfig.UseProviderId && interruptionEvent.ProviderID != "" {
-			resolvedNodeName, err := n.GetNodeNameFromProviderID(interruptionEvent.ProviderID)
-			if err != nil {
-				log.Warn().Err(err).Str("provider_id", interruptionEvent.ProviderID).Msg("Failed to resolve node name from provider ID, falling back to NodeName from event")
-			} else {
-				nodeName = resolvedNodeName
-			}
-		}
+	// the lifecycle action must not time out while the event waits for the disruption budget or do-not-disrupt pods
+	interruptionEvent.DeferTask = func(_ context.Context, _ monitor.InterruptionEvent, n node.Node) error {
+		startHeartbeating(n.GetNthConfig())
+		return nil
+	}
 
-		err := n.TaintASGLifecycleTermination(nodeName, interruptionEvent.EventID)
+	interruptionEvent.PreDrainTask = func(_ context.Context, interruptionEvent monitor.InterruptionEvent, n node.Node) error {
+		startHeartbeating(n.GetNthConfig())
+
+		err := monitor.MarkNode(n, interruptionEvent.NodeName, interruptionEvent, n.TaintASGLifecycleTermination)
 		if err != nil {
-			log.Err(err).Msgf("unable to taint node with taint %s:%s", node.ASGLifecycleTerminationTaint, interruptionEvent.EventID)
+			log.Err(err).Msgf("unable to mark node for event %s", interruptionEvent.EventID)
 		}
 		return nil
 	}
@@ -147,6 +180,35 @@ func (m SQSMonitor) asgTerminationToInterruptionEvent(event *EventBridgeEvent, m
 	return &interruptionEvent, nil
 }
 
+// globalLifecycleTimeout is the longest time an instance can stay in the wait state of a lifecycle hook, whatever its heartbeats
+const globalLifecycleTimeout = 48 * time.Hour
+
+// lifecycleActionDeadline returns the time at which the lifecycle action started at startTime times out, which is the heartbeat timeout
+// of its hook, or the end of the heartbeats NTH sends, capped by the global timeout of the hook.
+// It returns the zero time if the hook cannot be described.
+func (m SQSMonitor) lifecycleActionDeadline(lifecycleDetail *LifecycleDetail, startTime time.Time) time.Time {
+	if m.ASG == nil {
+		return time.Time{}
+	}
+	lifecycleHooks, err := m.ASG.DescribeLifecycleHooks(&autoscaling.DescribeLifecycleHooksInput{
+		AutoScalingGroupName: aws.String(lifecycleDetail.AutoScalingGroupName),
+		LifecycleHookNames:   []*string{aws.String(lifecycleDetail.LifecycleHookName)},
+	})
+	if err != nil || len(lifecycleHooks.LifecycleHooks) == 0 || aws.Int64Value(lifecycleHooks.LifecycleHooks[0].HeartbeatTimeout) <= 0 {
+		log.Warn().Err(err).Str("lifecycleHookName", lifecycleDetail.LifecycleHookName).Msg("Unable to find the heartbeat timeout of the lifecycle hook, the node is drained without a deadline")
+		return time.Time{}
+	}
+	heartbeatTimeout := time.Duration(aws.Int64Value(lifecycleHooks.LifecycleHooks[0].HeartbeatTimeout)) * time.Second
+	if m.HeartbeatUntil <= 0 {
+		return startTime.Add(heartbeatTimeout)
+	}
+	timeout := time.Duration(m.HeartbeatUntil) * time.Second
+	if globalTimeout := min(globalLifecycleTimeout, 100*heartbeatTimeout); timeout > globalTimeout {
+		timeout = globalTimeout
+	}
+	return startTime.Add(timeout)
+}
+
 // Compare the heartbeatInterval with the heartbeat timeout and warn if (heartbeatInterval >= heartbeat timeout)
 func (m SQSMonitor) checkHeartbeatTimeout(heartbeatInterval int, lifecycleDetail *LifecycleDetail) {
 	input := &autoscaling.DescribeLifecycleHooksInput{
@@ -313,7 +375,7 @@ func (m SQSMonitor) createAsgInstanceLaunchEvent(event *EventBridgeEvent, messag
 		Description:          fmt.Sprintf("ASG Lifecycle Launch event received. Instance was started at %s \n", event.getTime()),
 	}
 
-	interruptionEvent.PostDrainTask = func(interruptionEvent monitor.InterruptionEvent, _ node.Node) error {
+	interruptionEvent.PostDrainTask = func(_ context.Context, interruptionEvent monitor.InterruptionEvent, _ node.Node) error {
 		_, err = m.continueLifecycleAction(lifecycleDetail)
 		if err != nil {
 			return fmt.Errorf("continuing ASG launch lifecycle: %w", err)
@@ -14,6 +14,7 @@
 package sqsevent
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -21,6 +22,7 @@ import (
 	"github.com/aws/aws-node-termination-handler/pkg/monitor"
 	"github.com/aws/aws-node-termination-handler/pkg/node"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/rs/zerolog/log"
 )
 
 /* Example EC2 State Change Event:
@@ -79,7 +81,14 @@ func (m SQSMonitor) ec2StateChangeToInterruptionEvent(event *EventBridgeEvent, m
 		Description:          fmt.Sprintf("EC2 State Change event received. Instance %s went into %s at %s \n", ec2StateChangeDetail.InstanceID, ec2StateChangeDetail.State, event.getTime()),
 	}
 
-	interruptionEvent.PostDrainTask = func(interruptionEvent monitor.InterruptionEvent, n node.Node) error {
+	interruptionEvent.PreDrainTask = func(_ context.Context, interruptionEvent monitor.InterruptionEvent, n node.Node) error {
+		// state changes have no taint of their own, the node is only marked by the marking policy
+		if err := monitor.MarkNode(n, interruptionEvent.NodeName, interruptionEvent, nil); err != nil {
+			log.Err(err).Msgf("Unable to mark node for event %s", interruptionEvent.EventID)
+		}
+		return nil
+	}
+	interruptionEvent.PostDrainTask = func(_ context.Context, interruptionEvent monitor.InterruptionEvent, n node.Node) error {
 		errs := m.deleteMessages([]*sqs.Message{message})
 		if errs != nil {
 			return errs[0]
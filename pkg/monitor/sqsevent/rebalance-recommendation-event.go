@@ -14,6 +14,7 @@
 package sqsevent
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -70,29 +71,17 @@ func (m SQSMonitor) rebalanceRecommendationToInterruptionEvent(event *EventBridg
 		InstanceType:         nodeInfo.InstanceType,
 		Description:          fmt.Sprintf("Rebalance recommendation event received. Instance %s will be cordoned at %s \n", rebalanceRecDetail.InstanceID, event.getTime()),
 	}
-	interruptionEvent.PostDrainTask = func(interruptionEvent monitor.InterruptionEvent, n node.Node) error {
+	interruptionEvent.PostDrainTask = func(_ context.Context, interruptionEvent monitor.InterruptionEvent, n node.Node) error {
 		errs := m.deleteMessages([]*sqs.Message{message})
 		if errs != nil {
 			return errs[0]
 		}
 		return nil
 	}
-	interruptionEvent.PreDrainTask = func(interruptionEvent monitor.InterruptionEvent, n node.Node) error {
-		// Use provider ID to resolve the actual Kubernetes node name if UseProviderId is configured
-		nthConfig := n.GetNthConfig()
-		nodeName := interruptionEvent.NodeName
-		if nthConfig.UseProviderId && interruptionEvent.ProviderID != "" {
-			resolvedNodeName, err := n.GetNodeNameFromProviderID(interruptionEvent.ProviderID)
-			if err != nil {
-				log.Warn().Err(err).Str("provider_id", interruptionEvent.ProviderID).Msg("Failed to resolve node name from provider ID, falling back to NodeName from event")
-			} else {
-				nodeName = resolvedNodeName
-			}
-		}
-
-		err := n.TaintRebalanceRecommendation(nodeName, interruptionEvent.EventID)
+	interruptionEvent.PreDrainTask = func(_ context.Context, interruptionEvent monitor.InterruptionEvent, n node.Node) error {
+		err := monitor.MarkNode(n, interruptionEvent.NodeName, interruptionEvent, n.TaintRebalanceRecommendation)
 		if err != nil {
-			log.Err(err).Msgf("Unable to taint node with taint %s:%s", node.RebalanceRecommendationTaint, interruptionEvent.EventID)
+			log.Err(err).Msgf("Unable to mark node for event %s", interruptionEvent.EventID)
 		}
 		return nil
 	}
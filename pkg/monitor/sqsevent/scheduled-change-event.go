@@ -14,6 +14,7 @@
 package sqsevent
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -60,13 +61,27 @@ type AffectedEntity struct {
 	EntityValue string `json:"entityValue"`
 }
 
+// restartEventTypeCodes are the codes of the AWS Health scheduled change events after which the instance comes back,
+// so that its node is marked to be uncordoned once it rebooted
+var restartEventTypeCodes = map[string]bool{
+	"AWS_EC2_INSTANCE_REBOOT_MAINTENANCE_SCHEDULED": true,
+	"AWS_EC2_SYSTEM_REBOOT_MAINTENANCE_SCHEDULED":   true,
+	"AWS_EC2_INSTANCE_STOP_SCHEDULED":               true,
+	"AWS_EC2_INSTANCE_RETIREMENT_SCHEDULED":         true,
+}
+
 // ScheduledChangeEventDetail holds the event details for AWS Health scheduled EC2 change events from Amazon EventBridge
 type ScheduledChangeEventDetail struct {
 	EventTypeCategory string           `json:"eventTypeCategory"`
+	EventTypeCode     string           `json:"eventTypeCode"`
 	Service           string           `json:"service"`
+	StartTime         string           `json:"startTime"`
 	AffectedEntities  []AffectedEntity `json:"affectedEntities"`
 }
 
+// scheduledChangeTimeFormat is the format of the start time of an AWS Health event
+const scheduledChangeTimeFormat = time.RFC1123
+
 func (m SQSMonitor) scheduledEventToInterruptionEvents(event *EventBridgeEvent, message *sqs.Message) []InterruptionEventWrapper {
 	scheduledChangeEventDetail := &ScheduledChangeEventDetail{}
 	interruptionEventWrappers := []InterruptionEventWrapper{}
@@ -85,6 +100,13 @@ func (m SQSMonitor) scheduledEventToInterruptionEvents(event *EventBridgeEvent,
 		return append(interruptionEventWrappers, InterruptionEventWrapper{nil, err})
 	}
 
+	eventTypeCode := scheduledChangeEventDetail.EventTypeCode
+	// the node is drained before the scheduled change starts
+	drainDeadline, err := time.Parse(scheduledChangeTimeFormat, scheduledChangeEventDetail.StartTime)
+	if err != nil {
+		log.Warn().Err(err).Str("start_time", scheduledChangeEventDetail.StartTime).Msg("Unable to parse the start time of the scheduled change event, it is drained without a deadline")
+		drainDeadline = time.Time{}
+	}
 	for _, affectedEntity := range scheduledChangeEventDetail.AffectedEntities {
 		nodeInfo, err := m.getNodeInfo(affectedEntity.EntityValue)
 		if err != nil {
@@ -99,6 +121,7 @@ func (m SQSMonitor) scheduledEventToInterruptionEvents(event *EventBridgeEvent,
 			Monitor:              SQSMonitorKind,
 			AutoScalingGroupName: nodeInfo.AsgName,
 			StartTime:            time.Now(),
+			DrainDeadline:        drainDeadline,
 			NodeName:             nodeInfo.Name,
 			InstanceID:           nodeInfo.InstanceID,
 			ProviderID:           nodeInfo.ProviderID,
@@ -106,27 +129,18 @@ func (m SQSMonitor) scheduledEventToInterruptionEvents(event *EventBridgeEvent,
 			IsManaged:            nodeInfo.IsManaged,
 			Description:          fmt.Sprintf("AWS Health scheduled change event received. Instance %s will be interrupted at %s \n", nodeInfo.InstanceID, event.getTime()),
 		}
-		interruptionEvent.PostDrainTask = func(interruptionEvent monitor.InterruptionEvent, n node.Node) error {
+		interruptionEvent.PostDrainTask = func(_ context.Context, interruptionEvent monitor.InterruptionEvent, n node.Node) error {
 			if errs := m.deleteMessages([]*sqs.Message{message}); errs != nil {
 				return errs[0]
 			}
 			return nil
 		}
-		interruptionEvent.PreDrainTask = func(interruptionEvent monitor.InterruptionEvent, n node.Node) error {
-			// Use provider ID to resolve the actual Kubernetes node name if UseProviderId is configured
-			nthConfig := n.GetNthConfig()
-			nodeName := interruptionEvent.NodeName
-			if nthConfig.UseProviderId && interruptionEvent.ProviderID != "" {
-				resolvedNodeName, err := n.GetNodeNameFromProviderID(interruptionEvent.ProviderID)
-				if err != nil {
-					log.Warn().Err(err).Str("provider_id", interruptionEvent.ProviderID).Msg("Failed to resolve node name from provider ID, falling back to NodeName from event")
-				} else {
-					nodeName = resolvedNodeName
-				}
+		interruptionEvent.PreDrainTask = func(_ context.Context, interruptionEvent monitor.InterruptionEvent, n node.Node) error {
+			if err := monitor.MarkNode(n, interruptionEvent.NodeName, interruptionEvent, n.TaintScheduledMaintenance); err != nil {
+				log.Err(err).Msgf("Unable to mark node for event %s", interruptionEvent.EventID)
 			}
-
-			if err := n.TaintScheduledMaintenance(nodeName, interruptionEvent.EventID); err != nil {
-				log.Err(err).Msgf("Unable to taint node with taint %s:%s", node.ScheduledMaintenanceTaint, interruptionEvent.EventID)
+			if restartEventTypeCodes[eventTypeCode] {
+				return markForUncordonAfterReboot(interruptionEvent.NodeName, interruptionEvent.EventID, n)
 			}
 			return nil
 		}
@@ -136,3 +150,23 @@ func (m SQSMonitor) scheduledEventToInterruptionEvents(event *EventBridgeEvent,
 
 	return interruptionEventWrappers
 }
+
+// markForUncordonAfterReboot marks the node so that it is uncordoned once it rebooted, unless it was already unschedulable
+func markForUncordonAfterReboot(nodeName string, eventID string, n node.Node) error {
+	unschedulable, err := n.IsUnschedulable(nodeName)
+	if err != nil {
+		return fmt.Errorf("encountered an error while checking if the node is unschedulable. not setting an uncordon label: %w", err)
+	}
+	if unschedulable {
+		log.Debug().Str("node_name", nodeName).Msg("Node is already marked unschedulable, not taking any action to add uncordon label.")
+		return nil
+	}
+	if err := n.MarkWithEventID(nodeName, eventID); err != nil {
+		return fmt.Errorf("unable to mark node with event ID: %w", err)
+	}
+	if err := n.MarkForUncordonAfterReboot(nodeName); err != nil {
+		return fmt.Errorf("unable to mark the node for uncordon: %w", err)
+	}
+	log.Info().Str("node_name", nodeName).Msg("Successfully applied uncordon after reboot action label to node.")
+	return nil
+}
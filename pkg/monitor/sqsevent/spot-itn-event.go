@@ -14,6 +14,7 @@
 package sqsevent
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -65,6 +66,7 @@ func (m SQSMonitor) spotITNTerminationToInterruptionEvent(event *EventBridgeEven
 		Monitor:              SQSMonitorKind,
 		AutoScalingGroupName: nodeInfo.AsgName,
 		StartTime:            event.getTime(),
+		DrainDeadline:        event.getTime().Add(monitor.SpotITNNoticePeriod),
 		NodeName:             nodeInfo.Name,
 		IsManaged:            nodeInfo.IsManaged,
 		InstanceID:           spotInterruptionDetail.InstanceID,
@@ -72,29 +74,17 @@ func (m SQSMonitor) spotITNTerminationToInterruptionEvent(event *EventBridgeEven
 		InstanceType:         nodeInfo.InstanceType,
 		Description:          fmt.Sprintf("Spot Interruption notice for instance %s was sent at %s \n", spotInterruptionDetail.InstanceID, event.getTime()),
 	}
-	interruptionEvent.PostDrainTask = func(interruptionEvent monitor.InterruptionEvent, n node.Node) error {
+	interruptionEvent.PostDrainTask = func(_ context.Context, interruptionEvent monitor.InterruptionEvent, n node.Node) error {
 		errs := m.deleteMessages([]*sqs.Message{message})
 		if errs != nil {
 			return errs[0]
 		}
 		return nil
 	}
-	interruptionEvent.PreDrainTask = func(interruptionEvent monitor.InterruptionEvent, n node.Node) error {
-		// Use provider ID to resolve the actual Kubernetes node name if UseProviderId is configured
-		nthConfig := n.GetNthConfig()
-		nodeName := interruptionEvent.NodeName
-		if nthConfig.UseProviderId && interruptionEvent.ProviderID != "" {
-			resolvedNodeName, err := n.GetNodeNameFromProviderID(interruptionEvent.ProviderID)
-			if err != nil {
-				log.Warn().Err(err).Str("provider_id", interruptionEvent.ProviderID).Msg("Failed to resolve node name from provider ID, falling back to NodeName from event")
-			} else {
-				nodeName = resolvedNodeName
-			}
-		}
-
-		err := n.TaintSpotItn(nodeName, interruptionEvent.EventID)
+	interruptionEvent.PreDrainTask = func(_ context.Context, interruptionEvent monitor.InterruptionEvent, n node.Node) error {
+		err := monitor.MarkNode(n, interruptionEvent.NodeName, interruptionEvent, n.TaintSpotItn)
 		if err != nil {
-			log.Err(err).Msgf("Unable to taint node with taint %s:%s", node.SpotInterruptionTaint, interruptionEvent.EventID)
+			log.Err(err).Msgf("Unable to mark node for event %s", interruptionEvent.EventID)
 		}
 		return err
 	}
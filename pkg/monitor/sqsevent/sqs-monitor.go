@@ -14,6 +14,7 @@
 package sqsevent
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -21,6 +22,7 @@ import (
 	"github.com/aws/aws-node-termination-handler/pkg/config"
 	"github.com/aws/aws-node-termination-handler/pkg/logging"
 	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+	"github.com/aws/aws-node-termination-handler/pkg/node"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
@@ -33,6 +35,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"go.uber.org/multierr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 const (
@@ -42,6 +45,8 @@ const (
 	ASGTagName                        = "aws:autoscaling:groupName"
 	ASGTerminatingLifecycleTransition = "autoscaling:EC2_INSTANCE_TERMINATING"
 	ASGLaunchingLifecycleTransition   = "autoscaling:EC2_INSTANCE_LAUNCHING"
+	// maxReceivedMessages is the most messages SQS returns for a single receive
+	maxReceivedMessages = 10
 )
 
 // SQSMonitor is a struct definition that knows how to process events from Amazon EventBridge
@@ -55,7 +60,16 @@ type SQSMonitor struct {
 	CheckIfManaged                bool
 	ManagedTag                    string
 	BeforeCompleteLifecycleAction func()
-	SqsMsgVisibilityTimeoutSec    int
+	// HeartbeatsStopped, if set, is called once the heartbeats of a lifecycle action stop
+	HeartbeatsStopped          func()
+	SqsMsgVisibilityTimeoutSec int
+	// OverflowPolicy decides what happens to an event which does not fit in a full InterruptionChan, see monitor.Send
+	OverflowPolicy string
+	// NodeResolver finds the Kubernetes node of the instance of an event, the node is named after the private DNS name of the instance without it
+	NodeResolver *node.NodeResolver
+	// HeartbeatUntil is the period in seconds over which the lifecycle actions of ASG termination hooks are kept alive by heartbeats,
+	// -1 if NTH sends no heartbeats
+	HeartbeatUntil int
 }
 
 // InterruptionEventWrapper is a convenience wrapper for associating an interruption event with its error, if any
@@ -83,15 +97,36 @@ func (m SQSMonitor) Kind() string {
 }
 
 // Monitor continuously monitors SQS for events and coordinates processing of the events
-func (m SQSMonitor) Monitor() error {
+//
+// Once ctx is done, messages which have been received but not yet handed off are released back to the queue.
+func (m SQSMonitor) Monitor(ctx context.Context) error {
+	maxMessages := int64(maxReceivedMessages)
+	if headroom, bounded := monitor.Headroom(m.InterruptionChan); bounded {
+		if headroom == 0 {
+			// messages left in the queue stay visible to other consumers instead of lapsing their visibility timeout here
+			log.Debug().Int("capacity", cap(m.InterruptionChan)).Msg("Ingestion queue is full, deferring receiving queue messages")
+			return nil
+		}
+		if int64(headroom) < maxMessages {
+			maxMessages = int64(headroom)
+		}
+	}
 	log.Debug().Msg("Checking for queue messages")
-	messages, err := m.receiveQueueMessages(m.QueueURL)
+	messages, err := m.receiveQueueMessages(ctx, m.QueueURL, maxMessages)
 	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return err
 	}
 
 	failedEventBridgeEvents := 0
-	for _, message := range messages {
+	for i, message := range messages {
+		if ctx.Err() != nil {
+			m.releaseMessages(messages[i:])
+			return ctx.Err()
+		}
+
 		eventBridgeEvent, err := m.processSQSMessage(message)
 		if err != nil {
 			var s skip
@@ -106,7 +141,11 @@ func (m SQSMonitor) Monitor() error {
 
 		interruptionEventWrappers := m.processEventBridgeEvent(eventBridgeEvent, message)
 
-		if err = m.processInterruptionEvents(interruptionEventWrappers, message); err != nil {
+		if err = m.processInterruptionEvents(ctx, interruptionEventWrappers, message); err != nil {
+			if ctx.Err() != nil {
+				m.releaseMessages(messages[i+1:])
+				return ctx.Err()
+			}
 			log.Err(err).Msg("error processing interruption events")
 			failedEventBridgeEvents++
 		}
@@ -243,7 +282,7 @@ func (m SQSMonitor) processEventBridgeEvent(eventBridgeEvent *EventBridgeEvent,
 }
 
 // processInterruptionEvents takes interruption event wrappers and sends events to the interruption channel
-func (m SQSMonitor) processInterruptionEvents(interruptionEventWrappers []InterruptionEventWrapper, message *sqs.Message) error {
+func (m SQSMonitor) processInterruptionEvents(ctx context.Context, interruptionEventWrappers []InterruptionEventWrapper, message *sqs.Message) error {
 	dropMessageSuggestionCount := 0
 	failedInterruptionEventsCount := 0
 	var skipErr skip
@@ -271,7 +310,19 @@ func (m SQSMonitor) processInterruptionEvents(interruptionEventWrappers []Interr
 		case eventWrapper.InterruptionEvent.Monitor == SQSMonitorKind:
 			// Successfully processed SQS message into a eventWrapper.InterruptionEvent.Kind interruption event
 			logging.VersionedMsgs.SendingInterruptionEventToChannel(eventWrapper.InterruptionEvent.Kind)
-			m.InterruptionChan <- *eventWrapper.InterruptionEvent
+			eventWrapper.InterruptionEvent.ReleaseTask = func(_ context.Context, _ monitor.InterruptionEvent, _ node.Node) error {
+				return m.releaseMessage(message)
+			}
+			if err := monitor.Send(ctx, m.InterruptionChan, *eventWrapper.InterruptionEvent, m.OverflowPolicy); err != nil {
+				if errors.Is(err, monitor.ErrIngestionQueueFull) {
+					// the message is not deleted, so it is received again once its visibility timeout lapses
+					log.Warn().Str("message_id", aws.StringValue(message.MessageId)).Msg("Ingestion queue is full, rejecting interruption event")
+					failedInterruptionEventsCount++
+					continue
+				}
+				m.releaseMessages([]*sqs.Message{message})
+				return fmt.Errorf("sending interruption event for message Id %s: %w", *message.MessageId, err)
+			}
 
 		default:
 			eventJSON, _ := json.MarshalIndent(eventWrapper.InterruptionEvent, " ", "    ")
@@ -297,13 +348,13 @@ func (m SQSMonitor) processInterruptionEvents(interruptionEventWrappers []Interr
 }
 
 // receiveQueueMessages checks the configured SQS queue for new messages
-func (m SQSMonitor) receiveQueueMessages(qURL string) ([]*sqs.Message, error) {
+func (m SQSMonitor) receiveQueueMessages(ctx context.Context, qURL string, maxMessages int64) ([]*sqs.Message, error) {
 	visibilityTimeout := m.SqsMsgVisibilityTimeoutSec
 	if visibilityTimeout <= 0 || visibilityTimeout >= 120 {
 		visibilityTimeout = config.SqsMsgVisibilityTimeoutSecDefault
 	}
 
-	result, err := m.SQS.ReceiveMessage(&sqs.ReceiveMessageInput{
+	result, err := m.SQS.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
 		AttributeNames: []*string{
 			aws.String(sqs.MessageSystemAttributeNameSentTimestamp),
 		},
@@ -311,7 +362,7 @@ func (m SQSMonitor) receiveQueueMessages(qURL string) ([]*sqs.Message, error) {
 			aws.String(sqs.QueueAttributeNameAll),
 		},
 		QueueUrl:            &qURL,
-		MaxNumberOfMessages: aws.Int64(10),
+		MaxNumberOfMessages: aws.Int64(maxMessages),
 		VisibilityTimeout:   aws.Int64(int64(visibilityTimeout)),
 		WaitTimeSeconds:     aws.Int64(20), // Max long polling
 	})
@@ -339,6 +390,33 @@ func (m SQSMonitor) deleteMessages(messages []*sqs.Message) []error {
 	return errs
 }
 
+// releaseMessages makes messages immediately visible to other consumers of the configured SQS queue
+func (m SQSMonitor) releaseMessages(messages []*sqs.Message) []error {
+	var errs []error
+	for _, message := range messages {
+		_, err := m.SQS.ChangeMessageVisibility(&sqs.ChangeMessageVisibilityInput{
+			ReceiptHandle:     message.ReceiptHandle,
+			QueueUrl:          &m.QueueURL,
+			VisibilityTimeout: aws.Int64(0),
+		})
+		if err != nil {
+			log.Warn().Err(err).Str("message_id", aws.StringValue(message.MessageId)).Msg("Unable to release SQS message")
+			errs = append(errs, err)
+			continue
+		}
+		log.Info().Str("message_id", aws.StringValue(message.MessageId)).Msg("Released SQS message back to the queue")
+	}
+	return errs
+}
+
+func (m SQSMonitor) releaseMessage(message *sqs.Message) error {
+	errs := m.releaseMessages([]*sqs.Message{message})
+	if errs != nil {
+		return errs[0]
+	}
+	return nil
+}
+
 // completeLifecycleAction completes the lifecycle action after calling the "before" hook.
 func (m SQSMonitor) completeLifecycleAction(input *autoscaling.CompleteLifecycleActionInput) (*autoscaling.CompleteLifecycleActionOutput, error) {
 	if m.BeforeCompleteLifecycleAction != nil {
@@ -358,6 +436,30 @@ type NodeInfo struct {
 	Tags         map[string]string
 }
 
+// resolveNodeName returns the name of the Kubernetes node of the instance found by the node resolver,
+// or the private DNS name of the instance if it finds none, e.g. the instance has not joined the cluster yet
+func (m SQSMonitor) resolveNodeName(nodeInfo *NodeInfo) string {
+	if m.NodeResolver == nil {
+		return nodeInfo.Name
+	}
+	nodeName, err := m.NodeResolver.Resolve(context.TODO(), node.Instance{
+		InstanceID:     nodeInfo.InstanceID,
+		ProviderID:     nodeInfo.ProviderID,
+		PrivateDNSName: nodeInfo.Name,
+		Tags:           nodeInfo.Tags,
+	})
+	if err != nil {
+		logger := log.With().Err(err).Str("instance_id", nodeInfo.InstanceID).Logger()
+		if apierrors.IsNotFound(err) {
+			logger.Debug().Msg("No node found for the instance, using its private DNS name")
+		} else {
+			logger.Warn().Msg("Unable to resolve the node of the instance, using its private DNS name")
+		}
+		return nodeInfo.Name
+	}
+	return nodeName
+}
+
 // getNodeInfo returns the NodeInfo record for the given instanceID.
 //
 // The data is retrieved from the EC2 API.
@@ -434,6 +536,7 @@ func (m SQSMonitor) getNodeInfo(instanceID string) (*NodeInfo, error) {
 			nodeInfo.AsgName = *t.Value
 		}
 	}
+	nodeInfo.Name = m.resolveNodeName(nodeInfo)
 
 	if m.CheckIfManaged {
 		if _, ok := nodeInfo.Tags[m.ManagedTag]; !ok {
@@ -14,6 +14,7 @@
 package sqsevent_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -178,7 +179,7 @@ func TestMonitor_EventBridgeSuccess(t *testing.T) {
 			InterruptionChan: drainChan,
 		}
 
-		err = sqsMonitor.Monitor()
+		err = sqsMonitor.Monitor(context.Background())
 		h.Ok(t, err)
 
 		select {
@@ -189,7 +190,8 @@ func TestMonitor_EventBridgeSuccess(t *testing.T) {
 			h.Assert(t, result.PostDrainTask != nil, "PostDrainTask should have been set")
 			h.Assert(t, result.PreDrainTask != nil, "PreDrainTask should have been set")
 			if event.ID == asgLifecycleEvent.ID { h.Assert(t, result.CancelDrainTask != nil, "CancelDrainTask should have been set") }
-			err = result.PostDrainTask(result, node.Node{})
+			h.Assert(t, result.ReleaseTask != nil, "ReleaseTask should have been set")
+			err = result.PostDrainTask(context.Background(), result, node.Node{})
 			h.Ok(t, err)
 		default:
 			h.Ok(t, fmt.Errorf("Expected an event to be generated"))
@@ -223,7 +225,7 @@ func TestMonitor_EventBridgeTestNotification(t *testing.T) {
 		InterruptionChan: drainChan,
 	}
 
-	err = sqsMonitor.Monitor()
+	err = sqsMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 
 	select {
@@ -264,7 +266,7 @@ func TestMonitor_AsgDirectToSqsSuccess(t *testing.T) {
 		InterruptionChan: drainChan,
 	}
 
-	err = sqsMonitor.Monitor()
+	err = sqsMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 
 	select {
@@ -275,13 +277,43 @@ func TestMonitor_AsgDirectToSqsSuccess(t *testing.T) {
 		h.Assert(t, result.PostDrainTask != nil, "PostDrainTask should have been set")
 		h.Assert(t, result.PreDrainTask != nil, "PreDrainTask should have been set")
 		h.Assert(t, result.CancelDrainTask != nil, "CancelDrainTask should have been set")
-		err = result.PostDrainTask(result, node.Node{})
+		err = result.PostDrainTask(context.Background(), result, node.Node{})
 		h.Ok(t, err)
 	default:
 		h.Ok(t, fmt.Errorf("Expected an event to be generated"))
 	}
 }
 
+func TestMonitor_AsgLifecycleDeadline(t *testing.T) {
+	msg, err := getSQSMessageFromEvent(asgLifecycleEvent)
+	h.Ok(t, err)
+	dnsNodeName := "ip-10-0-0-157.us-east-2.compute.internal"
+	for _, test := range []struct {
+		heartbeatUntil int
+		expected       time.Duration
+	}{
+		{heartbeatUntil: -1, expected: 300 * time.Second},
+		{heartbeatUntil: 3600, expected: 3600 * time.Second},
+		// the global timeout of the hook is 100 times its heartbeat timeout
+		{heartbeatUntil: 172800, expected: 30000 * time.Second},
+	} {
+		drainChan := make(chan monitor.InterruptionEvent, 1)
+		sqsMonitor := sqsevent.SQSMonitor{
+			SQS:              h.MockedSQS{ReceiveMessageResp: sqs.ReceiveMessageOutput{Messages: []*sqs.Message{&msg}}},
+			EC2:              h.MockedEC2{DescribeInstancesResp: getDescribeInstancesResp(dnsNodeName, true, true)},
+			ASG:              h.MockedASG{HeartbeatTimeout: 300},
+			InterruptionChan: drainChan,
+			HeartbeatUntil:   test.heartbeatUntil,
+		}
+		h.Ok(t, sqsMonitor.Monitor(context.Background()))
+
+		result := <-drainChan
+		deadline, ok := result.Deadline()
+		h.Assert(t, ok, "ASG lifecycle event should have a deadline")
+		h.Equals(t, test.expected, deadline.Sub(result.StartTime))
+	}
+}
+
 func TestMonitor_AsgDirectToSqsTestNotification(t *testing.T) {
 	eventBytes, err := json.Marshal(&asgLifecycleTestNotificationFromSQS)
 	h.Ok(t, err)
@@ -310,7 +342,7 @@ func TestMonitor_AsgDirectToSqsTestNotification(t *testing.T) {
 		InterruptionChan: drainChan,
 	}
 
-	err = sqsMonitor.Monitor()
+	err = sqsMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 
 	select {
@@ -354,7 +386,7 @@ func TestMonitor_DrainTasks(t *testing.T) {
 		InterruptionChan: drainChan,
 	}
 
-	err := sqsMonitor.Monitor()
+	err := sqsMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 
 	i := 0
@@ -368,7 +400,7 @@ func TestMonitor_DrainTasks(t *testing.T) {
 			h.Assert(st, result.PostDrainTask != nil, "PostDrainTask should have been set")
 			h.Assert(st, result.PreDrainTask != nil, "PreDrainTask should have been set")
 			if event.ID == asgLifecycleEvent.ID { h.Assert(t, result.CancelDrainTask != nil, "CancelDrainTask should have been set") }
-			err := result.PostDrainTask(result, node.Node{})
+			err := result.PostDrainTask(context.Background(), result, node.Node{})
 			h.Ok(st, err)
 		})
 		i++
@@ -405,7 +437,7 @@ func TestMonitor_DrainTasks_Delay(t *testing.T) {
 		BeforeCompleteLifecycleAction: func() { hookCalled = true },
 	}
 
-	err = sqsMonitor.Monitor()
+	err = sqsMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 
 	t.Run(asgLaunchLifecycleEvent.DetailType, func(st *testing.T) {
@@ -414,7 +446,7 @@ func TestMonitor_DrainTasks_Delay(t *testing.T) {
 		h.Equals(st, sqsevent.SQSMonitorKind, result.Monitor)
 		h.Equals(st, result.NodeName, dnsNodeName)
 		h.Assert(st, result.PostDrainTask != nil, "PostDrainTask should have been set")
-		err := result.PostDrainTask(result, node.Node{})
+		err := result.PostDrainTask(context.Background(), result, node.Node{})
 		h.Ok(st, err)
 		h.Assert(st, hookCalled, "BeforeCompleteLifecycleAction hook not called")
 	})
@@ -453,7 +485,7 @@ func TestMonitor_DrainTasks_Errors(t *testing.T) {
 		InterruptionChan: drainChan,
 	}
 
-	err := sqsMonitor.Monitor()
+	err := sqsMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 
 	count := 0
@@ -470,7 +502,7 @@ func TestMonitor_DrainTasks_Errors(t *testing.T) {
 			h.Assert(t, result.PostDrainTask != nil, "PostDrainTask should have been set")
 			h.Assert(t, result.PreDrainTask != nil, "PreDrainTask should have been set")
 			if i == 1 { h.Assert(t, result.CancelDrainTask != nil, "CancelDrainTask should have been set") }
-			err := result.PostDrainTask(result, node.Node{})
+			err := result.PostDrainTask(context.Background(), result, node.Node{})
 			h.Ok(t, err)
 		default:
 			done = true
@@ -511,7 +543,7 @@ func TestMonitor_DrainTasksASGFailure(t *testing.T) {
 		InterruptionChan: drainChan,
 	}
 
-	err = sqsMonitor.Monitor()
+	err = sqsMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 
 	select {
@@ -520,7 +552,7 @@ func TestMonitor_DrainTasksASGFailure(t *testing.T) {
 		h.Equals(t, sqsevent.SQSMonitorKind, result.Monitor)
 		h.Equals(t, result.NodeName, dnsNodeName)
 		h.Assert(t, result.PostDrainTask != nil, "PostDrainTask should have been set")
-		err = result.PostDrainTask(result, node.Node{})
+		err = result.PostDrainTask(context.Background(), result, node.Node{})
 		h.Nok(t, err)
 	default:
 		h.Ok(t, fmt.Errorf("Expected to get an event with a failing post drain task"))
@@ -547,7 +579,7 @@ func TestMonitor_Failure(t *testing.T) {
 			InterruptionChan: drainChan,
 		}
 
-		err = sqsMonitor.Monitor()
+		err = sqsMonitor.Monitor(context.Background())
 		h.Nok(t, err)
 
 		select {
@@ -578,7 +610,7 @@ func TestMonitor_SQSFailure(t *testing.T) {
 			InterruptionChan: drainChan,
 		}
 
-		err = sqsMonitor.Monitor()
+		err = sqsMonitor.Monitor(context.Background())
 		h.Nok(t, err)
 
 		select {
@@ -605,7 +637,7 @@ func TestMonitor_SQSNoMessages(t *testing.T) {
 		QueueURL:         "https://test-queue",
 		InterruptionChan: drainChan,
 	}
-	err := sqsMonitor.Monitor()
+	err := sqsMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 
 	select {
@@ -643,7 +675,7 @@ func TestMonitor_SQSJsonErr(t *testing.T) {
 			QueueURL:         "https://test-queue",
 			InterruptionChan: drainChan,
 		}
-		err := sqsMonitor.Monitor()
+		err := sqsMonitor.Monitor(context.Background())
 		h.Nok(t, err)
 
 		select {
@@ -679,7 +711,7 @@ func TestMonitor_EC2Failure(t *testing.T) {
 			InterruptionChan: drainChan,
 		}
 
-		err = sqsMonitor.Monitor()
+		err = sqsMonitor.Monitor(context.Background())
 		h.Nok(t, err)
 
 		select {
@@ -714,7 +746,7 @@ func TestMonitor_EC2NoInstances(t *testing.T) {
 			InterruptionChan: drainChan,
 		}
 
-		err = sqsMonitor.Monitor()
+		err = sqsMonitor.Monitor(context.Background())
 		h.Ok(t, err)
 
 		select {
@@ -750,7 +782,7 @@ func TestMonitor_DescribeInstancesError(t *testing.T) {
 			InterruptionChan: drainChan,
 		}
 
-		err = sqsMonitor.Monitor()
+		err = sqsMonitor.Monitor(context.Background())
 		h.Ok(t, err)
 
 		select {
@@ -787,7 +819,7 @@ func TestMonitor_EC2NoDNSName(t *testing.T) {
 		InterruptionChan: drainChan,
 	}
 
-	err = sqsMonitor.Monitor()
+	err = sqsMonitor.Monitor(context.Background())
 	h.Ok(t, err)
 
 	select {
@@ -826,7 +858,7 @@ func TestMonitor_EC2NoDNSNameOnTerminatedInstance(t *testing.T) {
 		InterruptionChan: drainChan,
 	}
 
-	err = sqsMonitor.Monitor()
+	err = sqsMonitor.Monitor(context.Background())
 	h.Nok(t, err)
 
 	select {
@@ -863,7 +895,7 @@ func TestMonitor_SQSDeleteFailure(t *testing.T) {
 		InterruptionChan: drainChan,
 	}
 
-	err = sqsMonitor.Monitor()
+	err = sqsMonitor.Monitor(context.Background())
 	h.Nok(t, err)
 
 	select {
@@ -900,7 +932,7 @@ func TestMonitor_InstanceNotManaged(t *testing.T) {
 			InterruptionChan: drainChan,
 		}
 
-		err = sqsMonitor.Monitor()
+		err = sqsMonitor.Monitor(context.Background())
 		h.Ok(t, err)
 
 		select {
@@ -912,41 +944,171 @@ func TestMonitor_InstanceNotManaged(t *testing.T) {
 	}
 }
 
+func TestMonitor_ContextCanceled(t *testing.T) {
+	msg, err := getSQSMessageFromEvent(spotItnEvent)
+	h.Ok(t, err)
+	messages := []*sqs.Message{
+		&msg,
+	}
+	sqsMock := h.MockedSQS{
+		ReceiveMessageResp: sqs.ReceiveMessageOutput{Messages: messages},
+		ReceiveMessageErr:  nil,
+	}
+	dnsNodeName := "ip-10-0-0-157.us-east-2.compute.internal"
+	ec2Mock := h.MockedEC2{
+		DescribeInstancesResp: getDescribeInstancesResp(dnsNodeName, true, true),
+	}
+	drainChan := make(chan monitor.InterruptionEvent)
+
+	sqsMonitor := sqsevent.SQSMonitor{
+		SQS:              sqsMock,
+		EC2:              ec2Mock,
+		ASG:              &h.MockedASG{},
+		QueueURL:         "https://test-queue",
+		InterruptionChan: drainChan,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = sqsMonitor.Monitor(ctx)
+	h.Equals(t, context.Canceled, err)
+
+	select {
+	case <-drainChan:
+		h.Ok(t, fmt.Errorf("Expected no events"))
+	default:
+		h.Ok(t, nil)
+	}
+}
+
+func TestMonitor_IngestionQueueFull(t *testing.T) {
+	msg, err := getSQSMessageFromEvent(spotItnEvent)
+	h.Ok(t, err)
+	sqsMock := h.MockedSQS{
+		ReceiveMessageResp: sqs.ReceiveMessageOutput{Messages: []*sqs.Message{&msg}},
+		ReceiveMessageErr:  nil,
+	}
+	dnsNodeName := "ip-10-0-0-157.us-east-2.compute.internal"
+	ec2Mock := h.MockedEC2{
+		DescribeInstancesResp: getDescribeInstancesResp(dnsNodeName, true, true),
+	}
+	drainChan := make(chan monitor.InterruptionEvent, 1)
+	drainChan <- monitor.InterruptionEvent{EventID: "queued"}
+
+	sqsMonitor := sqsevent.SQSMonitor{
+		SQS:              sqsMock,
+		EC2:              ec2Mock,
+		ASG:              &h.MockedASG{},
+		QueueURL:         "https://test-queue",
+		InterruptionChan: drainChan,
+	}
+
+	// receiving is deferred rather than blocking until the store takes the queued event in
+	err = sqsMonitor.Monitor(context.Background())
+	h.Ok(t, err)
+	h.Equals(t, 1, len(drainChan))
+	h.Equals(t, "queued", (<-drainChan).EventID)
+}
+
+func TestMonitor_IngestionQueueOverflowReject(t *testing.T) {
+	msg, err := getSQSMessageFromEvent(spotItnEvent)
+	h.Ok(t, err)
+	otherMsg, err := getSQSMessageFromEvent(spotItnEvent)
+	h.Ok(t, err)
+	sqsMock := h.MockedSQS{
+		ReceiveMessageResp: sqs.ReceiveMessageOutput{Messages: []*sqs.Message{&msg, &otherMsg}},
+		ReceiveMessageErr:  nil,
+	}
+	dnsNodeName := "ip-10-0-0-157.us-east-2.compute.internal"
+	ec2Mock := h.MockedEC2{
+		DescribeInstancesResp: getDescribeInstancesResp(dnsNodeName, true, true),
+	}
+	drainChan := make(chan monitor.InterruptionEvent, 1)
+
+	sqsMonitor := sqsevent.SQSMonitor{
+		SQS:              sqsMock,
+		EC2:              ec2Mock,
+		ASG:              &h.MockedASG{},
+		QueueURL:         "https://test-queue",
+		InterruptionChan: drainChan,
+		OverflowPolicy:   config.IngestionOverflowReject,
+	}
+
+	// the event which does not fit is rejected instead of blocking the monitor
+	err = sqsMonitor.Monitor(context.Background())
+	h.Ok(t, err)
+	h.Equals(t, 1, len(drainChan))
+}
+
 func TestSendHeartbeats_EarlyClosure(t *testing.T) {
 	err := heartbeatTestHelper(nil, 3500, 1, 5, false)
 	h.Ok(t, err)
-	h.Assert(t, h.HeartbeatCallCount == 3, "3 Heartbeat Expected, got %d", h.HeartbeatCallCount)
+	h.Assert(t, h.HeartbeatCallCount.Load() == 3, "3 Heartbeat Expected, got %d", h.HeartbeatCallCount.Load())
 }
 
 func TestSendHeartbeats_HeartbeatUntilExpire(t *testing.T) {
 	err := heartbeatTestHelper(nil, 8000, 1, 5, false)
 	h.Ok(t, err)
-	h.Assert(t, h.HeartbeatCallCount == 5, "5 Heartbeat Expected, got %d", h.HeartbeatCallCount)
+	h.Assert(t, h.HeartbeatCallCount.Load() == 5, "5 Heartbeat Expected, got %d", h.HeartbeatCallCount.Load())
 }
 
 func TestSendHeartbeats_ErrThrottlingASG(t *testing.T) {
 	RecordLifecycleActionHeartbeatErr := awserr.New("Throttling", "Rate exceeded", nil)
 	err := heartbeatTestHelper(RecordLifecycleActionHeartbeatErr, 8000, 1, 6, false)
 	h.Ok(t, err)
-	h.Assert(t, h.HeartbeatCallCount == 6, "6 Heartbeat Expected, got %d", h.HeartbeatCallCount)
+	h.Assert(t, h.HeartbeatCallCount.Load() == 6, "6 Heartbeat Expected, got %d", h.HeartbeatCallCount.Load())
 }
 
 func TestSendHeartbeats_ErrInvalidTarget(t *testing.T) {
 	RecordLifecycleActionHeartbeatErr := awserr.New("ValidationError", "No active Lifecycle Action found", nil)
 	err := heartbeatTestHelper(RecordLifecycleActionHeartbeatErr, 6000, 1, 4, false)
 	h.Ok(t, err)
-	h.Assert(t, h.HeartbeatCallCount == 1, "1 Heartbeat Expected, got %d", h.HeartbeatCallCount)
+	h.Assert(t, h.HeartbeatCallCount.Load() == 1, "1 Heartbeat Expected, got %d", h.HeartbeatCallCount.Load())
 }
 
 
 func TestSendHeartbeats_CancelHeartbeat(t *testing.T) {
 	err := heartbeatTestHelper(nil, 6000, 1, 4, true)
 	h.Ok(t, err)
-	h.Assert(t, h.HeartbeatCallCount == 2, "2 Heartbeat Expected, got %d", h.HeartbeatCallCount)
+	h.Assert(t, h.HeartbeatCallCount.Load() == 2, "2 Heartbeat Expected, got %d", h.HeartbeatCallCount.Load())
+}
+
+func TestSendHeartbeats_StartedWhenDeferred(t *testing.T) {
+	h.HeartbeatCallCount.Store(0)
+	msg, err := getSQSMessageFromEvent(asgLifecycleEvent)
+	h.Ok(t, err)
+	dnsNodeName := "ip-10-0-0-157.us-east-2.compute.internal"
+	drainChan := make(chan monitor.InterruptionEvent, 1)
+	heartbeatSent := make(chan struct{}, 2)
+	heartbeatsStopped := make(chan struct{})
+	sqsMonitor := sqsevent.SQSMonitor{
+		SQS:               h.MockedSQS{ReceiveMessageResp: sqs.ReceiveMessageOutput{Messages: []*sqs.Message{&msg}}},
+		EC2:               h.MockedEC2{DescribeInstancesResp: getDescribeInstancesResp(dnsNodeName, true, true)},
+		ASG:               h.MockedASG{HeartbeatTimeout: 30, HeartbeatSent: heartbeatSent},
+		InterruptionChan:  drainChan,
+		HeartbeatsStopped: func() { close(heartbeatsStopped) },
+	}
+	h.Ok(t, sqsMonitor.Monitor(context.Background()))
+
+	testNode, _ := node.New(config.Config{HeartbeatInterval: 1, HeartbeatUntil: 60}, nil)
+	result := <-drainChan
+	h.Assert(t, result.DeferTask != nil, "DeferTask should have been set")
+	h.Ok(t, result.DeferTask(context.Background(), result, *testNode))
+	h.Ok(t, result.DeferTask(context.Background(), result, *testNode))
+	// the heartbeats started while the event was deferred carry on once it is drained
+	h.Ok(t, result.PreDrainTask(context.Background(), result, *testNode))
+	<-heartbeatSent
+	h.Ok(t, result.CancelDrainTask(context.Background(), result, *testNode))
+	select {
+	case <-heartbeatsStopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Heartbeats did not stop once the drain was cancelled")
+	}
+	h.Assert(t, h.HeartbeatCallCount.Load() == 1, "1 Heartbeat Expected, got %d", h.HeartbeatCallCount.Load())
 }
 
 func heartbeatTestHelper(RecordLifecycleActionHeartbeatErr error, sleepMilliSeconds int, heartbeatInterval int, heartbeatUntil int, cancelDrain bool) error {
-	h.HeartbeatCallCount = 0
+	h.HeartbeatCallCount.Store(0)
 
 	msg, err := getSQSMessageFromEvent(asgLifecycleEvent)
 	if err != nil {
@@ -978,7 +1140,7 @@ func heartbeatTestHelper(RecordLifecycleActionHeartbeatErr error, sleepMilliSeco
 		},
 	}
 
-	if err := sqsMonitor.Monitor(); err != nil {
+	if err := sqsMonitor.Monitor(context.Background()); err != nil {
 		return err
 	}
 
@@ -993,7 +1155,7 @@ func heartbeatTestHelper(RecordLifecycleActionHeartbeatErr error, sleepMilliSeco
 	if result.PreDrainTask == nil {
 		return fmt.Errorf("PreDrainTask should have been set")
 	}
-	if err := result.PreDrainTask(result, *testNode); err != nil {
+	if err := result.PreDrainTask(context.Background(), result, *testNode); err != nil {
 		return err
 	}
 
@@ -1002,7 +1164,7 @@ func heartbeatTestHelper(RecordLifecycleActionHeartbeatErr error, sleepMilliSeco
 			return fmt.Errorf("CancelDrainTask should have been set")
 		}
 		time.Sleep(2100 * time.Millisecond)
-		if err := result.CancelDrainTask(result, *testNode); err != nil {
+		if err := result.CancelDrainTask(context.Background(), result, *testNode); err != nil {
 			return err
 		}
 	}
@@ -1010,7 +1172,7 @@ func heartbeatTestHelper(RecordLifecycleActionHeartbeatErr error, sleepMilliSeco
 	if result.PostDrainTask == nil {
 		return fmt.Errorf("PostDrainTask should have been set")
 	}
-	if err := result.PostDrainTask(result, *testNode); err != nil {
+	if err := result.PostDrainTask(context.Background(), result, *testNode); err != nil {
 		return err
 	}
 
@@ -0,0 +1,212 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	"github.com/aws/aws-node-termination-handler/pkg/logging"
+	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+	"github.com/aws/aws-node-termination-handler/pkg/observability"
+)
+
+// State is the circuit breaker state of a supervised monitor
+type State string
+
+const (
+	// StateClosed means the monitor is polled at its regular interval, backing off after failures
+	StateClosed State = "closed"
+	// StateOpen means the monitor failed too many times in a row and is not polled until the cooldown expires
+	StateOpen State = "open"
+	// StateHalfOpen means the cooldown expired and the next poll decides whether the circuit closes or opens again
+	StateHalfOpen State = "half-open"
+)
+
+// Config holds the backoff and circuit breaker settings shared by all supervisors
+type Config struct {
+	// Interval is the delay between polls of a healthy monitor
+	Interval time.Duration
+	// MaxBackoff caps the exponential backoff applied after consecutive failures
+	MaxBackoff time.Duration
+	// FailureThreshold is the number of consecutive failures which opens the circuit
+	FailureThreshold int
+	// Cooldown is how long the circuit stays open before a trial poll is allowed
+	Cooldown time.Duration
+	// ExitOnOpen makes Run return an error once the circuit opens so that the process can exit
+	ExitOnOpen bool
+}
+
+// NewConfig builds a supervisor Config from the NTH config
+func NewConfig(nthConfig config.Config) Config {
+	return Config{
+		Interval:         2 * time.Second,
+		MaxBackoff:       time.Duration(nthConfig.MonitorMaxBackoff) * time.Second,
+		FailureThreshold: nthConfig.MonitorFailureThreshold,
+		Cooldown:         time.Duration(nthConfig.MonitorCircuitCooldown) * time.Second,
+		ExitOnOpen:       nthConfig.ExitOnMonitorFailure,
+	}
+}
+
+// Supervisor polls a monitor, backing off on failures and isolating it behind a circuit breaker
+type Supervisor struct {
+	sync.RWMutex
+	monitor             monitor.Monitor
+	config              Config
+	metrics             observability.Metrics
+	recorder            observability.K8sEventRecorder
+	nodeName            string
+	state               State
+	consecutiveFailures int
+}
+
+// New creates a new Supervisor for m
+func New(m monitor.Monitor, config Config, metrics observability.Metrics, recorder observability.K8sEventRecorder, nodeName string) *Supervisor {
+	return &Supervisor{
+		monitor:  m,
+		config:   config,
+		metrics:  metrics,
+		recorder: recorder,
+		nodeName: nodeName,
+		state:    StateClosed,
+	}
+}
+
+// State returns the current circuit breaker state
+func (s *Supervisor) State() State {
+	s.RLock()
+	defer s.RUnlock()
+	return s.state
+}
+
+// Run polls the monitor until ctx is done. It only returns an error if the circuit opened and ExitOnOpen is set.
+func (s *Supervisor) Run(ctx context.Context) error {
+	logging.VersionedMsgs.MonitoringStarted(s.monitor.Kind())
+	s.metrics.MonitorCircuitStateRecord(s.monitor.Kind(), stateValue(StateClosed))
+	timer := time.NewTimer(s.config.Interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Str("monitor_type", s.monitor.Kind()).Msg("Stopped monitoring for events")
+			return nil
+		case <-timer.C:
+		}
+		if s.State() == StateOpen {
+			s.transition(StateHalfOpen, nil)
+		}
+
+		err := s.monitor.Monitor(ctx)
+		if ctx.Err() != nil {
+			continue
+		}
+		if err == nil {
+			s.recordSuccess()
+			timer.Reset(s.config.Interval)
+			continue
+		}
+
+		if s.recordFailure(err) && s.config.ExitOnOpen {
+			return fmt.Errorf("circuit breaker opened for monitor %s: %w", s.monitor.Kind(), err)
+		}
+		timer.Reset(s.nextDelay())
+	}
+}
+
+func (s *Supervisor) recordSuccess() {
+	s.Lock()
+	s.consecutiveFailures = 0
+	s.Unlock()
+	if s.State() != StateClosed {
+		s.transition(StateClosed, nil)
+	}
+}
+
+// recordFailure returns true if the failure opened the circuit
+func (s *Supervisor) recordFailure(err error) bool {
+	kind := s.monitor.Kind()
+	logging.VersionedMsgs.ProblemMonitoringForEvents(kind, err)
+	s.metrics.ErrorEventsInc(kind)
+	s.recorder.Emit(s.nodeName, observability.Warning, observability.MonitorErrReason, observability.MonitorErrMsgFmt, kind)
+
+	s.Lock()
+	s.consecutiveFailures++
+	shouldOpen := s.state == StateHalfOpen || (s.state == StateClosed && s.consecutiveFailures >= s.config.FailureThreshold)
+	s.Unlock()
+	if shouldOpen {
+		s.transition(StateOpen, err)
+	}
+	return shouldOpen
+}
+
+func (s *Supervisor) transition(state State, err error) {
+	s.Lock()
+	previous := s.state
+	s.state = state
+	failures := s.consecutiveFailures
+	s.Unlock()
+
+	kind := s.monitor.Kind()
+	s.metrics.MonitorCircuitStateRecord(kind, stateValue(state))
+	logEvent := log.Info()
+	if state == StateOpen {
+		logEvent = log.Warn().Err(err).Dur("cooldown", s.config.Cooldown)
+		s.recorder.Emit(s.nodeName, observability.Warning, observability.MonitorCircuitOpenReason, observability.MonitorCircuitOpenMsgFmt, kind, failures)
+	}
+	logEvent.Str("monitor_type", kind).
+		Str("previous_state", string(previous)).
+		Str("state", string(state)).
+		Int("consecutive_failures", failures).
+		Msg("Monitor circuit breaker changed state")
+}
+
+// nextDelay returns the cooldown while the circuit is open, or an exponential backoff with jitter otherwise
+func (s *Supervisor) nextDelay() time.Duration {
+	s.RLock()
+	state := s.state
+	failures := s.consecutiveFailures
+	s.RUnlock()
+	if state == StateOpen {
+		return s.config.Cooldown
+	}
+
+	backoff := s.config.Interval
+	for i := 0; i < failures && backoff < s.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > s.config.MaxBackoff {
+		backoff = s.config.MaxBackoff
+	}
+	// equal jitter keeps at least half of the backoff so that a failing monitor is never polled in a tight loop
+	half := backoff / 2
+	return half + rand.N(half+1)
+}
+
+// stateValue maps a state to the value of the circuit state gauge
+func stateValue(state State) int64 {
+	switch state {
+	case StateHalfOpen:
+		return 1
+	case StateOpen:
+		return 2
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package supervisor_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-node-termination-handler/pkg/monitor/supervisor"
+	"github.com/aws/aws-node-termination-handler/pkg/observability"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+)
+
+type failingMonitor struct {
+	calls    atomic.Int32
+	failures int32
+}
+
+func (m *failingMonitor) Monitor(_ context.Context) error {
+	if m.calls.Add(1) <= m.failures {
+		return errors.New("monitor failure")
+	}
+	return nil
+}
+
+func (m *failingMonitor) Kind() string {
+	return "TEST_MONITOR"
+}
+
+func getConfig() supervisor.Config {
+	return supervisor.Config{
+		Interval:         time.Millisecond,
+		MaxBackoff:       2 * time.Millisecond,
+		FailureThreshold: 3,
+		Cooldown:         time.Hour,
+	}
+}
+
+func waitForState(t *testing.T, s *supervisor.Supervisor, state supervisor.State) {
+	deadline := time.Now().Add(5 * time.Second)
+	for s.State() != state {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected state %s but was %s", state, s.State())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRun_OpensAfterThreshold(t *testing.T) {
+	m := &failingMonitor{failures: 100}
+	s := supervisor.New(m, getConfig(), observability.Metrics{}, observability.K8sEventRecorder{}, "node")
+	h.Equals(t, supervisor.StateClosed, s.State())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	waitForState(t, s, supervisor.StateOpen)
+	h.Equals(t, int32(3), m.calls.Load())
+
+	cancel()
+	h.Ok(t, <-done)
+}
+
+func TestRun_ExitOnOpen(t *testing.T) {
+	config := getConfig()
+	config.ExitOnOpen = true
+	m := &failingMonitor{failures: 100}
+	s := supervisor.New(m, config, observability.Metrics{}, observability.K8sEventRecorder{}, "node")
+
+	select {
+	case err := <-runAsync(s):
+		h.Assert(t, err != nil, "Expected an error once the circuit opened")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Run to return once the circuit opened")
+	}
+	h.Equals(t, supervisor.StateOpen, s.State())
+}
+
+func TestRun_HalfOpenRecovers(t *testing.T) {
+	config := getConfig()
+	config.FailureThreshold = 2
+	config.Cooldown = 5 * time.Millisecond
+	m := &failingMonitor{failures: 3}
+	s := supervisor.New(m, config, observability.Metrics{}, observability.K8sEventRecorder{}, "node")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = s.Run(ctx) }()
+
+	// 2 failures open the circuit, the first half-open trial fails and reopens it, and the second one closes it
+	deadline := time.Now().Add(5 * time.Second)
+	for m.calls.Load() < 4 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the monitor to be retried after the cooldown")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	waitForState(t, s, supervisor.StateClosed)
+}
+
+func runAsync(s *supervisor.Supervisor) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- s.Run(context.Background()) }()
+	return done
+}
@@ -14,6 +14,7 @@
 package monitor
 
 import (
+	"context"
 	"strings"
 	"time"
 
@@ -37,8 +38,11 @@ const (
 	SQSTerminateKind = "SQS_TERMINATE"
 )
 
-// DrainTask defines a task to be run when draining a node
-type DrainTask func(InterruptionEvent, node.Node) error
+// SpotITNNoticePeriod is the time between a spot interruption notice and the interruption of the instance
+const SpotITNNoticePeriod = 2 * time.Minute
+
+// DrainTask defines a task to be run when draining a node, which gives up once ctx is done
+type DrainTask func(context.Context, InterruptionEvent, node.Node) error
 
 // InterruptionEvent gives more context of the interruption event
 type InterruptionEvent struct {
@@ -51,17 +55,20 @@ type InterruptionEvent struct {
 	NodeName             string
 	NodeLabels           map[string]string
 	Pods                 []string
+	Evictions            []node.PodEvictionResult
 	InstanceID           string
 	ProviderID           string
 	InstanceType         string
 	IsManaged            bool
 	StartTime            time.Time
 	EndTime              time.Time
-	NodeProcessed        bool
-	InProgress           bool
+	DrainDeadline        time.Time
+	Lifecycle            Lifecycle
 	PreDrainTask         DrainTask `json:"-"`
 	PostDrainTask        DrainTask `json:"-"`
 	CancelDrainTask      DrainTask `json:"-"`
+	ReleaseTask          DrainTask `json:"-"`
+	DeferTask            DrainTask `json:"-"`
 }
 
 // TimeUntilEvent returns the duration until the event start time
@@ -69,13 +76,24 @@ func (e *InterruptionEvent) TimeUntilEvent() time.Duration {
 	return time.Until(e.StartTime)
 }
 
+// Deadline returns the time by which the node of the event must be drained, or false if the event has no deadline.
+// It is the DrainDeadline of the event, which is unrelated to its EndTime, e.g. the end of the window of a scheduled maintenance.
+func (e *InterruptionEvent) Deadline() (time.Time, bool) {
+	if e.DrainDeadline.IsZero() {
+		return time.Time{}, false
+	}
+	return e.DrainDeadline, true
+}
+
 // IsRebalanceRecommendation returns true if the interruption event is a rebalance recommendation
 func (e *InterruptionEvent) IsRebalanceRecommendation() bool {
 	return strings.Contains(e.EventID, "rebalance-recommendation")
 }
 
 // Monitor is an interface which can be implemented for various sources of interruption events
+//
+// Monitor should return promptly once ctx is done and must not block on sending events after that point.
 type Monitor interface {
-	Monitor() error
+	Monitor(ctx context.Context) error
 	Kind() string
 }
@@ -33,6 +33,22 @@ func TestTimeUntilEvent(t *testing.T) {
 	h.Equals(t, expected, result.Round(time.Second))
 }
 
+func TestDeadline(t *testing.T) {
+	drainDeadline := time.Now().Add(monitor.SpotITNNoticePeriod)
+	event := &monitor.InterruptionEvent{
+		StartTime:     time.Now(),
+		EndTime:       drainDeadline.Add(time.Hour),
+		DrainDeadline: drainDeadline,
+	}
+
+	deadline, ok := event.Deadline()
+	h.Equals(t, true, ok)
+	h.Equals(t, drainDeadline, deadline)
+
+	_, ok = (&monitor.InterruptionEvent{StartTime: time.Now(), EndTime: drainDeadline}).Deadline()
+	h.Equals(t, false, ok)
+}
+
 func TestIsRebalanceRecommendation_Monitor_Success(t *testing.T) {
 	monitorEventId := "rebalance-recommendation-"
 	event := &monitor.InterruptionEvent{
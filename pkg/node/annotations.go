@@ -0,0 +1,126 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DrainOrderAnnotationKey is a pod annotation whose integer value orders the drain waves, lower values first.
+	// A DaemonSet pod with this annotation is evicted after all the other pods when the pods are drained in waves.
+	DrainOrderAnnotationKey = "aws-node-termination-handler/drain-order"
+	// SkipEvictionAnnotationKey is a pod annotation which keeps the pod on the node when it is drained.
+	// "true" skips the pod unless the interruption has a deadline, "always" skips it even then.
+	SkipEvictionAnnotationKey = "aws-node-termination-handler/skip-eviction"
+	// SkipEvictionAlways is the value of the skip-eviction annotation which skips the pod even when the interruption has a deadline
+	SkipEvictionAlways = "always"
+	// GracePeriodAnnotationKey is a pod annotation whose value in seconds overrides the grace period the pod is evicted with.
+	// It is still cut to meet the interruption deadline.
+	GracePeriodAnnotationKey = "aws-node-termination-handler/grace-period-seconds"
+	// EvictLastAnnotationKey is a pod annotation which evicts the pod after all the other pods of the node
+	EvictLastAnnotationKey = "aws-node-termination-handler/evict-last"
+	// DeleteInsteadOfEvictAnnotationKey is a pod annotation which deletes the pod rather than evicting it,
+	// so that its PodDisruptionBudget does not hold back the drain
+	DeleteInsteadOfEvictAnnotationKey = "aws-node-termination-handler/delete-instead-of-evict"
+	// DoNotDisruptAnnotationKey is a pod annotation which defers the drain of its node for rebalance recommendations and
+	// scheduled events, up to the disruption budget max deferral. It does not defer other interruptions.
+	DoNotDisruptAnnotationKey = "aws-node-termination-handler/do-not-disrupt"
+	// DrainHoldAnnotationKey is a pod annotation set to DrainHoldRequested before a pod held by the data loss policy is evicted.
+	// The pod is evicted once a hook sets it to DrainHoldReleased, e.g. once it saved the data of the pod.
+	DrainHoldAnnotationKey = "aws-node-termination-handler/drain-hold"
+	// DrainHoldRequested is the value of the drain-hold annotation while the eviction of a pod waits for a hook
+	DrainHoldRequested = "requested"
+	// DrainHoldReleased is the value of the drain-hold annotation which releases a held pod
+	DrainHoldReleased = "released"
+)
+
+// podPolicy is the drain policy a pod sets with its annotations
+type podPolicy struct {
+	// skipEviction is "true" or SkipEvictionAlways if the pod is not evicted, or empty
+	skipEviction         string
+	gracePeriod          *int64
+	evictLast            bool
+	deleteInsteadOfEvict bool
+	drainOrder           int64
+}
+
+// skip returns true if the pod is kept on the node when it is drained, with or without a deadline
+func (p podPolicy) skip(hasDeadline bool) bool {
+	return p.skipEviction == SkipEvictionAlways || (p.skipEviction == "true" && !hasDeadline)
+}
+
+// parsePodPolicy returns the drain policy of the pod and an error for each invalid annotation, which is ignored
+func parsePodPolicy(pod corev1.Pod) (podPolicy, []error) {
+	var policy podPolicy
+	var errs []error
+	if value, ok := pod.Annotations[SkipEvictionAnnotationKey]; ok {
+		switch value {
+		case "true", SkipEvictionAlways:
+			policy.skipEviction = value
+		case "false":
+		default:
+			errs = append(errs, fmt.Errorf("invalid %s annotation %q: should be true, false or %s", SkipEvictionAnnotationKey, value, SkipEvictionAlways))
+		}
+	}
+	if value, ok := pod.Annotations[GracePeriodAnnotationKey]; ok {
+		gracePeriod, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || gracePeriod < 0 {
+			errs = append(errs, fmt.Errorf("invalid %s annotation %q: should be a number of seconds >= 0", GracePeriodAnnotationKey, value))
+		} else {
+			policy.gracePeriod = &gracePeriod
+		}
+	}
+	if value, ok := pod.Annotations[DrainOrderAnnotationKey]; ok {
+		order, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s annotation %q: should be an integer", DrainOrderAnnotationKey, value))
+		} else {
+			policy.drainOrder = order
+		}
+	}
+	var err error
+	if policy.evictLast, err = boolAnnotation(pod, EvictLastAnnotationKey); err != nil {
+		errs = append(errs, err)
+	}
+	if policy.deleteInsteadOfEvict, err = boolAnnotation(pod, DeleteInsteadOfEvictAnnotationKey); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err = DoNotDisrupt(pod); err != nil {
+		errs = append(errs, err)
+	}
+	return policy, errs
+}
+
+// DoNotDisrupt returns true if the pod has the do-not-disrupt annotation set to true,
+// and an error if the value of the annotation is invalid
+func DoNotDisrupt(pod corev1.Pod) (bool, error) {
+	return boolAnnotation(pod, DoNotDisruptAnnotationKey)
+}
+
+// boolAnnotation returns the value of a boolean pod annotation, false if it is missing or invalid
+func boolAnnotation(pod corev1.Pod, key string) (bool, error) {
+	value, ok := pod.Annotations[key]
+	if !ok {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s annotation %q: should be true or false", key, value)
+	}
+	return b, nil
+}
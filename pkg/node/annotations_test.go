@@ -0,0 +1,116 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func annotatedPod(name string, annotations map[string]string) *v1.Pod {
+	controller := true
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "default",
+			Annotations:     annotations,
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "owner", Controller: &controller}},
+		},
+		Spec: v1.PodSpec{NodeName: nodeName},
+	}
+}
+
+func TestParsePodPolicy(t *testing.T) {
+	policy, errs := parsePodPolicy(*annotatedPod("pod", map[string]string{
+		SkipEvictionAnnotationKey:         "always",
+		GracePeriodAnnotationKey:          "5",
+		EvictLastAnnotationKey:            "true",
+		DeleteInsteadOfEvictAnnotationKey: "true",
+		DrainOrderAnnotationKey:           "-3",
+	}))
+	h.Equals(t, 0, len(errs))
+	h.Equals(t, SkipEvictionAlways, policy.skipEviction)
+	h.Equals(t, int64(5), *policy.gracePeriod)
+	h.Equals(t, true, policy.evictLast)
+	h.Equals(t, true, policy.deleteInsteadOfEvict)
+	h.Equals(t, int64(-3), policy.drainOrder)
+	h.Equals(t, true, policy.skip(true))
+
+	policy, errs = parsePodPolicy(*annotatedPod("pod", map[string]string{
+		SkipEvictionAnnotationKey:         "sometimes",
+		GracePeriodAnnotationKey:          "-1",
+		EvictLastAnnotationKey:            "yes",
+		DeleteInsteadOfEvictAnnotationKey: "false",
+		DoNotDisruptAnnotationKey:         "please",
+	}))
+	// each invalid annotation is reported and ignored
+	h.Equals(t, 4, len(errs))
+	h.Equals(t, podPolicy{}, policy)
+}
+
+func TestPodsToEvictAnnotations(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		annotatedPod("skipped", map[string]string{SkipEvictionAnnotationKey: "true"}),
+		annotatedPod("always-skipped", map[string]string{SkipEvictionAnnotationKey: SkipEvictionAlways}),
+		annotatedPod("short-grace", map[string]string{GracePeriodAnnotationKey: "5"}),
+		annotatedPod("invalid", map[string]string{GracePeriodAnnotationKey: "soon"}),
+	)
+	drainHelper := getTestDrainHelper(client)
+	tNode, err := NewWithValues(config.Config{NodeName: nodeName, UseAPIServerCacheToListPods: true}, drainHelper, nil)
+	h.Ok(t, err)
+	recorder := record.NewFakeRecorder(10)
+
+	evictions, skipped, err := tNode.podsToEvict(drainHelper, nodeName, nil, false, nodeName, recorder)
+	h.Ok(t, err)
+	gracePeriods := map[string]int64{}
+	for _, eviction := range evictions {
+		gracePeriods[eviction.pod.Name] = eviction.gracePeriod
+	}
+	h.Equals(t, map[string]int64{"short-grace": 5, "invalid": v1.DefaultTerminationGracePeriodSeconds}, gracePeriods)
+	h.Equals(t, 2, len(skipped))
+	for _, result := range skipped {
+		h.Equals(t, PodSkipped, result.Outcome)
+	}
+	h.Equals(t, 1, len(recorder.Events))
+	event := <-recorder.Events
+	h.Assert(t, strings.Contains(event, PodAnnotationInvalidReason) && strings.Contains(event, GracePeriodAnnotationKey), "Expected an event for the invalid annotation, got %s", event)
+
+	// with a deadline, only the pods which always skip their eviction are kept on the node
+	evictions, skipped, err = tNode.podsToEvict(drainHelper, nodeName, nil, true, nodeName, nil)
+	h.Ok(t, err)
+	h.Equals(t, 3, len(evictions))
+	h.Equals(t, 1, len(skipped))
+	h.Equals(t, "always-skipped", skipped[0].Name)
+}
+
+func TestEvictPodsDeletesWhenPodAsks(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	// the eviction would be refused, but the pod is deleted instead
+	client.PrependReactor("create", "pods", evictionReactor(client, map[string]bool{"deleted": true}, nil))
+	evictions := createPods(t, client, "deleted")
+	evictions[0].policy.deleteInsteadOfEvict = true
+
+	results := evictor{client: client, maxParallelism: 10}.evictPods(context.Background(), evictions)
+
+	h.Equals(t, PodDeleted, results[0].Outcome)
+	h.Equals(t, 1, results[0].Attempts)
+}
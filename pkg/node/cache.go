@@ -0,0 +1,238 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Indexes of the nodes and pods of the informer cache
+const (
+	// providerIDIndex indexes nodes by the instance ID of their provider ID
+	providerIDIndex = "providerID"
+	// instanceIDLabelIndex indexes nodes by the value of the instance ID label of the node resolution
+	instanceIDLabelIndex = "instanceIDLabel"
+	// hostnameIndex indexes nodes by their kubernetes.io/hostname label
+	hostnameIndex = "hostname"
+	// nodeNameIndex indexes pods and volume attachments by the name of their node
+	nodeNameIndex = "nodeName"
+)
+
+// Cache serves nodes and pods, and volume attachments if NTH waits for volumes to be detached, from shared informers
+// which watch the API server, instead of listing them on every lookup.
+// Lookups fall back to the API server until the cache has synced.
+type Cache struct {
+	factory           informers.SharedInformerFactory
+	nodes             cache.SharedIndexInformer
+	pods              cache.SharedIndexInformer
+	volumeAttachments cache.SharedIndexInformer
+}
+
+// NewCache returns an informer cache of the nodes and pods of the cluster, which is empty until it is started
+func NewCache(nthConfig config.Config, client kubernetes.Interface) (*Cache, error) {
+	factory := informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithTransform(stripManagedFields))
+	nodes := factory.Core().V1().Nodes().Informer()
+	err := nodes.AddIndexers(cache.Indexers{
+		providerIDIndex: func(obj interface{}) ([]string, error) {
+			return nonEmpty(instanceIDFromProviderID(obj.(*corev1.Node).Spec.ProviderID)), nil
+		},
+		instanceIDLabelIndex: func(obj interface{}) ([]string, error) {
+			return nonEmpty(obj.(*corev1.Node).Labels[nthConfig.NodeResolutionInstanceIDLabel]), nil
+		},
+		hostnameIndex: func(obj interface{}) ([]string, error) {
+			return nonEmpty(obj.(*corev1.Node).Labels[hostnameLabelKey]), nil
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to index the node informer: %w", err)
+	}
+	pods := factory.Core().V1().Pods().Informer()
+	err = pods.AddIndexers(cache.Indexers{
+		nodeNameIndex: func(obj interface{}) ([]string, error) {
+			return nonEmpty(obj.(*corev1.Pod).Spec.NodeName), nil
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to index the pod informer: %w", err)
+	}
+	c := &Cache{factory: factory, nodes: nodes, pods: pods}
+	if nthConfig.WaitForVolumeDetachment {
+		c.volumeAttachments = factory.Storage().V1().VolumeAttachments().Informer()
+		err = c.volumeAttachments.AddIndexers(cache.Indexers{
+			nodeNameIndex: func(obj interface{}) ([]string, error) {
+				return nonEmpty(obj.(*storagev1.VolumeAttachment).Spec.NodeName), nil
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to index the volume attachment informer: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// Start runs the informers until ctx is done, and waits up to syncTimeout for them to sync.
+// The informers keep trying to sync in the background if they time out.
+func (c *Cache) Start(ctx context.Context, syncTimeout time.Duration) error {
+	c.factory.Start(ctx.Done())
+	syncCtx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), c.informersSynced()...) {
+		return fmt.Errorf("the informer cache did not sync within %s", syncTimeout)
+	}
+	return nil
+}
+
+// Synced returns true once the informers have listed the objects of the cluster, and false for a nil cache
+func (c *Cache) Synced() bool {
+	if c == nil {
+		return false
+	}
+	for _, synced := range c.informersSynced() {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+// informersSynced returns the functions which report whether each informer has synced
+func (c *Cache) informersSynced() []cache.InformerSynced {
+	synced := []cache.InformerSynced{c.nodes.HasSynced, c.pods.HasSynced}
+	if c.volumeAttachments != nil {
+		synced = append(synced, c.volumeAttachments.HasSynced)
+	}
+	return synced
+}
+
+// Node returns a copy of the cached node with the given name, and false if the node is not found or the cache has not synced
+func (c *Cache) Node(name string) (*corev1.Node, bool) {
+	if !c.Synced() {
+		return nil, false
+	}
+	obj, exists, err := c.nodes.GetIndexer().GetByKey(name)
+	if err != nil || !exists {
+		return nil, false
+	}
+	return obj.(*corev1.Node).DeepCopy(), true
+}
+
+// Nodes returns the cached nodes, which are shared with the informer and must not be modified, and false if the cache has not synced
+func (c *Cache) Nodes() ([]*corev1.Node, bool) {
+	if !c.Synced() {
+		return nil, false
+	}
+	objs := c.nodes.GetIndexer().List()
+	nodes := make([]*corev1.Node, 0, len(objs))
+	for _, obj := range objs {
+		nodes = append(nodes, obj.(*corev1.Node))
+	}
+	return nodes, true
+}
+
+// Pods returns the cached pods of the node, which are shared with the informer and must not be modified, and false if the cache has not synced
+func (c *Cache) Pods(nodeName string) ([]*corev1.Pod, bool) {
+	if !c.Synced() {
+		return nil, false
+	}
+	objs, err := c.pods.GetIndexer().ByIndex(nodeNameIndex, nodeName)
+	if err != nil {
+		return nil, false
+	}
+	pods := make([]*corev1.Pod, 0, len(objs))
+	for _, obj := range objs {
+		pods = append(pods, obj.(*corev1.Pod))
+	}
+	return pods, true
+}
+
+// nodesByIndex returns copies of the cached nodes with any of the given index values
+func (c *Cache) nodesByIndex(index string, values ...string) ([]*corev1.Node, error) {
+	var nodes []*corev1.Node
+	found := make(map[string]bool)
+	for _, value := range values {
+		objs, err := c.nodes.GetIndexer().ByIndex(index, value)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			node := obj.(*corev1.Node)
+			if !found[node.Name] {
+				found[node.Name] = true
+				nodes = append(nodes, node.DeepCopy())
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// instanceIDs returns the instance IDs of the provider IDs of the cached nodes
+func (c *Cache) instanceIDs() []string {
+	return c.nodes.GetIndexer().ListIndexFuncValues(providerIDIndex)
+}
+
+// podsOnNode returns copies of the cached pods of the node
+func (c *Cache) podsOnNode(nodeName string) (*corev1.PodList, error) {
+	objs, err := c.pods.GetIndexer().ByIndex(nodeNameIndex, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	pods := &corev1.PodList{Items: make([]corev1.Pod, 0, len(objs))}
+	for _, obj := range objs {
+		pods.Items = append(pods.Items, *obj.(*corev1.Pod).DeepCopy())
+	}
+	return pods, nil
+}
+
+// volumeAttachmentsOnNode returns the cached volume attachments of the node, which are shared with the informer and must not be modified,
+// and false if volume attachments are not cached
+func (c *Cache) volumeAttachmentsOnNode(nodeName string) ([]*storagev1.VolumeAttachment, bool) {
+	if !c.Synced() || c.volumeAttachments == nil {
+		return nil, false
+	}
+	objs, err := c.volumeAttachments.GetIndexer().ByIndex(nodeNameIndex, nodeName)
+	if err != nil {
+		return nil, false
+	}
+	attachments := make([]*storagev1.VolumeAttachment, 0, len(objs))
+	for _, obj := range objs {
+		attachments = append(attachments, obj.(*storagev1.VolumeAttachment))
+	}
+	return attachments, true
+}
+
+// stripManagedFields drops the managed fields of the cached objects, which NTH does not read, to save memory in large clusters
+func stripManagedFields(obj interface{}) (interface{}, error) {
+	if accessor, err := meta.Accessor(obj); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	return obj, nil
+}
+
+// nonEmpty returns the value as the only index value, or no index value if it is empty
+func nonEmpty(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
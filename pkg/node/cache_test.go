@@ -0,0 +1,120 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func getCachedTestNode(t *testing.T, client *fake.Clientset, nthConfig config.Config) *Node {
+	nthConfig.EnableSQSTerminationDraining = true
+	nthConfig.EnableInformerCache = true
+	tNode, err := NewWithValues(nthConfig, getTestDrainHelper(client), nil)
+	h.Ok(t, err)
+	tNode.cache, err = NewCache(nthConfig, client)
+	h.Ok(t, err)
+	tNode.resolver.cache = tNode.cache
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	h.Ok(t, tNode.cache.Start(ctx, 10*time.Second))
+	return tNode
+}
+
+func TestCacheServesNodes(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-1", Labels: map[string]string{hostnameLabelKey: "ip-10-0-0-1"}},
+			Spec:       v1.NodeSpec{ProviderID: "aws:///us-west-2a/i-1234"},
+		},
+		&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-2"},
+			Spec:       v1.NodeSpec{ProviderID: "aws:///us-west-2a/i-5678"},
+		},
+	)
+	tNode := getCachedTestNode(t, client, config.Config{})
+	client.ClearActions()
+
+	k8sNode, err := tNode.fetchKubernetesNode("ip-10-0-0-1.us-west-2.compute.internal")
+	h.Ok(t, err)
+	h.Equals(t, "worker-1", k8sNode.Name)
+
+	k8sNode, err = tNode.fetchKubernetesNode("worker-2")
+	h.Ok(t, err)
+	h.Equals(t, "worker-2", k8sNode.Name)
+
+	instanceIDs, err := tNode.FetchKubernetesNodeInstanceIds()
+	h.Ok(t, err)
+	h.Assert(t, len(instanceIDs) == 2, "Failed to return the instance IDs of the cached nodes")
+
+	nodeName, err := tNode.NodeResolver().Resolve(context.Background(), Instance{InstanceID: "i-5678"})
+	h.Ok(t, err)
+	h.Equals(t, "worker-2", nodeName)
+
+	h.Equals(t, 0, len(client.Actions()))
+}
+
+func TestCacheServesPods(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default"}, Spec: v1.PodSpec{NodeName: nodeName}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-2", Namespace: "default"}, Spec: v1.PodSpec{NodeName: "other"}},
+	)
+	tNode := getCachedTestNode(t, client, config.Config{})
+	client.ClearActions()
+
+	pods, err := tNode.fetchAllPods(nodeName)
+	h.Ok(t, err)
+	h.Equals(t, 1, len(pods.Items))
+	h.Equals(t, "pod-1", pods.Items[0].Name)
+	h.Equals(t, 0, len(client.Actions()))
+}
+
+func TestCacheFallsBackToAPIServerUntilSynced(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}})
+	nthConfig := config.Config{EnableSQSTerminationDraining: true, EnableInformerCache: true}
+	tNode, err := NewWithValues(nthConfig, getTestDrainHelper(client), nil)
+	h.Ok(t, err)
+	tNode.cache, err = NewCache(nthConfig, client)
+	h.Ok(t, err)
+	h.Assert(t, !tNode.cache.Synced(), "Failed to report a cache which was not started as not synced")
+
+	k8sNode, err := tNode.fetchKubernetesNode(nodeName)
+	h.Ok(t, err)
+	h.Equals(t, nodeName, k8sNode.Name)
+	h.Assert(t, len(client.Actions()) > 0, "Failed to look up the node with the API server")
+}
+
+func TestCacheServesVolumeAttachments(t *testing.T) {
+	other := volumeAttachment("other-attachment", "other-pv")
+	other.Spec.NodeName = "other"
+	client := fake.NewSimpleClientset(
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}},
+		volumeAttachment("db-attachment", "db-pv"),
+		other,
+	)
+	tNode := getCachedTestNode(t, client, config.Config{WaitForVolumeDetachment: true, VolumeDetachmentCheckNodeStatus: true})
+	client.ClearActions()
+
+	attached, err := tNode.attachedVolumes(context.Background(), nodeName, map[string]bool{})
+	h.Ok(t, err)
+	h.Equals(t, []string{"db-attachment"}, attached)
+	h.Equals(t, 0, len(client.Actions()))
+}
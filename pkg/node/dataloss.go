@@ -0,0 +1,132 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"maps"
+	"strings"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// dataLossDescriptions describe what the drain of a pod of each kind loses, for the events emitted for the pods
+var dataLossDescriptions = map[string]string{
+	config.DataLossOrphan:   "the pod, which has no controller to recreate it",
+	config.DataLossEmptyDir: "the data of its emptyDir volumes",
+	config.DataLossLocalPV:  "access to its local persistent volumes, which stay on the node",
+}
+
+// dataLossPolicy decides whether the pods whose drain loses them or their data are evicted, kept on the node or held
+type dataLossPolicy struct {
+	defaults map[string]string
+	rules    []config.DataLossPolicyRule
+}
+
+// newDataLossPolicy returns the data loss policy set by the NTH config
+func newDataLossPolicy(nthConfig config.Config) dataLossPolicy {
+	defaults := map[string]string{
+		config.DataLossOrphan:   config.DataLossForce,
+		config.DataLossEmptyDir: config.DataLossForce,
+		config.DataLossLocalPV:  config.DataLossForce,
+	}
+	if !nthConfig.DeleteLocalData {
+		defaults[config.DataLossEmptyDir] = config.DataLossSkip
+	}
+	// the policy and its overrides are validated when the config is parsed
+	actions, _ := config.ParseDataLossPolicy(nthConfig.PodDataLossPolicy)
+	maps.Copy(defaults, actions)
+	rules, _ := config.ParseDataLossPolicyOverrides(nthConfig.PodDataLossPolicyOverrides)
+	return dataLossPolicy{defaults: defaults, rules: rules}
+}
+
+// action returns what happens to a pod whose drain causes the kinds of loss:
+// it is skipped if any kind is skipped, held if any kind is held, and evicted otherwise
+func (p dataLossPolicy) action(pod corev1.Pod, kinds []string) string {
+	action := config.DataLossForce
+	for _, kind := range kinds {
+		switch p.kindAction(pod, kind) {
+		case config.DataLossSkip:
+			return config.DataLossSkip
+		case config.DataLossHold:
+			action = config.DataLossHold
+		}
+	}
+	return action
+}
+
+// kindAction returns the action of the first override which matches the pod and sets the kind, or the default action of the kind
+func (p dataLossPolicy) kindAction(pod corev1.Pod, kind string) string {
+	for _, rule := range p.rules {
+		if action, ok := rule.Actions[kind]; ok && rule.Matches(pod.Namespace, pod.Labels) {
+			return action
+		}
+	}
+	return p.defaults[kind]
+}
+
+// dataLossKinds returns the kinds of loss the drain of the pod causes.
+// A persistent volume which cannot be looked up is assumed not to be local.
+func dataLossKinds(ctx context.Context, client kubernetes.Interface, pod corev1.Pod) []string {
+	var kinds []string
+	if metav1.GetControllerOf(&pod) == nil {
+		kinds = append(kinds, config.DataLossOrphan)
+	}
+	var emptyDir, localPV bool
+	for _, volume := range pod.Spec.Volumes {
+		switch {
+		case volume.EmptyDir != nil:
+			emptyDir = true
+		case volume.PersistentVolumeClaim != nil && !localPV:
+			local, err := isLocalVolumeClaim(ctx, client, pod.Namespace, volume.PersistentVolumeClaim.ClaimName)
+			if err != nil {
+				log.Warn().Err(err).Str("pod_name", pod.Name).Str("pod_namespace", pod.Namespace).Msg("Unable to check whether the persistent volume of the pod is local")
+			}
+			localPV = local
+		}
+	}
+	if emptyDir {
+		kinds = append(kinds, config.DataLossEmptyDir)
+	}
+	if localPV {
+		kinds = append(kinds, config.DataLossLocalPV)
+	}
+	return kinds
+}
+
+// isLocalVolumeClaim returns true if the claim is bound to a persistent volume on the node, a local or a hostPath volume
+func isLocalVolumeClaim(ctx context.Context, client kubernetes.Interface, namespace, claimName string) (bool, error) {
+	claim, err := client.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, claimName, metav1.GetOptions{})
+	if err != nil || claim.Spec.VolumeName == "" {
+		return false, err
+	}
+	volume, err := client.CoreV1().PersistentVolumes().Get(ctx, claim.Spec.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	return volume.Spec.Local != nil || volume.Spec.HostPath != nil, nil
+}
+
+// describeDataLoss describes what the drain of a pod causing the kinds of loss loses
+func describeDataLoss(kinds []string) string {
+	descriptions := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		descriptions = append(descriptions, dataLossDescriptions[kind])
+	}
+	return strings.Join(descriptions, ", ")
+}
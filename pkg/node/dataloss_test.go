@@ -0,0 +1,109 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDataLossPolicyAction(t *testing.T) {
+	policy := newDataLossPolicy(config.Config{
+		DeleteLocalData:            false,
+		PodDataLossPolicy:          "localpv=hold",
+		PodDataLossPolicyOverrides: "namespace/debug:orphan=skip;selector/scratch=true:emptydir=force",
+	})
+	pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default"}}
+	h.Equals(t, config.DataLossForce, policy.action(pod, nil))
+	h.Equals(t, config.DataLossForce, policy.action(pod, []string{config.DataLossOrphan}))
+	// emptyDir pods are kept on the node without delete-local-data
+	h.Equals(t, config.DataLossSkip, policy.action(pod, []string{config.DataLossEmptyDir, config.DataLossLocalPV}))
+	h.Equals(t, config.DataLossHold, policy.action(pod, []string{config.DataLossOrphan, config.DataLossLocalPV}))
+
+	debugPod := v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "debug"}}
+	h.Equals(t, config.DataLossSkip, policy.action(debugPod, []string{config.DataLossOrphan}))
+	scratchPod := v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Labels: map[string]string{"scratch": "true"}}}
+	h.Equals(t, config.DataLossForce, policy.action(scratchPod, []string{config.DataLossEmptyDir}))
+}
+
+func TestDataLossKinds(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "local", Namespace: "default"}, Spec: v1.PersistentVolumeClaimSpec{VolumeName: "local-pv"}},
+		&v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "local-pv"}, Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{Local: &v1.LocalVolumeSource{Path: "/mnt/disk"}}}},
+		&v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "ebs", Namespace: "default"}, Spec: v1.PersistentVolumeClaimSpec{VolumeName: "ebs-pv"}},
+		&v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "ebs-pv"}, Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{CSI: &v1.CSIPersistentVolumeSource{Driver: "ebs.csi.aws.com"}}}},
+	)
+	claim := func(name string) v1.Volume {
+		return v1.Volume{Name: name, VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: name}}}
+	}
+	pod := *annotatedPod("db", nil)
+	h.Equals(t, []string(nil), dataLossKinds(context.Background(), client, pod))
+
+	pod.Spec.Volumes = []v1.Volume{claim("ebs"), {Name: "scratch", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}}
+	h.Equals(t, []string{config.DataLossEmptyDir}, dataLossKinds(context.Background(), client, pod))
+
+	pod.OwnerReferences = nil
+	pod.Spec.Volumes = append(pod.Spec.Volumes, claim("local"), claim("missing"))
+	h.Equals(t, []string{config.DataLossOrphan, config.DataLossEmptyDir, config.DataLossLocalPV}, dataLossKinds(context.Background(), client, pod))
+}
+
+func TestPodsToEvictDataLoss(t *testing.T) {
+	orphan := annotatedPod("debug", nil)
+	orphan.OwnerReferences = nil
+	scratch := annotatedPod("scratch", nil)
+	scratch.Spec.Volumes = []v1.Volume{{Name: "scratch", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}}
+	client := fake.NewSimpleClientset(orphan, scratch, annotatedPod("web", nil))
+	drainHelper := getTestDrainHelper(client)
+	tNode, err := NewWithValues(config.Config{NodeName: nodeName, UseAPIServerCacheToListPods: true, DeleteLocalData: true, PodDataLossPolicy: "orphan=skip,emptydir=hold"}, drainHelper, nil)
+	h.Ok(t, err)
+
+	evictions, skipped, err := tNode.podsToEvict(drainHelper, nodeName, nil, false, nodeName, nil)
+	h.Ok(t, err)
+	h.Equals(t, 1, len(skipped))
+	h.Equals(t, "debug", skipped[0].Name)
+	h.Equals(t, []string{config.DataLossOrphan}, skipped[0].DataLoss)
+	held := map[string]bool{}
+	for _, eviction := range evictions {
+		held[eviction.pod.Name] = eviction.hold
+	}
+	h.Equals(t, map[string]bool{"scratch": true, "web": false}, held)
+}
+
+func TestEvictPodsHeldUntilReleased(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "pods", evictionReactor(client, nil, nil))
+	evictions := createPods(t, client, "held", "never-released")
+	evictions[0].hold = true
+	evictions[1].hold = true
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		patch := `{"metadata":{"annotations":{"` + DrainHoldAnnotationKey + `":"` + DrainHoldReleased + `"}}}`
+		_, err := client.CoreV1().Pods("default").Patch(context.Background(), "held", types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+		h.Ok(t, err)
+	}()
+
+	results := evictor{client: client, maxParallelism: 10, timeout: 500 * time.Millisecond}.evictPods(context.Background(), evictions)
+
+	h.Equals(t, PodEvicted, results[0].Outcome)
+	h.Equals(t, PodTimedOut, results[1].Outcome)
+	h.Equals(t, 0, results[1].Attempts)
+}
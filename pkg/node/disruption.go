@@ -0,0 +1,122 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+const (
+	// DisruptionAnnotationKey is a pod annotation whose JSON value describes the interruption which is about to drain the node of the pod
+	DisruptionAnnotationKey = "aws-node-termination-handler/disruption"
+	// DisruptionTargetReason is the reason of the DisruptionTarget condition set on the pods of a node about to be drained
+	DisruptionTargetReason = "TerminationByNodeTerminationHandler"
+)
+
+// DisruptionNotice describes the interruption the pods of a node are notified of before they are evicted
+type DisruptionNotice struct {
+	Kind    string `json:"kind"`
+	EventID string `json:"eventId"`
+	// Deadline is the time the pods are evicted by, if the interruption has one
+	Deadline *metav1.Time `json:"deadline,omitempty"`
+}
+
+// NotifyPodsOfDisruption sets the DisruptionTarget condition and, if configured, the disruption annotation on the pods of the node
+// which are still running, so that they can start handing off their work before they are evicted.
+// Pods which skip their eviction whatever the deadline are left alone.
+func (n Node) NotifyPodsOfDisruption(ctx context.Context, nodeName string, notice DisruptionNotice) error {
+	if !n.nthConfig.PodDisruptionCondition && !n.nthConfig.PodDisruptionAnnotation {
+		return nil
+	}
+	if n.nthConfig.DryRun {
+		log.Info().Str("node_name", nodeName).Msg("Pods would have been notified of the disruption, but dry-run flag was set")
+		return nil
+	}
+	node, err := n.fetchKubernetesNode(nodeName)
+	if err != nil {
+		return err
+	}
+	pods, err := n.fetchAllPods(node.Name)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed || pod.Annotations[SkipEvictionAnnotationKey] == SkipEvictionAlways {
+			continue
+		}
+		if err := n.notifyPod(ctx, pod, notice); err != nil {
+			errs = append(errs, fmt.Errorf("notify pod %s/%s of the disruption: %w", pod.Namespace, pod.Name, err))
+		}
+	}
+	log.Info().Str("node_name", nodeName).Int("pods", len(pods.Items)).Int("failed", len(errs)).Msg("Notified the pods of the node of the disruption")
+	return utilerrors.NewAggregate(errs)
+}
+
+// notifyPod patches the disruption annotation and the DisruptionTarget condition of the pod, which is left alone if it is already set
+func (n Node) notifyPod(ctx context.Context, pod corev1.Pod, notice DisruptionNotice) error {
+	client := n.drainHelper.Client.CoreV1().Pods(pod.Namespace)
+	if n.nthConfig.PodDisruptionAnnotation {
+		value, err := json.Marshal(notice)
+		if err != nil {
+			return err
+		}
+		patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": map[string]string{DisruptionAnnotationKey: string(value)}}})
+		if err != nil {
+			return err
+		}
+		if _, err := client.Patch(ctx, pod.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return err
+		}
+	}
+	if !n.nthConfig.PodDisruptionCondition || hasDisruptionTarget(pod) {
+		return nil
+	}
+	message := fmt.Sprintf("The node of the pod is drained for a %s interruption event", notice.Kind)
+	if notice.Deadline != nil {
+		message += fmt.Sprintf(" before %s", notice.Deadline.UTC().Format(time.RFC3339))
+	}
+	// the conditions of a pod are merged by type
+	patch, err := json.Marshal(map[string]interface{}{"status": map[string]interface{}{"conditions": []corev1.PodCondition{{
+		Type:               corev1.DisruptionTarget,
+		Status:             corev1.ConditionTrue,
+		Reason:             DisruptionTargetReason,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	}}}})
+	if err != nil {
+		return err
+	}
+	_, err = client.Patch(ctx, pod.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}, "status")
+	return err
+}
+
+// hasDisruptionTarget returns true if the DisruptionTarget condition of the pod is true
+func hasDisruptionTarget(pod corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.DisruptionTarget {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNotifyPodsOfDisruption(t *testing.T) {
+	completed := annotatedPod("completed", nil)
+	completed.Status.Phase = v1.PodSucceeded
+	client := fake.NewSimpleClientset(
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}},
+		annotatedPod("web", nil),
+		annotatedPod("pinned", map[string]string{SkipEvictionAnnotationKey: SkipEvictionAlways}),
+		completed,
+	)
+	tNode, err := NewWithValues(config.Config{PodDisruptionCondition: true, PodDisruptionAnnotation: true}, getTestDrainHelper(client), nil)
+	h.Ok(t, err)
+	deadline := metav1.NewTime(time.Now().Add(2 * time.Minute).Truncate(time.Second))
+
+	err = tNode.NotifyPodsOfDisruption(context.Background(), nodeName, DisruptionNotice{Kind: "SPOT_ITN", EventID: "spot-itn-1", Deadline: &deadline})
+	h.Ok(t, err)
+
+	web, err := client.CoreV1().Pods("default").Get(context.Background(), "web", metav1.GetOptions{})
+	h.Ok(t, err)
+	h.Equals(t, true, hasDisruptionTarget(*web))
+	var notice DisruptionNotice
+	h.Ok(t, json.Unmarshal([]byte(web.Annotations[DisruptionAnnotationKey]), &notice))
+	h.Equals(t, "SPOT_ITN", notice.Kind)
+	h.Equals(t, "spot-itn-1", notice.EventID)
+	h.Assert(t, notice.Deadline.Equal(&deadline), "Expected the deadline %v to be annotated, got %v", deadline, notice.Deadline)
+
+	for _, name := range []string{"pinned", "completed"} {
+		pod, err := client.CoreV1().Pods("default").Get(context.Background(), name, metav1.GetOptions{})
+		h.Ok(t, err)
+		h.Equals(t, false, hasDisruptionTarget(*pod))
+		h.Equals(t, "", pod.Annotations[DisruptionAnnotationKey])
+	}
+}
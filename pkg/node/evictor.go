@@ -0,0 +1,324 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// EvictionOutcome is what happened to a pod when its node was drained
+type EvictionOutcome string
+
+const (
+	// PodEvicted means the pod was evicted through the eviction API and terminated
+	PodEvicted EvictionOutcome = "Evicted"
+	// PodDeleted means the pod was deleted, since evictions are disabled, the pod asked for it or a PodDisruptionBudget was overridden, and terminated
+	PodDeleted EvictionOutcome = "Deleted"
+	// PodSkipped means the pod was left on the node, e.g. a DaemonSet pod
+	PodSkipped EvictionOutcome = "Skipped"
+	// PodBlockedByPDB means evictions of the pod kept being refused, e.g. by a PodDisruptionBudget, until the drain timed out
+	PodBlockedByPDB EvictionOutcome = "BlockedByPDB"
+	// PodTimedOut means the pod was evicted or deleted but did not terminate before the drain timed out
+	PodTimedOut EvictionOutcome = "TimedOut"
+	// PodTerminated means the pod was gone before it was evicted
+	PodTerminated EvictionOutcome = "Terminated"
+	// PodFailed means evicting the pod failed with an error which is not retried
+	PodFailed EvictionOutcome = "Failed"
+)
+
+var (
+	evictionRetryInitialBackoff time.Duration = time.Second
+	evictionRetryMaxBackoff     time.Duration = 10 * time.Second
+	evictionPollInterval        time.Duration = time.Second
+)
+
+// PodEvictionResult is what happened to a pod when its node was drained
+type PodEvictionResult struct {
+	Namespace string          `json:"namespace"`
+	Name      string          `json:"name"`
+	Outcome   EvictionOutcome `json:"outcome"`
+	// ControllerKind and ControllerName identify the controller of the pod, if it has one
+	ControllerKind string `json:"controllerKind,omitempty"`
+	ControllerName string `json:"controllerName,omitempty"`
+	// Attempts is the number of eviction or deletion requests sent for the pod
+	Attempts           int   `json:"attempts"`
+	GracePeriodSeconds int64 `json:"gracePeriodSeconds"`
+	// Duration is the time from the first request until the pod terminated or the drain gave up on it
+	Duration time.Duration `json:"duration"`
+	// Message is the reason a pod was skipped or the error which ended its eviction
+	Message string `json:"message,omitempty"`
+	// PDBOverridden is true if the pod was deleted since a PodDisruptionBudget kept refusing its eviction past the escalation time
+	PDBOverridden bool `json:"pdbOverridden,omitempty"`
+	// DataLoss lists the kinds of loss the drain of the pod causes, see the data loss policy
+	DataLoss []string `json:"dataLoss,omitempty"`
+	err      error
+}
+
+// Succeeded returns true if the pod is off the node or was meant to stay on it
+func (r PodEvictionResult) Succeeded() bool {
+	switch r.Outcome {
+	case PodEvicted, PodDeleted, PodTerminated, PodSkipped:
+		return true
+	}
+	return false
+}
+
+// Err returns the error which ended the eviction of the pod, or nil if it succeeded
+func (r PodEvictionResult) Err() error {
+	if r.Succeeded() {
+		return nil
+	}
+	return r.err
+}
+
+// podEviction is a pod to evict, the grace period it is given and the drain policy set by its annotations
+type podEviction struct {
+	pod         corev1.Pod
+	gracePeriod int64
+	policy      podPolicy
+	// dataLoss lists the kinds of loss the drain of the pod causes
+	dataLoss []string
+	// hold is true if the pod is evicted once a hook releases it with the drain-hold annotation
+	hold bool
+}
+
+// evictor evicts, or deletes if evictions are disabled or a pod asks for it, pods with a bounded number of requests in flight,
+// and waits for them to terminate
+type evictor struct {
+	client          kubernetes.Interface
+	disableEviction bool
+	maxParallelism  int
+	// timeout bounds the evictions, 0 means no timeout
+	timeout time.Duration
+	// overridePDBAt is the time after which a pod whose eviction a PodDisruptionBudget refuses is deleted instead,
+	// the zero time means PodDisruptionBudgets are never overridden
+	overridePDBAt time.Time
+	// onPDBOverride is called before a pod is deleted despite its PodDisruptionBudget
+	onPDBOverride func(pod corev1.Pod, gracePeriod int64, refusal error)
+}
+
+// evictPods evicts the pods and returns the result of each, in the order of the pods.
+// A slot is held while the requests for a pod are sent, including retries, but not while the pod terminates,
+// so that a pod with a long grace period does not hold back the eviction of the others.
+func (e evictor) evictPods(ctx context.Context, evictions []podEviction) []PodEvictionResult {
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+	maxParallelism := e.maxParallelism
+	if maxParallelism < 1 {
+		maxParallelism = len(evictions)
+	}
+	slots := make(chan struct{}, maxParallelism)
+	results := make([]PodEvictionResult, len(evictions))
+	var wg sync.WaitGroup
+	for i, eviction := range evictions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = e.evictPod(ctx, eviction, slots)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func (e evictor) evictPod(ctx context.Context, eviction podEviction, slots chan struct{}) PodEvictionResult {
+	pod := eviction.pod
+	start := time.Now()
+	result := PodEvictionResult{
+		Namespace:          pod.Namespace,
+		Name:               pod.Name,
+		GracePeriodSeconds: eviction.gracePeriod,
+		DataLoss:           eviction.dataLoss,
+	}
+	if controller := metav1.GetControllerOf(&pod); controller != nil {
+		result.ControllerKind, result.ControllerName = controller.Kind, controller.Name
+	}
+	finish := func(outcome EvictionOutcome, err error) PodEvictionResult {
+		result.Outcome = outcome
+		result.Duration = time.Since(start)
+		if err != nil {
+			result.err = err
+			result.Message = err.Error()
+		}
+		return result
+	}
+
+	if eviction.hold {
+		// no slot is held while waiting for the hook
+		err := e.awaitHoldRelease(ctx, pod)
+		switch {
+		case apierrors.IsNotFound(err):
+			return finish(PodTerminated, nil)
+		case err != nil && ctx.Err() != nil:
+			return finish(PodTimedOut, fmt.Errorf("drain hold of pod %s/%s was not released before the drain timed out: %w", pod.Namespace, pod.Name, err))
+		case err != nil:
+			return finish(PodFailed, fmt.Errorf("drain hold of pod %s/%s: %w", pod.Namespace, pod.Name, err))
+		}
+	}
+
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return finish(PodTimedOut, fmt.Errorf("pod %s/%s was not evicted before the drain timed out: %w", pod.Namespace, pod.Name, ctx.Err()))
+	}
+	err := e.request(ctx, eviction, &result)
+	<-slots
+	switch {
+	case apierrors.IsNotFound(err):
+		return finish(PodTerminated, nil)
+	case apierrors.IsTooManyRequests(err):
+		return finish(PodBlockedByPDB, err)
+	case err != nil && ctx.Err() != nil:
+		return finish(PodTimedOut, err)
+	case err != nil:
+		return finish(PodFailed, err)
+	}
+
+	if err := e.waitForTermination(ctx, pod.Namespace, pod.Name, pod.UID); err != nil {
+		return finish(PodTimedOut, fmt.Errorf("pod %s/%s did not terminate before the drain timed out: %w", pod.Namespace, pod.Name, err))
+	}
+	if e.disableEviction || eviction.policy.deleteInsteadOfEvict || result.PDBOverridden {
+		return finish(PodDeleted, nil)
+	}
+	return finish(PodEvicted, nil)
+}
+
+// request evicts the pod, or deletes it if evictions are disabled or the pod asks for it, retrying with backoff while the eviction is refused with a 429,
+// which the API server returns when a PodDisruptionBudget does not allow the eviction or when it throttles requests.
+// Once the PodDisruptionBudget override time passes, a pod whose eviction a PodDisruptionBudget refuses is deleted instead.
+// The last refusal is returned once ctx is done.
+func (e evictor) request(ctx context.Context, eviction podEviction, result *PodEvictionResult) error {
+	pod := eviction.pod
+	deleteOptions := metav1.DeleteOptions{GracePeriodSeconds: &eviction.gracePeriod}
+	backoff := evictionRetryInitialBackoff
+	deleting := e.disableEviction || eviction.policy.deleteInsteadOfEvict
+	for {
+		result.Attempts++
+		var err error
+		if deleting {
+			err = e.client.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, deleteOptions)
+		} else {
+			err = e.client.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+				ObjectMeta:    metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+				DeleteOptions: &deleteOptions,
+			})
+		}
+		if !apierrors.IsTooManyRequests(err) {
+			return err
+		}
+		if delay, ok := apierrors.SuggestsClientDelay(err); ok && time.Duration(delay)*time.Second > backoff {
+			backoff = time.Duration(delay) * time.Second
+		}
+		wait := backoff
+		if !e.overridePDBAt.IsZero() && isPDBRefusal(err) {
+			if untilOverride := time.Until(e.overridePDBAt); untilOverride <= 0 {
+				result.PDBOverridden = true
+				deleting = true
+				if e.onPDBOverride != nil {
+					e.onPDBOverride(pod, eviction.gracePeriod, err)
+				}
+				continue
+			} else if untilOverride < wait {
+				wait = untilOverride
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, evictionRetryMaxBackoff)
+	}
+}
+
+// awaitHoldRelease sets the drain-hold annotation of the pod to requested, unless a hook released it already,
+// and polls the pod until a hook sets the annotation to released
+func (e evictor) awaitHoldRelease(ctx context.Context, pod corev1.Pod) error {
+	if pod.Annotations[DrainHoldAnnotationKey] == DrainHoldReleased {
+		return nil
+	}
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, DrainHoldAnnotationKey, DrainHoldRequested)
+	if _, err := e.client.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		return err
+	}
+	log.Info().Str("pod_name", pod.Name).Str("pod_namespace", pod.Namespace).Msgf("Holding the eviction of the pod until a hook sets its %s annotation to %s", DrainHoldAnnotationKey, DrainHoldReleased)
+	for {
+		current, err := e.client.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			return err
+		case err == nil && current.UID != pod.UID:
+			// the pod was replaced by a pod of the same name
+			return apierrors.NewNotFound(corev1.Resource("pods"), pod.Name)
+		case err == nil && current.Annotations[DrainHoldAnnotationKey] == DrainHoldReleased:
+			return nil
+		case err != nil && ctx.Err() == nil:
+			log.Warn().Err(err).Str("pod_name", pod.Name).Str("pod_namespace", pod.Namespace).Msg("Unable to check the drain hold of the pod")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(evictionPollInterval):
+		}
+	}
+}
+
+// waitForTermination polls the pod until it is gone or replaced by a pod of the same name
+func (e evictor) waitForTermination(ctx context.Context, namespace, name string, uid types.UID) error {
+	for {
+		pod, err := e.client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) || (err == nil && pod.UID != uid) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(evictionPollInterval):
+		}
+	}
+}
+
+// isPDBRefusal returns true if the error is the refusal of an eviction by a PodDisruptionBudget
+func isPDBRefusal(err error) bool {
+	var status apierrors.APIStatus
+	if !apierrors.IsTooManyRequests(err) || !errors.As(err, &status) {
+		return false
+	}
+	if details := status.Status().Details; details != nil {
+		for _, cause := range details.Causes {
+			if cause.Type == policyv1.DisruptionBudgetCause {
+				return true
+			}
+		}
+	}
+	// older API servers do not report the cause
+	return strings.Contains(status.Status().Message, "disruption budget")
+}
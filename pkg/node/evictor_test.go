@@ -0,0 +1,179 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func init() {
+	evictionRetryInitialBackoff = 10 * time.Millisecond
+	evictionRetryMaxBackoff = 20 * time.Millisecond
+	evictionPollInterval = 10 * time.Millisecond
+}
+
+// evictionReactor handles evictions with the fake clientset, which does not delete evicted pods.
+// Evictions of the pods in blocked are refused as if a PodDisruptionBudget did not allow them.
+func evictionReactor(client *fake.Clientset, blocked map[string]bool, onEvict func(name string)) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		if onEvict != nil {
+			onEvict(eviction.Name)
+		}
+		if blocked[eviction.Name] {
+			return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+		err := client.Tracker().Delete(v1.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+		return true, nil, err
+	}
+}
+
+func createPods(t *testing.T, client *fake.Clientset, names ...string) []podEviction {
+	var evictions []podEviction
+	for _, name := range names {
+		pod, err := client.CoreV1().Pods("default").Create(context.Background(), &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       v1.PodSpec{NodeName: nodeName},
+		}, metav1.CreateOptions{})
+		h.Ok(t, err)
+		evictions = append(evictions, podEviction{pod: *pod, gracePeriod: 30})
+	}
+	return evictions
+}
+
+func TestEvictPodsOutcomes(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "pods", evictionReactor(client, map[string]bool{"blocked": true}, nil))
+	evictions := createPods(t, client, "evicted", "blocked")
+	evictions = append(evictions, podEviction{pod: v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "gone", Namespace: "default"}}})
+
+	results := evictor{client: client, maxParallelism: 10, timeout: 200 * time.Millisecond}.evictPods(context.Background(), evictions)
+
+	h.Equals(t, 3, len(results))
+	h.Equals(t, "evicted", results[0].Name)
+	h.Equals(t, PodEvicted, results[0].Outcome)
+	h.Equals(t, 1, results[0].Attempts)
+	h.Equals(t, int64(30), results[0].GracePeriodSeconds)
+	h.Ok(t, results[0].Err())
+
+	h.Equals(t, PodBlockedByPDB, results[1].Outcome)
+	h.Assert(t, results[1].Attempts > 1, "Expected the blocked eviction to be retried")
+	h.Assert(t, apierrors.IsTooManyRequests(results[1].Err()), "Expected the blocked eviction to return the 429")
+	h.Assert(t, !results[1].Succeeded(), "Expected the blocked eviction not to succeed")
+
+	h.Equals(t, PodTerminated, results[2].Outcome)
+	h.Assert(t, results[2].Succeeded(), "Expected the eviction of a pod which is gone to succeed")
+}
+
+func TestEvictPodsOverridesPDB(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "pods", evictionReactor(client, map[string]bool{"blocked": true}, nil))
+	evictions := createPods(t, client, "blocked")
+	var overridden []string
+	podEvictor := evictor{
+		client:         client,
+		maxParallelism: 10,
+		timeout:        time.Second,
+		overridePDBAt:  time.Now().Add(50 * time.Millisecond),
+		onPDBOverride: func(pod v1.Pod, gracePeriod int64, refusal error) {
+			h.Equals(t, int64(30), gracePeriod)
+			h.Assert(t, isPDBRefusal(refusal), "Expected the override to follow a PodDisruptionBudget refusal")
+			overridden = append(overridden, pod.Name)
+		},
+	}
+
+	results := podEvictor.evictPods(context.Background(), evictions)
+
+	h.Equals(t, PodDeleted, results[0].Outcome)
+	h.Equals(t, true, results[0].PDBOverridden)
+	h.Assert(t, results[0].Attempts > 2, "Expected the eviction to be retried before the override")
+	h.Equals(t, []string{"blocked"}, overridden)
+	_, err := client.CoreV1().Pods("default").Get(context.Background(), "blocked", metav1.GetOptions{})
+	h.Assert(t, apierrors.IsNotFound(err), "Expected the pod to be deleted")
+}
+
+func TestIsPDBRefusal(t *testing.T) {
+	throttled := apierrors.NewTooManyRequests("Too many requests, please try again later.", 1)
+	h.Equals(t, false, isPDBRefusal(throttled))
+	blocked := apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+	h.Equals(t, true, isPDBRefusal(blocked))
+	blocked.ErrStatus.Message = "eviction refused"
+	blocked.ErrStatus.Details = &metav1.StatusDetails{Causes: []metav1.StatusCause{{Type: policyv1.DisruptionBudgetCause}}}
+	h.Equals(t, true, isPDBRefusal(blocked))
+	h.Equals(t, false, isPDBRefusal(nil))
+}
+
+func TestEvictPodsDeletesWhenEvictionDisabled(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	evictions := createPods(t, client, "deleted")
+
+	results := evictor{client: client, disableEviction: true, maxParallelism: 10}.evictPods(context.Background(), evictions)
+
+	h.Equals(t, PodDeleted, results[0].Outcome)
+	_, err := client.CoreV1().Pods("default").Get(context.Background(), "deleted", metav1.GetOptions{})
+	h.Assert(t, apierrors.IsNotFound(err), "Expected the pod to be deleted")
+}
+
+func TestEvictPodsTimesOutWaitingForTermination(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	// evictions are accepted but the pod never terminates
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return action.GetSubresource() == "eviction", nil, nil
+	})
+	evictions := createPods(t, client, "stuck")
+
+	results := evictor{client: client, maxParallelism: 10, timeout: 50 * time.Millisecond}.evictPods(context.Background(), evictions)
+
+	h.Equals(t, PodTimedOut, results[0].Outcome)
+	h.Assert(t, results[0].Err() != nil, "Expected the timed out eviction to return an error")
+}
+
+func TestEvictPodsMaxParallelism(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "pods", evictionReactor(client, nil, func(string) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	evictions := createPods(t, client, "pod-1", "pod-2", "pod-3", "pod-4", "pod-5", "pod-6")
+
+	results := evictor{client: client, maxParallelism: 2}.evictPods(context.Background(), evictions)
+
+	for _, result := range results {
+		h.Equals(t, PodEvicted, result.Outcome)
+	}
+	h.Assert(t, maxInFlight <= 2, "Expected at most 2 evictions in flight")
+}
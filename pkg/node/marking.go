@@ -0,0 +1,153 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// MarkingRule returns the marking policy rule of the kind of event, or false if the kind keeps its default marks
+func (n Node) MarkingRule(kind string) (config.MarkingRule, bool) {
+	rule, ok := n.markingPolicy[kind]
+	return rule, ok
+}
+
+// Mark sets the taints, labels and annotations of the rule on the node, rendering their values with data.
+// A taint, label or annotation the node already has with the same value is left alone, the others are recorded
+// as owned by NTH so that RemoveNTHTaints and RemoveNTHLabels revert exactly what was set.
+func (n Node) Mark(nodeName string, rule config.MarkingRule, data interface{}) error {
+	taints, labels, annotations, err := n.renderMarks(rule, data)
+	if err != nil {
+		return err
+	}
+
+	k8sNode, err := n.fetchKubernetesNode(nodeName)
+	if err != nil {
+		return fmt.Errorf("unable to fetch kubernetes node from API: %w", err)
+	}
+	if n.nthConfig.DryRun {
+		log.Info().Interface("taints", taints).Interface("labels", labels).Interface("annotations", annotations).Msgf("Would have marked node %s, but dry-run flag was set", nodeName)
+		return nil
+	}
+	err = n.updateOwned(k8sNode, func(node *corev1.Node, record *ownership) bool {
+		for _, taint := range taints {
+			if addTaintToSpec(node, taint.Key, taint.Value, taint.Effect) {
+				record.Taints = append(record.Taints, taint)
+			}
+		}
+		node.Labels = setOwned(node.Labels, labels, &record.Labels)
+		node.Annotations = setOwned(node.Annotations, annotations, &record.Annotations)
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("%v node update failed when marking the node: %w", k8sNode.Name, err)
+	}
+	log.Info().Str("node_name", nodeName).Interface("taints", taints).Interface("labels", labels).Interface("annotations", annotations).Msg("Successfully marked node")
+	return nil
+}
+
+// isMarkedByRule returns true if the node carries a taint, label or annotation of the rule rendered with data, which NTH set
+func (n Node) isMarkedByRule(k8sNode *corev1.Node, rule config.MarkingRule, data interface{}) (bool, error) {
+	taints, labels, annotations, err := n.renderMarks(rule, data)
+	if err != nil {
+		return false, err
+	}
+	record := readOwnership(k8sNode)
+	for _, owned := range record.Taints {
+		if slices.ContainsFunc(taints, func(taint corev1.Taint) bool { return taint.Key == owned.Key && taint.Value == owned.Value }) && hasTaint(k8sNode, owned) {
+			return true, nil
+		}
+	}
+	for key, owned := range record.Labels {
+		if value, ok := labels[key]; ok && owned.Value == value && k8sNode.Labels[key] == value {
+			return true, nil
+		}
+	}
+	for key, owned := range record.Annotations {
+		if value, ok := annotations[key]; ok && owned.Value == value && k8sNode.Annotations[key] == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// renderMarks returns the taints, labels and annotations of the rule, with their values rendered with data
+func (n Node) renderMarks(rule config.MarkingRule, data interface{}) ([]corev1.Taint, map[string]string, map[string]string, error) {
+	taints := make([]corev1.Taint, 0, len(rule.Taints))
+	for _, taint := range rule.Taints {
+		value, err := renderLabelValue(taint.Key, taint.Value, data)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		effect := taint.Effect
+		if effect == "" {
+			effect = n.nthConfig.TaintEffect
+		}
+		taints = append(taints, corev1.Taint{Key: taint.Key, Value: value, Effect: getTaintEffect(effect)})
+	}
+	labels := map[string]string{}
+	for key, valueTemplate := range rule.Labels {
+		value, err := renderLabelValue(key, valueTemplate, data)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		labels[key] = value
+	}
+	annotations := map[string]string{}
+	for key, valueTemplate := range rule.Annotations {
+		value, err := render(key, valueTemplate, data)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		annotations[key] = value
+	}
+	return taints, labels, annotations, nil
+}
+
+// hasTaint returns true if the node carries the taint with the same key and value
+func hasTaint(k8sNode *corev1.Node, taint corev1.Taint) bool {
+	return slices.ContainsFunc(k8sNode.Spec.Taints, func(other corev1.Taint) bool { return other.Key == taint.Key && other.Value == taint.Value })
+}
+
+// render executes the template of the value of key with data
+func render(key string, valueTemplate *template.Template, data interface{}) (string, error) {
+	var value strings.Builder
+	if err := valueTemplate.Execute(&value, data); err != nil {
+		return "", fmt.Errorf("unable to render the value of %s: %w", key, err)
+	}
+	return value.String(), nil
+}
+
+// renderLabelValue renders the value of a label or taint, truncated to the maximum length of a label value
+func renderLabelValue(key string, valueTemplate *template.Template, data interface{}) (string, error) {
+	value, err := render(key, valueTemplate, data)
+	if err != nil {
+		return "", err
+	}
+	if len(value) > maxTaintValueLength {
+		value = value[:maxTaintValueLength]
+	}
+	if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+		return "", fmt.Errorf("invalid value %q of %s: %s", value, key, strings.Join(errs, ", "))
+	}
+	return value, nil
+}
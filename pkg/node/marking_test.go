@@ -0,0 +1,97 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMarkAndRevert(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        nodeName,
+			Labels:      map[string]string{"example.com/pool": "spot", "example.com/team": "data"},
+			Annotations: map[string]string{"example.com/owner": "team-a"},
+		},
+		Spec: v1.NodeSpec{Taints: []v1.Taint{{Key: "example.com/dedicated", Value: "data", Effect: v1.TaintEffectNoSchedule}}},
+	})
+	rules, err := config.ParseMarkingPolicy("STATE_CHANGE:taint/example.com/terminating={{ .EventID }}:NoExecute,taint/example.com/dedicated=other," +
+		"label/example.com/pool=draining,label/example.com/team=data,label/example.com/event={{ .Kind | lower }},annotation/example.com/owner=nth")
+	h.Ok(t, err)
+	tNode, err := NewWithValues(config.Config{TaintEffect: "NoSchedule"}, getTestDrainHelper(client), nil)
+	h.Ok(t, err)
+	err = tNode.Mark(nodeName, rules["STATE_CHANGE"], struct{ EventID, Kind string }{"event-1", "STATE_CHANGE"})
+	h.Ok(t, err)
+
+	k8sNode, err := client.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{})
+	h.Ok(t, err)
+	h.Equals(t, []v1.Taint{
+		{Key: "example.com/dedicated", Value: "data", Effect: v1.TaintEffectNoSchedule},
+		{Key: "example.com/terminating", Value: "event-1", Effect: v1.TaintEffectNoExecute},
+	}, k8sNode.Spec.Taints)
+	h.Equals(t, map[string]string{"example.com/pool": "draining", "example.com/team": "data", "example.com/event": "state_change"}, k8sNode.Labels)
+	h.Equals(t, "nth", k8sNode.Annotations["example.com/owner"])
+
+	// the taints, labels and annotations the node had before it was marked are left as they were
+	conflicts, err := tNode.revertOwnedLabels(nodeName)
+	h.Ok(t, err)
+	h.Equals(t, 0, len(conflicts))
+	err = tNode.RemoveNTHTaints(nodeName)
+	h.Ok(t, err)
+	k8sNode, err = client.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{})
+	h.Ok(t, err)
+	h.Equals(t, []v1.Taint{{Key: "example.com/dedicated", Value: "data", Effect: v1.TaintEffectNoSchedule}}, k8sNode.Spec.Taints)
+	h.Equals(t, map[string]string{"example.com/pool": "spot", "example.com/team": "data"}, k8sNode.Labels)
+	h.Equals(t, map[string]string{"example.com/owner": "team-a"}, k8sNode.Annotations)
+}
+
+func TestIsMarkedForEventByRule(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName, Labels: map[string]string{"example.com/event": "event-2"}},
+	})
+	nthConfig := config.Config{MarkingPolicy: "STATE_CHANGE:label/example.com/event={{ .EventID }}"}
+	tNode, err := NewWithValues(nthConfig, getTestDrainHelper(client), nil)
+	h.Ok(t, err)
+
+	// a label NTH did not set is not a mark
+	marked, err := tNode.IsMarkedForEvent(nodeName, "event-2", "STATE_CHANGE", struct{ EventID string }{"event-2"})
+	h.Ok(t, err)
+	h.Equals(t, false, marked)
+
+	err = tNode.Mark(nodeName, tNode.markingPolicy["STATE_CHANGE"], struct{ EventID string }{"event-1"})
+	h.Ok(t, err)
+	marked, err = tNode.IsMarkedForEvent(nodeName, "event-1", "STATE_CHANGE", struct{ EventID string }{"event-1"})
+	h.Ok(t, err)
+	h.Equals(t, true, marked)
+	marked, err = tNode.IsMarkedForEvent(nodeName, "event-3", "STATE_CHANGE", struct{ EventID string }{"event-3"})
+	h.Ok(t, err)
+	h.Equals(t, false, marked)
+}
+
+func TestMarkInvalidLabelValue(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}})
+	rules, err := config.ParseMarkingPolicy("SPOT_ITN:label/example.com/reason={{ .Description }}")
+	h.Ok(t, err)
+	tNode, err := NewWithValues(config.Config{}, getTestDrainHelper(client), nil)
+	h.Ok(t, err)
+	err = tNode.Mark(nodeName, rules["SPOT_ITN"], struct{ Description string }{"Spot ITN received!"})
+	h.Assert(t, err != nil, "Failed to return error when a label value is invalid")
+}
@@ -31,6 +31,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/kubectl/pkg/drain"
 )
@@ -44,6 +45,8 @@ const (
 	ActionLabelTimeKey = "aws-node-termination-handler/action-time"
 	// EventIDLabelKey is a k8s label key whose value is the drainable event id
 	EventIDLabelKey = "aws-node-termination-handler/event-id"
+	// BootIDAnnotationKey is a k8s annotation key whose value is the boot ID of the node when it was marked for uncordon after reboot
+	BootIDAnnotationKey = "aws-node-termination-handler/boot-id"
 	// Apply this label to enable the ServiceNodeExclusion feature gate for excluding nodes from load balancers
 	ExcludeFromLoadBalancersLabelKey = "node.kubernetes.io/exclude-from-external-load-balancers"
 	// The value associated with this label is irrelevant for enabling the feature gate
@@ -67,6 +70,7 @@ const (
 
 	maxTaintValueLength = 63
 	daemonSet           = "DaemonSet"
+	statefulSet         = "StatefulSet"
 )
 
 const (
@@ -74,6 +78,34 @@ const (
 	PodEvictReason = "PodEviction"
 	// PodEvictMsg is the event message emitted for Pod evictions during node drain
 	PodEvictMsgFmt = "Pod evicted due to node drain (node %s)"
+	// PodGraceClampedReason is the event reason emitted when the grace period of a Pod is cut to meet the interruption deadline
+	PodGraceClampedReason = "PodGracePeriodClamped"
+	// PodGraceClampedMsgFmt is the event message emitted when the grace period of a Pod is cut to meet the interruption deadline
+	PodGraceClampedMsgFmt = "Pod grace period cut from %ds to %ds to meet the interruption deadline (node %s)"
+	// PodEvictFailedReason is the event reason emitted for Pods which are still on the node after the drain
+	PodEvictFailedReason = "PodEvictionFailed"
+	// PodEvictFailedMsgFmt is the event message emitted for Pods which are still on the node after the drain
+	PodEvictFailedMsgFmt = "Pod eviction %s after %s (node %s): %s"
+	// PodPDBOverriddenReason is the event reason emitted for Pods deleted despite their PodDisruptionBudget
+	PodPDBOverriddenReason = "PodDisruptionBudgetOverridden"
+	// PodPDBOverriddenMsgFmt is the event message emitted for Pods deleted despite their PodDisruptionBudget
+	PodPDBOverriddenMsgFmt = "Eviction blocked by a PodDisruptionBudget, deleting the pod with a grace period of %ds to meet the interruption deadline (node %s)"
+	// PodAnnotationInvalidReason is the event reason emitted for Pods with an invalid drain policy annotation, which is ignored
+	PodAnnotationInvalidReason = "PodAnnotationInvalid"
+	// PodAnnotationInvalidMsgFmt is the event message emitted for Pods with an invalid drain policy annotation, which is ignored
+	PodAnnotationInvalidMsgFmt = "Ignoring %s (node %s)"
+	// PodDataLossReason is the event reason emitted for Pods whose drain loses them or their data
+	PodDataLossReason = "PodDataLoss"
+	// PodDataLossMsgFmt is the event message emitted for Pods whose drain loses them or their data
+	PodDataLossMsgFmt = "Draining the pod loses %s (node %s)"
+)
+
+const (
+	// drainDeadlineMargin is kept between the end of the pod grace periods and the interruption deadline
+	// so that the kubelet can kill the containers which outlive their grace period
+	drainDeadlineMargin = 5 * time.Second
+	// minDrainTimeout bounds the drain timeout of a node whose interruption deadline passed
+	minDrainTimeout = time.Second
 )
 
 var (
@@ -84,9 +116,13 @@ var (
 
 // Node represents a kubernetes node with functions to manipulate its state via the kubernetes api server
 type Node struct {
-	nthConfig   config.Config
-	drainHelper *drain.Helper
-	uptime      uptime.UptimeFuncType
+	nthConfig      config.Config
+	drainHelper    *drain.Helper
+	uptime         uptime.UptimeFuncType
+	dataLossPolicy dataLossPolicy
+	markingPolicy  map[string]config.MarkingRule
+	resolver       *NodeResolver
+	cache          *Cache
 }
 
 type ZerologWriter struct {
@@ -107,37 +143,76 @@ func New(nthConfig config.Config, clientset *kubernetes.Clientset) (*Node, error
 	if err != nil {
 		return nil, err
 	}
-	return NewWithValues(nthConfig, drainHelper, getUptimeFunc(nthConfig.UptimeFromFile))
+	n, err := NewWithValues(nthConfig, drainHelper, getUptimeFunc(nthConfig.UptimeFromFile))
+	if err != nil {
+		return nil, err
+	}
+	if nthConfig.EnableSQSTerminationDraining && nthConfig.EnableInformerCache {
+		n.cache, err = NewCache(nthConfig, clientset)
+		if err != nil {
+			return nil, err
+		}
+		n.resolver.cache = n.cache
+	}
+	return n, nil
 }
 
 // NewWithValues will construct a node struct with a drain helper and an uptime function
 func NewWithValues(nthConfig config.Config, drainHelper *drain.Helper, uptime uptime.UptimeFuncType) (*Node, error) {
+	markingPolicy, err := config.ParseMarkingPolicy(nthConfig.MarkingPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid marking policy: %w", err)
+	}
+	var client kubernetes.Interface
+	if drainHelper != nil {
+		client = drainHelper.Client
+	}
+	resolver, err := NewNodeResolver(nthConfig, client)
+	if err != nil {
+		return nil, err
+	}
 	return &Node{
-		nthConfig:   nthConfig,
-		drainHelper: drainHelper,
-		uptime:      uptime,
+		nthConfig:      nthConfig,
+		drainHelper:    drainHelper,
+		uptime:         uptime,
+		dataLossPolicy: newDataLossPolicy(nthConfig),
+		markingPolicy:  markingPolicy,
+		resolver:       resolver,
 	}, nil
 }
 
-// CordonAndDrain will cordon the node and evict pods based on the config
-func (n Node) CordonAndDrain(nodeName string, reason string, recorder recorderInterface) error {
+// NodeResolver returns the resolver which finds the Kubernetes node of an EC2 instance
+func (n Node) NodeResolver() *NodeResolver {
+	return n.resolver
+}
+
+// Cache returns the informer cache which serves the nodes and pods looked up in Queue Processor mode, or nil if there is none
+func (n Node) Cache() *Cache {
+	return n.cache
+}
+
+// CordonAndDrain will cordon the node and evict pods based on the config, and returns what happened to each pod
+//
+// A non-zero deadline cuts the drain timeout and the grace periods of the pods so that every pod terminates before it.
+// Evictions are aborted once ctx is done.
+func (n Node) CordonAndDrain(ctx context.Context, nodeName string, reason string, deadline time.Time, recorder recorderInterface) ([]PodEvictionResult, error) {
 	if n.nthConfig.DryRun {
 		log.Info().Str("node_name", nodeName).Str("reason", reason).Msg("Node would have been cordoned and drained, but dry-run flag was set.")
-		return nil
+		return nil, nil
 	}
 	err := n.MaybeMarkForExclusionFromLoadBalancers(nodeName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	err = n.Cordon(nodeName, reason)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// Be very careful here: in tests, nodeName and node.Name can be different, as
 	// fetchKubernetesNode does some translation using the kubernetes.io/hostname label
 	node, err := n.fetchKubernetesNode(nodeName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	var pods *corev1.PodList
 	// Delete all pods on the node
@@ -147,36 +222,230 @@ func (n Node) CordonAndDrain(nodeName string, reason string, recorder recorderIn
 		pods, err = n.fetchAllPods(node.Name)
 		if err == nil {
 			for _, pod := range pods.Items {
-				podRef := &corev1.ObjectReference{
-					Kind:      "Pod",
-					Name:      pod.Name,
-					Namespace: pod.Namespace,
-				}
-				annotations := make(map[string]string)
-				annotations["node"] = nodeName
-				for k, v := range pod.GetLabels() {
-					annotations[k] = v
-				}
-				recorder.AnnotatedEventf(podRef, annotations, corev1.EventTypeNormal, PodEvictReason, PodEvictMsgFmt, nodeName)
+				emitPodEvent(recorder, pod, nodeName, corev1.EventTypeNormal, PodEvictReason, PodEvictMsgFmt, nodeName)
 			}
+		} else {
+			pods = nil
 		}
 	}
-	if n.nthConfig.UseAPIServerCacheToListPods {
-		if pods != nil {
-			pods = n.FilterOutDaemonSetPods(pods)
-			err = n.drainHelper.DeleteOrEvictPods(pods.Items)
+	drainHelper := n.drainHelperWithContext(ctx)
+	evictions, results, err := n.podsToEvict(drainHelper, node.Name, pods, !deadline.IsZero(), nodeName, recorder)
+	if err != nil {
+		return nil, err
+	}
+	podEvictor := evictor{
+		client:          drainHelper.Client,
+		disableEviction: drainHelper.DisableEviction,
+		maxParallelism:  n.nthConfig.EvictionMaxParallelism,
+		timeout:         drainHelper.Timeout,
+	}
+	if !deadline.IsZero() && n.nthConfig.PDBEscalationThreshold < 100 {
+		podEvictor.overridePDBAt = time.Now().Add(time.Until(deadline) * time.Duration(n.nthConfig.PDBEscalationThreshold) / 100)
+		podEvictor.onPDBOverride = func(pod corev1.Pod, gracePeriod int64, refusal error) {
+			log.Warn().Err(refusal).Str("node_name", nodeName).Str("pod_name", pod.Name).Str("pod_namespace", pod.Namespace).
+				Time("deadline", deadline).Msg("Overriding the PodDisruptionBudget of the pod to meet the interruption deadline")
+			if recorder != nil {
+				emitPodEvent(recorder, pod, nodeName, corev1.EventTypeWarning, PodPDBOverriddenReason, PodPDBOverriddenMsgFmt, gracePeriod, nodeName)
+			}
 		}
+	}
+	waves := drainWaves(evictions, n.nthConfig.DrainWaveOrder)
+	results = append(results, evictWaves(ctx, podEvictor, waves, nodeName, deadline, recorder)...)
+	return results, reportEvictions(results, nodeName, recorder)
+}
+
+// podsToEvict returns the pods of the node to evict, each with the grace period it is given without a deadline,
+// and a skipped result for each of the given pods which is not evicted, e.g. a DaemonSet pod.
+// When the pods are drained in waves, DaemonSet pods which opted in with the drain-order annotation are evicted too.
+// A pod with the skip-eviction annotation is skipped, unless the drain has a deadline and the annotation is not "always".
+// A pod whose drain loses it or its data is skipped, held or evicted as the data loss policy says.
+func (n Node) podsToEvict(drainHelper *drain.Helper, k8sNodeName string, pods *corev1.PodList, hasDeadline bool, nodeName string, recorder recorderInterface) ([]podEviction, []PodEvictionResult, error) {
+	waves := n.nthConfig.DrainWaveOrder != "" && n.nthConfig.DrainWaveOrder != config.DrainWaveOrderNone
+	if pods == nil && (n.nthConfig.UseAPIServerCacheToListPods || waves) {
+		var err error
+		if pods, err = n.fetchAllPods(k8sNodeName); err != nil {
+			return nil, nil, err
+		}
+	}
+	var podsToEvict []corev1.Pod
+	if n.nthConfig.UseAPIServerCacheToListPods {
+		// FilterOutDaemonSetPods filters the list in place
+		podsToEvict = n.FilterOutDaemonSetPods(&corev1.PodList{Items: pods.Items}).Items
 	} else {
-		// RunNodeDrain does an etcd quorum-read to list all pods on this node
-		err = drain.RunNodeDrain(n.drainHelper, node.Name)
+		// GetPodsForDeletion does an etcd quorum-read to list all pods on this node
+		list, errs := drainHelper.GetPodsForDeletion(k8sNodeName)
+		if errs != nil {
+			return nil, nil, utilerrors.NewAggregate(errs)
+		}
+		if warnings := list.Warnings(); warnings != "" {
+			fmt.Fprintf(drainHelper.ErrOut, "WARNING: %s\n", warnings)
+		}
+		podsToEvict = list.Pods()
+	}
+
+	var evictions []podEviction
+	var skipped []PodEvictionResult
+	considered := make(map[types.NamespacedName]bool, len(podsToEvict))
+	consider := func(pod corev1.Pod) {
+		considered[types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}] = true
+		policy := n.podPolicy(pod, nodeName, recorder)
+		if policy.skip(hasDeadline) {
+			log.Info().Str("node_name", nodeName).Str("pod_name", pod.Name).Str("pod_namespace", pod.Namespace).
+				Msgf("Skipping the eviction of the pod as its %s annotation asks", SkipEvictionAnnotationKey)
+			skipped = append(skipped, PodEvictionResult{
+				Namespace: pod.Namespace,
+				Name:      pod.Name,
+				Outcome:   PodSkipped,
+				Message:   fmt.Sprintf("pod is not drained as its %s annotation is %s", SkipEvictionAnnotationKey, policy.skipEviction),
+			})
+			return
+		}
+		dataLoss := dataLossKinds(drainHelper.Ctx, drainHelper.Client, pod)
+		action := n.dataLossPolicy.action(pod, dataLoss)
+		logger := log.With().Str("node_name", nodeName).Str("pod_name", pod.Name).Str("pod_namespace", pod.Namespace).Strs("data_loss", dataLoss).Logger()
+		if action == config.DataLossSkip {
+			logger.Info().Msg("Skipping the eviction of the pod as the data loss policy asks")
+			skipped = append(skipped, PodEvictionResult{
+				Namespace: pod.Namespace,
+				Name:      pod.Name,
+				Outcome:   PodSkipped,
+				Message:   fmt.Sprintf("pod is not drained as the data loss policy skips %s pods", strings.Join(dataLoss, ", ")),
+				DataLoss:  dataLoss,
+			})
+			return
+		}
+		if len(dataLoss) > 0 {
+			logger.Warn().Str("action", action).Msg("Draining the pod loses it or its data")
+			if recorder != nil {
+				emitPodEvent(recorder, pod, nodeName, corev1.EventTypeWarning, PodDataLossReason, PodDataLossMsgFmt, describeDataLoss(dataLoss), nodeName)
+			}
+		}
+		gracePeriod := podGracePeriod(pod, drainHelper.GracePeriodSeconds)
+		if policy.gracePeriod != nil {
+			gracePeriod = *policy.gracePeriod
+		}
+		evictions = append(evictions, podEviction{pod: pod, gracePeriod: gracePeriod, policy: policy, dataLoss: dataLoss, hold: action == config.DataLossHold})
 	}
-	if err != nil {
-		return err
+	for _, pod := range podsToEvict {
+		consider(pod)
 	}
-	return nil
+	if pods != nil {
+		for _, pod := range pods.Items {
+			key := types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
+			if waves && !considered[key] && isOptedInDaemonSetPod(pod) && filterPodForDeletion(n.nthConfig.PodName, n.nthConfig.PodNamespace)(pod).Delete {
+				consider(pod)
+			}
+			if !considered[key] {
+				skipped = append(skipped, PodEvictionResult{
+					Namespace: pod.Namespace,
+					Name:      pod.Name,
+					Outcome:   PodSkipped,
+					Message:   "pod is not drained, e.g. it is managed by a DaemonSet or it is a mirror pod",
+				})
+			}
+		}
+	}
+	return evictions, skipped, nil
+}
+
+// podPolicy returns the drain policy set by the annotations of the pod, logging each invalid annotation
+// and emitting an event for it
+func (n Node) podPolicy(pod corev1.Pod, nodeName string, recorder recorderInterface) podPolicy {
+	policy, errs := parsePodPolicy(pod)
+	for _, err := range errs {
+		log.Warn().Err(err).Str("node_name", nodeName).Str("pod_name", pod.Name).Str("pod_namespace", pod.Namespace).Msg("Ignoring invalid pod annotation")
+		if recorder != nil {
+			emitPodEvent(recorder, pod, nodeName, corev1.EventTypeWarning, PodAnnotationInvalidReason, PodAnnotationInvalidMsgFmt, err.Error(), nodeName)
+		}
+	}
+	return policy
+}
+
+// evictWaves evicts the waves one after the other, each with an even share of the time left until the drain times out
+// or the deadline, if any, so that a wave which ends early leaves more time to the next ones.
+// With a deadline, the grace periods of the pods of a wave are cut so that they terminate within its share of the time left.
+func evictWaves(ctx context.Context, podEvictor evictor, waves [][]podEviction, nodeName string, deadline time.Time, recorder recorderInterface) []PodEvictionResult {
+	var end time.Time
+	if podEvictor.timeout > 0 {
+		end = time.Now().Add(podEvictor.timeout)
+	}
+	if !deadline.IsZero() {
+		log.Info().Str("node_name", nodeName).Time("deadline", deadline).Msg("Draining the node before the interruption deadline")
+		if end.IsZero() || deadline.Before(end) {
+			end = deadline
+		}
+	}
+
+	var results []PodEvictionResult
+	for i, wave := range waves {
+		wavesLeft := time.Duration(len(waves) - i)
+		waveEvictor := podEvictor
+		if !end.IsZero() {
+			waveEvictor.timeout = max(time.Until(end)/wavesLeft, minDrainTimeout)
+		}
+		if !deadline.IsZero() {
+			clampGracePeriods(wave, time.Until(deadline)/wavesLeft, nodeName, recorder)
+		}
+		if len(waves) > 1 {
+			log.Info().Str("node_name", nodeName).Int("wave", i+1).Int("waves", len(waves)).Int("pods", len(wave)).Dur("timeout", waveEvictor.timeout).Msg("Evicting drain wave")
+		}
+		results = append(results, waveEvictor.evictPods(ctx, wave)...)
+	}
+	return results
+}
+
+// clampGracePeriods cuts the grace periods of the evictions so that every pod terminates within the given time
+func clampGracePeriods(evictions []podEviction, within time.Duration, nodeName string, recorder recorderInterface) {
+	maxGracePeriod := max(int64((within-drainDeadlineMargin)/time.Second), 0)
+	for i, eviction := range evictions {
+		if eviction.gracePeriod <= maxGracePeriod {
+			continue
+		}
+		pod := eviction.pod
+		log.Warn().Str("node_name", nodeName).Str("pod_name", pod.Name).Str("pod_namespace", pod.Namespace).
+			Int64("grace_period_seconds", eviction.gracePeriod).Int64("clamped_grace_period_seconds", maxGracePeriod).
+			Msg("Cutting the grace period of the pod to meet the interruption deadline")
+		if recorder != nil {
+			emitPodEvent(recorder, pod, nodeName, corev1.EventTypeWarning, PodGraceClampedReason, PodGraceClampedMsgFmt, eviction.gracePeriod, maxGracePeriod, nodeName)
+		}
+		evictions[i].gracePeriod = maxGracePeriod
+	}
+}
+
+// reportEvictions logs the result of each pod and emits an event for each pod which is still on the node,
+// and returns the errors which ended their evictions
+func reportEvictions(results []PodEvictionResult, nodeName string, recorder recorderInterface) error {
+	var errs []error
+	for _, result := range results {
+		logger := log.With().Str("node_name", nodeName).Str("pod_name", result.Name).Str("pod_namespace", result.Namespace).
+			Str("outcome", string(result.Outcome)).Int("attempts", result.Attempts).Dur("duration", result.Duration).Logger()
+		if result.Succeeded() {
+			logger.Debug().Msg("Pod drained")
+			continue
+		}
+		logger.Warn().Err(result.err).Msg("Pod was not drained")
+		errs = append(errs, result.Err())
+		if recorder != nil {
+			podRef := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: result.Name, Namespace: result.Namespace}}
+			emitPodEvent(recorder, podRef, nodeName, corev1.EventTypeWarning, PodEvictFailedReason, PodEvictFailedMsgFmt, result.Outcome, result.Duration.Round(time.Second), nodeName, result.Message)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
 }
 
-// Cordon will add a NoSchedule on the node
+// podGracePeriod returns the grace period the pod is given when it is evicted without a deadline,
+// which is the configured one, or the one of the pod if none is configured
+func podGracePeriod(pod corev1.Pod, configured int) int64 {
+	if configured >= 0 {
+		return int64(configured)
+	}
+	if pod.Spec.TerminationGracePeriodSeconds != nil {
+		return *pod.Spec.TerminationGracePeriodSeconds
+	}
+	return corev1.DefaultTerminationGracePeriodSeconds
+}
+
+// Cordon will add a NoSchedule on the node, and record it as owned by NTH unless the node was already cordoned
 func (n Node) Cordon(nodeName string, reason string) error {
 	if n.nthConfig.DryRun {
 		log.Info().Str("node_name", nodeName).Str("reason", reason).Msgf("Node would have been cordoned, but dry-run flag was set")
@@ -186,14 +455,21 @@ func (n Node) Cordon(nodeName string, reason string) error {
 	if err != nil {
 		return err
 	}
-	err = drain.RunCordonOrUncordon(n.drainHelper, node, true)
-	if err != nil {
-		return err
-	}
-	return nil
+	return n.updateOwned(node, func(node *corev1.Node, record *ownership) bool {
+		if node.Spec.Unschedulable {
+			if !record.Cordoned {
+				log.Info().Str("node_name", nodeName).Msg("Node is already cordoned by another actor, it is left cordoned once NTH is done with it")
+			}
+			return false
+		}
+		node.Spec.Unschedulable = true
+		record.Cordoned = true
+		return true
+	})
 }
 
-// Uncordon will remove the NoSchedule on the node
+// Uncordon will remove the NoSchedule on the node if NTH cordoned it.
+// A node cordoned by another actor is left cordoned and an OwnershipConflictError is returned.
 func (n Node) Uncordon(nodeName string) error {
 	if n.nthConfig.DryRun {
 		log.Info().Str("node_name", nodeName).Msg("Node would have been uncordoned, but dry-run flag was set")
@@ -203,11 +479,24 @@ func (n Node) Uncordon(nodeName string) error {
 	if err != nil {
 		return fmt.Errorf("there was an error fetching the node in preparation for uncordoning: %w", err)
 	}
-	err = drain.RunCordonOrUncordon(n.drainHelper, node, false)
+	var conflict error
+	err = n.updateOwned(node, func(node *corev1.Node, record *ownership) bool {
+		conflict = nil
+		switch {
+		case record.Cordoned:
+			// the node may have been uncordoned by another actor meanwhile, either way NTH no longer owns its cordon
+			node.Spec.Unschedulable = false
+			record.Cordoned = false
+			return true
+		case node.Spec.Unschedulable:
+			conflict = conflictError(nodeName, []string{"cordon"})
+		}
+		return false
+	})
 	if err != nil {
 		return err
 	}
-	return nil
+	return conflict
 }
 
 // IsUnschedulable checks if the node is marked as unschedulable
@@ -238,26 +527,50 @@ func (n Node) MaybeMarkForExclusionFromLoadBalancers(nodeName string) error {
 		log.Debug().Msg("Not marking for exclusion from load balancers because the configuration flag is not set")
 		return nil
 	}
-	err := n.addLabel(nodeName, ExcludeFromLoadBalancersLabelKey, ExcludeFromLoadBalancersLabelValue, true)
+	node, err := n.fetchKubernetesNode(nodeName)
+	if err != nil {
+		return fmt.Errorf("unable to label node for exclusion from load balancers: %w", err)
+	}
+	if _, ok := node.Labels[ExcludeFromLoadBalancersLabelKey]; ok {
+		return nil
+	}
+	if n.nthConfig.DryRun {
+		log.Info().Msgf("Would have added label (%s=%s) to node %s, but dry-run flag was set", ExcludeFromLoadBalancersLabelKey, ExcludeFromLoadBalancersLabelValue, nodeName)
+		return nil
+	}
+	err = n.updateOwned(node, func(node *corev1.Node, record *ownership) bool {
+		if _, ok := node.Labels[ExcludeFromLoadBalancersLabelKey]; ok {
+			return false
+		}
+		node.Labels = setOwned(node.Labels, map[string]string{ExcludeFromLoadBalancersLabelKey: ExcludeFromLoadBalancersLabelValue}, &record.Labels)
+		return true
+	})
 	if err != nil {
 		return fmt.Errorf("unable to label node for exclusion from load balancers: %w", err)
 	}
 	return nil
 }
 
-// RemoveNTHLabels will remove all the custom NTH labels added to the node
+// RemoveNTHLabels will remove all the custom NTH labels added to the node,
+// and revert the labels and annotations NTH set, such as the exclusion from load balancers and the marks of the marking policy.
+// A label or annotation another actor changed since NTH set it is left in place and an OwnershipConflictError is returned.
 func (n Node) RemoveNTHLabels(nodeName string) error {
+	conflicts, err := n.revertOwnedLabels(nodeName)
+	if err != nil {
+		return fmt.Errorf("unable to revert the labels and annotations set by NTH: %w", err)
+	}
 	for _, label := range []string{EventIDLabelKey, ActionLabelKey, ActionLabelTimeKey} {
 		err := n.removeLabel(nodeName, label)
 		if err != nil {
 			return fmt.Errorf("unable to remove %s from node: %w", label, err)
 		}
 	}
-	err := n.removeLabelIfValueMatches(nodeName, ExcludeFromLoadBalancersLabelKey, ExcludeFromLoadBalancersLabelValue)
+	// nodes labeled before NTH recorded its ownership carry the unique value of NTH
+	err = n.removeLabelIfValueMatches(nodeName, ExcludeFromLoadBalancersLabelKey, ExcludeFromLoadBalancersLabelValue)
 	if err != nil {
 		return fmt.Errorf("unable to remove %s from node: %w", ExcludeFromLoadBalancersLabelKey, err)
 	}
-	return nil
+	return conflictError(nodeName, conflicts)
 }
 
 // GetEventID will retrieve the event ID value from the node label
@@ -277,10 +590,24 @@ func (n Node) GetEventID(nodeName string) (string, error) {
 	return val, nil
 }
 
-// MarkForUncordonAfterReboot adds labels to the kubernetes node which NTH will read upon reboot
+// MarkForUncordonAfterReboot adds labels to the kubernetes node which NTH will read upon reboot,
+// and records the boot ID of the node, which changes once the node has rebooted
 func (n Node) MarkForUncordonAfterReboot(nodeName string) error {
+	k8sNode, err := n.fetchKubernetesNode(nodeName)
+	if err != nil {
+		return fmt.Errorf("unable to fetch kubernetes node from API: %w", err)
+	}
+	if bootID := k8sNode.Status.NodeInfo.BootID; bootID != "" {
+		err = n.addAnnotation(nodeName, BootIDAnnotationKey, bootID)
+	} else {
+		// a boot ID recorded by a previous mark must not be compared to the next one
+		err = n.removeAnnotation(nodeName, BootIDAnnotationKey)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to record the boot ID of the node for uncordon after system-reboot: %w", err)
+	}
 	// adds label to node so that the system will uncordon the node after the scheduled reboot has taken place
-	err := n.addLabel(nodeName, ActionLabelKey, UncordonAfterRebootLabelVal, false)
+	err = n.addLabel(nodeName, ActionLabelKey, UncordonAfterRebootLabelVal, false)
 	if err != nil {
 		return fmt.Errorf("unable to label node with action to uncordon after system-reboot: %w", err)
 	}
@@ -374,19 +701,56 @@ func (n Node) removeLabel(nodeName string, key string) error {
 	return nil
 }
 
-// removeLabelIfValueMatches will remove a node label given a label key provided the label's value equals matchValue
-func (n Node) removeLabelIfValueMatches(nodeName string, key string, matchValue string) error {
-	type patchRequest struct {
-		Op   string `json:"op"`
-		Path string `json:"path"`
+// addAnnotation will add an annotation to the node given an annotation key and value
+func (n Node) addAnnotation(nodeName string, key string, value string) error {
+	return n.patchAnnotation(nodeName, key, &value)
+}
+
+// removeAnnotation will remove a node annotation given an annotation key, if the node has it
+func (n Node) removeAnnotation(nodeName string, key string) error {
+	return n.patchAnnotation(nodeName, key, nil)
+}
+
+// patchAnnotation sets the annotation of the node to value, or removes it if value is nil
+func (n Node) patchAnnotation(nodeName string, key string, value *string) error {
+	payload := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]*string{key: value},
+		},
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("an error occurred while marshalling the json to patch an annotation of the node: %w", err)
+	}
+	node, err := n.fetchKubernetesNode(nodeName)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		if _, ok := node.Annotations[key]; !ok {
+			return nil
+		}
+	}
+	if n.nthConfig.DryRun {
+		log.Info().Msgf("Would have patched annotation %s of node %s, but dry-run flag was set", key, nodeName)
+		return nil
 	}
+	_, err = n.drainHelper.Client.CoreV1().Nodes().Patch(context.TODO(), node.Name, types.StrategicMergePatchType, payloadBytes, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("%v node patch failed when patching an annotation of the node: %w", node.Name, err)
+	}
+	return nil
+}
 
-	var patchReqs []interface{}
-	patchRemove := patchRequest{
-		Op:   "remove",
-		Path: fmt.Sprintf("/metadata/labels/%s", jsonPatchEscape(key)),
+// removeLabelIfValueMatches will remove a node label given a label key provided the label's value equals matchValue.
+// The label is removed with a merge patch, which does nothing if the label is already gone, since the node may be read from a stale cache.
+func (n Node) removeLabelIfValueMatches(nodeName string, key string, matchValue string) error {
+	payload := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]*string{key: nil},
+		},
 	}
-	payload, err := json.Marshal(append(patchReqs, patchRemove))
+	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("an error occurred while marshalling the json to remove a label from the node: %w", err)
 	}
@@ -395,14 +759,14 @@ func (n Node) removeLabelIfValueMatches(nodeName string, key string, matchValue
 		return err
 	}
 	val, ok := node.Labels[key]
-	if !ok || val == matchValue {
+	if !ok || val != matchValue {
 		return nil
 	}
 	if n.nthConfig.DryRun {
 		log.Info().Msgf("Would have removed label with key %s from node %s, but dry-run flag was set", key, nodeName)
 		return nil
 	}
-	_, err = n.drainHelper.Client.CoreV1().Nodes().Patch(context.TODO(), node.Name, types.JSONPatchType, payload, metav1.PatchOptions{})
+	_, err = n.drainHelper.Client.CoreV1().Nodes().Patch(context.TODO(), node.Name, types.StrategicMergePatchType, payloadBytes, metav1.PatchOptions{})
 	if err != nil {
 		return fmt.Errorf("%v node patch failed when removing a label from the node: %w", node.Name, err)
 	}
@@ -422,34 +786,6 @@ func (n Node) GetNodeLabels(nodeName string) (map[string]string, error) {
 	return node.Labels, nil
 }
 
-func (n Node) GetNodeNameFromProviderID(providerId string) (string, error) {
-	if n.nthConfig.DryRun {
-		return "", nil
-	}
-
-	listOptions := metav1.ListOptions{}
-	nodes, err := n.drainHelper.Client.CoreV1().Nodes().List(context.TODO(), listOptions)
-	if err != nil {
-		log.Err(err).Msgf("Error when trying to list nodes to find node with ProviderID")
-
-		return "", err
-	}
-
-	for _, n := range nodes.Items {
-		if n.Spec.ProviderID == providerId {
-			labels := n.GetObjectMeta().GetLabels()
-
-			if hostname, ok := labels["kubernetes.io/hostname="]; ok {
-				return hostname, nil
-			}
-
-			return n.GetObjectMeta().GetName(), nil
-		}
-	}
-
-	return "", fmt.Errorf("node with ProviderID '%s' was not found in the cluster", providerId)
-}
-
 // TaintSpotItn adds the spot termination notice taint onto a node
 func (n Node) TaintSpotItn(nodeName string, eventID string) error {
 	if !n.nthConfig.TaintNode {
@@ -560,27 +896,14 @@ func (n Node) TaintOutOfService(nodeName string) error {
 	return addTaint(k8sNode, n, OutOfServiceTaintKey, OutOfServiceTaintValue, OutOfServiceTaintEffectType)
 }
 
-// RemoveNTHTaints removes NTH-specific taints from a node
+// RemoveNTHTaints removes the taints NTH added to the node, including the taints set by the marking policy.
+// A taint another actor changed since NTH added it is left in place and an OwnershipConflictError is returned.
 func (n Node) RemoveNTHTaints(nodeName string) error {
-	if !n.nthConfig.TaintNode {
-		return nil
-	}
-
-	k8sNode, err := n.fetchKubernetesNode(nodeName)
+	conflicts, err := n.revertOwnedTaints(nodeName)
 	if err != nil {
-		return fmt.Errorf("unable to fetch kubernetes node from API: %w", err)
+		return fmt.Errorf("unable to clean the taints added by NTH from node %s: %w", nodeName, err)
 	}
-
-	taints := []string{SpotInterruptionTaint, ScheduledMaintenanceTaint, ASGLifecycleTerminationTaint, RebalanceRecommendationTaint}
-
-	for _, taint := range taints {
-		_, err = removeTaint(k8sNode, n.drainHelper.Client, taint)
-		if err != nil {
-			return fmt.Errorf("unable to clean taint %s from node %s", taint, nodeName)
-		}
-	}
-
-	return nil
+	return conflictError(nodeName, conflicts)
 }
 
 // IsLabeledWithAction will return true if the current node is labeled with NTH action labels
@@ -594,56 +917,134 @@ func (n Node) IsLabeledWithAction(nodeName string) (bool, error) {
 	return actionLabelOK && eventIDLabelOK, nil
 }
 
+// IsMarkedForEvent will return true if the node carries the marks NTH set for the given event, which are the taints, labels
+// and annotations of the marking policy rule of its kind rendered with data, or by default the NTH event ID label or a taint
+// NTH added with the event ID as its value.
+func (n Node) IsMarkedForEvent(nodeName string, eventID string, kind string, data interface{}) (bool, error) {
+	k8sNode, err := n.fetchKubernetesNode(nodeName)
+	if err != nil {
+		return false, fmt.Errorf("unable to fetch kubernetes node from API: %w", err)
+	}
+	if rule, ok := n.MarkingRule(kind); ok {
+		return n.isMarkedByRule(k8sNode, rule, data)
+	}
+	if k8sNode.Labels[EventIDLabelKey] == eventID {
+		return true, nil
+	}
+	taintValue := eventID
+	if len(taintValue) > maxTaintValueLength {
+		taintValue = taintValue[:maxTaintValueLength]
+	}
+	for _, owned := range readOwnership(k8sNode).Taints {
+		if owned.Value == taintValue && hasTaint(k8sNode, owned) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Unmark uncordons the node and removes the labels and taints NTH added to it, for an event NTH no longer acts on.
+// Changes another actor made since NTH did are left in place and returned as OwnershipConflictErrors.
+func (n Node) Unmark(nodeName string) error {
+	return utilerrors.NewAggregate([]error{n.Uncordon(nodeName), n.RemoveNTHLabels(nodeName), n.RemoveNTHTaints(nodeName)})
+}
+
 // UncordonIfRebooted will check for node labels to trigger an uncordon because of a system-reboot scheduled event
 func (n Node) UncordonIfRebooted(nodeName string) error {
-	// TODO: this logic needs to be updated to dynamically look up the last reboot
-	// w/ the ec2 api if the nodeName is not local.
 	k8sNode, err := n.fetchKubernetesNode(nodeName)
 	if err != nil {
 		return fmt.Errorf("unable to fetch kubernetes node from API: %w", err)
 	}
-	timeVal, ok := k8sNode.Labels[ActionLabelTimeKey]
-	if !ok {
+	if _, ok := k8sNode.Labels[ActionLabelTimeKey]; !ok {
 		log.Debug().Msgf("There was no %s label found requiring action label handling", ActionLabelTimeKey)
 		return nil
 	}
-	timeValNum, err := strconv.ParseInt(timeVal, 10, 64)
-	if err != nil {
-		return fmt.Errorf("cannot convert unix time: %w", err)
-	}
-	secondsSinceLabel := time.Now().Unix() - timeValNum
 	switch actionVal := k8sNode.Labels[ActionLabelKey]; actionVal {
 	case UncordonAfterRebootLabelVal:
-		uptime, err := n.uptime()
+		rebooted, err := n.hasRebooted(nodeName, k8sNode)
 		if err != nil {
 			return err
 		}
-		if secondsSinceLabel < uptime {
-			log.Debug().Msg("The system has not restarted yet.")
+		if !rebooted {
+			log.Debug().Str("node_name", nodeName).Msg("The system has not restarted yet.")
 			return nil
 		}
+		// conflicts do not stop the cleanup, otherwise the action would be handled again and again
+		var conflicts []string
 		err = n.Uncordon(nodeName)
-		if err != nil {
+		if err != nil && !collectConflicts(err, &conflicts) {
 			return fmt.Errorf("unable to uncordon node: %w", err)
 		}
 		err = n.RemoveNTHLabels(nodeName)
-		if err != nil {
+		if err != nil && !collectConflicts(err, &conflicts) {
 			return err
 		}
+		for _, annotation := range []string{BootIDAnnotationKey, TerminationAnnotationKey} {
+			err = n.removeAnnotation(nodeName, annotation)
+			if err != nil {
+				return fmt.Errorf("unable to remove %s from node: %w", annotation, err)
+			}
+		}
 
 		err = n.RemoveNTHTaints(nodeName)
-		if err != nil {
+		if err != nil && !collectConflicts(err, &conflicts) {
 			return err
 		}
 
-		log.Info().Msgf("Successfully completed action %s.", UncordonAfterRebootLabelVal)
+		log.Info().Str("node_name", nodeName).Msgf("Successfully completed action %s.", UncordonAfterRebootLabelVal)
+		return conflictError(nodeName, conflicts)
 	default:
 		log.Debug().Msg("There are no label actions to handle.")
 	}
 	return nil
 }
 
-// fetchKubernetesNode will send an http request to the k8s api server and return the corev1 model node
+// hasRebooted returns true if the node rebooted since it was marked for uncordon after reboot.
+// The boot ID of the node is compared to the one recorded when it was marked. The local node is also compared against
+// the uptime of the system NTH runs on, since the kubelet may not have reported the new boot ID yet when NTH starts.
+func (n Node) hasRebooted(nodeName string, k8sNode *corev1.Node) (bool, error) {
+	markedBootID, marked := k8sNode.Annotations[BootIDAnnotationKey]
+	if bootID := k8sNode.Status.NodeInfo.BootID; marked && bootID != "" && bootID != markedBootID {
+		return true, nil
+	}
+	if nodeName != n.nthConfig.NodeName {
+		if !marked {
+			log.Debug().Str("node_name", nodeName).Msgf("Node has no %s annotation, unable to tell whether it rebooted", BootIDAnnotationKey)
+		}
+		return false, nil
+	}
+	timeValNum, err := strconv.ParseInt(k8sNode.Labels[ActionLabelTimeKey], 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("cannot convert unix time: %w", err)
+	}
+	secondsSinceLabel := time.Now().Unix() - timeValNum
+	uptime, err := n.uptime()
+	if err != nil {
+		return false, err
+	}
+	return secondsSinceLabel >= uptime, nil
+}
+
+// UncordonRebootedNodes uncordons every node marked for uncordon after reboot which has rebooted since it was marked
+func (n Node) UncordonRebootedNodes(ctx context.Context) error {
+	if n.nthConfig.DryRun {
+		return nil
+	}
+	selector := fmt.Sprintf("%s=%s", ActionLabelKey, UncordonAfterRebootLabelVal)
+	nodes, err := n.drainHelper.Client.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return fmt.Errorf("unable to list the nodes marked for uncordon after reboot: %w", err)
+	}
+	var errs []error
+	for _, k8sNode := range nodes.Items {
+		if err := n.UncordonIfRebooted(k8sNode.Name); err != nil {
+			errs = append(errs, fmt.Errorf("node %s: %w", k8sNode.Name, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// fetchKubernetesNode will return the corev1 model node from the informer cache if it has synced, or else send an http request to the k8s api server
 func (n Node) fetchKubernetesNode(nodeName string) (*corev1.Node, error) {
 	node := &corev1.Node{
 		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
@@ -653,6 +1054,16 @@ func (n Node) fetchKubernetesNode(nodeName string) (*corev1.Node, error) {
 		return node, nil
 	}
 	shortNodeName := strings.Split(nodeName, ".")[0]
+	if n.cache.Synced() {
+		nodes, err := n.cache.nodesByIndex(hostnameIndex, nodeName, shortNodeName)
+		if err == nil && len(nodes) > 0 {
+			return nodes[0], nil
+		}
+		if cachedNode, ok := n.cache.Node(nodeName); ok {
+			return cachedNode, nil
+		}
+		// the node may have joined the cluster since the cache was last updated
+	}
 	labelSelector := metav1.LabelSelector{
 		MatchExpressions: []metav1.LabelSelectorRequirement{
 			{
@@ -680,6 +1091,9 @@ func (n Node) FetchKubernetesNodeInstanceIds() ([]string, error) {
 		log.Info().Msgf("Would have retrieved nodes, but dry-run flag was set")
 		return ids, nil
 	}
+	if n.cache.Synced() {
+		return append(ids, n.cache.instanceIDs()...), nil
+	}
 	matchingNodes, err := n.drainHelper.Client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
 		log.Warn().Msgf("Unable to list Nodes")
@@ -714,6 +1128,9 @@ func (n Node) fetchAllPods(nodeName string) (*corev1.PodList, error) {
 		log.Info().Msgf("Would have retrieved running pod list on node %s, but dry-run flag was set", nodeName)
 		return &corev1.PodList{}, nil
 	}
+	if n.cache.Synced() {
+		return n.cache.podsOnNode(nodeName)
+	}
 	listOptions := metav1.ListOptions{
 		FieldSelector: "spec.nodeName=" + nodeName,
 	}
@@ -748,10 +1165,11 @@ func getDrainHelper(nthConfig config.Config, clientset *kubernetes.Clientset) (*
 		GracePeriodSeconds:  nthConfig.PodTerminationGracePeriod,
 		IgnoreAllDaemonSets: nthConfig.IgnoreDaemonSets,
 		AdditionalFilters:   []drain.PodFilter{filterPodForDeletion(nthConfig.PodName, nthConfig.PodNamespace)},
-		DeleteEmptyDirData:  nthConfig.DeleteLocalData,
-		Timeout:             time.Duration(nthConfig.NodeTerminationGracePeriod) * time.Second,
-		Out:                 &StdWriter{&ZerologWriter{logger: log.Logger}},
-		ErrOut:              &ErrWriter{&ZerologWriter{logger: log.Logger}},
+		// pods which are lost with their node, or lose their data, are handled by the data loss policy
+		DeleteEmptyDirData: true,
+		Timeout:            time.Duration(nthConfig.NodeTerminationGracePeriod) * time.Second,
+		Out:                &StdWriter{&ZerologWriter{logger: log.Logger}},
+		ErrOut:             &ErrWriter{&ZerologWriter{logger: log.Logger}},
 	}
 
 	if nthConfig.DryRun {
@@ -762,6 +1180,13 @@ func getDrainHelper(nthConfig config.Config, clientset *kubernetes.Clientset) (*
 	return drainHelper, nil
 }
 
+// drainHelperWithContext returns a copy of the drain helper bound to ctx
+func (n Node) drainHelperWithContext(ctx context.Context) *drain.Helper {
+	drainHelper := *n.drainHelper
+	drainHelper.Ctx = ctx
+	return &drainHelper
+}
+
 func jsonPatchEscape(value string) string {
 	value = strings.ReplaceAll(value, "~", "~0")
 	return strings.ReplaceAll(value, "/", "~1")
@@ -815,6 +1240,9 @@ func addTaint(node *corev1.Node, nth Node, taintKey string, taintValue string, e
 			}
 			return nil
 		}
+		if err = recordTaint(freshNode, corev1.Taint{Key: taintKey, Value: taintValue, Effect: effect}); err != nil {
+			return err
+		}
 		_, err = client.CoreV1().Nodes().Update(context.TODO(), freshNode, metav1.UpdateOptions{})
 		if err != nil && errors.IsConflict(err) && time.Now().Before(retryDeadline) {
 			refresh = true
@@ -856,63 +1284,6 @@ func addTaintToSpec(node *corev1.Node, taintKey string, taintValue string, effec
 	return true
 }
 
-func removeTaint(node *corev1.Node, client kubernetes.Interface, taintKey string) (bool, error) {
-	retryDeadline := time.Now().Add(maxRetryDeadline)
-	freshNode := node.DeepCopy()
-	var err error
-	refresh := false
-	for {
-		if refresh {
-			// Get the newest version of the node.
-			freshNode, err = client.CoreV1().Nodes().Get(context.TODO(), node.Name, metav1.GetOptions{})
-			if err != nil || freshNode == nil {
-				return false, fmt.Errorf("failed to get node %v: %v", node.Name, err)
-			}
-		}
-		newTaints := make([]corev1.Taint, 0)
-		for _, taint := range freshNode.Spec.Taints {
-			if taint.Key == taintKey {
-				log.Info().
-					Interface("taint", taint).
-					Str("node_name", node.Name).
-					Msg("Releasing taint on node")
-			} else {
-				newTaints = append(newTaints, taint)
-			}
-		}
-		if len(newTaints) == len(freshNode.Spec.Taints) {
-			if !refresh {
-				// Make sure we have the latest version before skipping update.
-				refresh = true
-				continue
-			}
-			return false, nil
-		}
-
-		freshNode.Spec.Taints = newTaints
-		_, err = client.CoreV1().Nodes().Update(context.TODO(), freshNode, metav1.UpdateOptions{})
-
-		if err != nil && errors.IsConflict(err) && time.Now().Before(retryDeadline) {
-			refresh = true
-			time.Sleep(conflictRetryInterval)
-			continue
-		}
-
-		if err != nil {
-			log.Err(err).
-				Str("taint_key", taintKey).
-				Str("node_name", node.Name).
-				Msg("Error while releasing taint on node")
-			return false, err
-		}
-		log.Info().
-			Str("taint_key", taintKey).
-			Str("node_name", node.Name).
-			Msg("Successfully released taint on node")
-		return true, nil
-	}
-}
-
 func getUptimeFunc(uptimeFile string) uptime.UptimeFuncType {
 	if uptimeFile != "" {
 		return func() (int64, error) {
@@ -932,8 +1303,12 @@ func filterPodForDeletion(podName, podNamespace string) func(pod corev1.Pod) dra
 }
 
 func isDaemonSetPod(pod corev1.Pod) bool {
+	return isOwnedBy(pod, daemonSet)
+}
+
+func isOwnedBy(pod corev1.Pod, kind string) bool {
 	for _, owner := range pod.OwnerReferences {
-		if owner.Kind == daemonSet {
+		if owner.Kind == kind {
 			return true
 		}
 	}
@@ -955,3 +1330,18 @@ func (w *ErrWriter) Write(p []byte) (n int, err error) {
 type recorderInterface interface {
 	AnnotatedEventf(object runtime.Object, annotations map[string]string, eventType, reason, messageFmt string, args ...interface{})
 }
+
+// emitPodEvent emits a Kubernetes event for the pod, annotated with the node and the labels of the pod
+func emitPodEvent(recorder recorderInterface, pod corev1.Pod, nodeName string, eventType, reason, messageFmt string, args ...interface{}) {
+	podRef := &corev1.ObjectReference{
+		Kind:      "Pod",
+		Name:      pod.Name,
+		Namespace: pod.Namespace,
+	}
+	annotations := make(map[string]string)
+	annotations["node"] = nodeName
+	for k, v := range pod.GetLabels() {
+		annotations[k] = v
+	}
+	recorder.AnnotatedEventf(podRef, annotations, eventType, reason, messageFmt, args...)
+}
@@ -15,6 +15,7 @@ package node_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -24,12 +25,14 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/aws/aws-node-termination-handler/pkg/config"
+	"github.com/aws/aws-node-termination-handler/pkg/monitor"
 	"github.com/aws/aws-node-termination-handler/pkg/node"
 	h "github.com/aws/aws-node-termination-handler/pkg/test"
 	"github.com/aws/aws-node-termination-handler/pkg/uptime"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/kubectl/pkg/drain"
 )
@@ -83,7 +86,7 @@ func TestDryRun(t *testing.T) {
 	fakeRecorder := record.NewFakeRecorder(recorderBufferSize)
 	defer close(fakeRecorder.Events)
 
-	err = tNode.CordonAndDrain(nodeName, "cordonReason", fakeRecorder)
+	_, err = tNode.CordonAndDrain(context.Background(), nodeName, "cordonReason", time.Time{}, fakeRecorder)
 
 	h.Ok(t, err)
 
@@ -158,7 +161,7 @@ func TestDrainSuccess(t *testing.T) {
 	drainHelper := getDrainHelper(client)
 	drainHelper.DisableEviction = true
 	tNode := getNode(t, drainHelper)
-	err = tNode.CordonAndDrain(nodeName, "cordonReason", fakeRecorder)
+	_, err = tNode.CordonAndDrain(context.Background(), nodeName, "cordonReason", time.Time{}, fakeRecorder)
 	close(fakeRecorder.Events)
 	h.Ok(t, err)
 	expectedEventArrived := false
@@ -170,11 +173,79 @@ func TestDrainSuccess(t *testing.T) {
 	h.Assert(t, expectedEventArrived, "PodEvicted event was not emitted")
 }
 
+func TestDrainClampsGracePeriodToDeadline(t *testing.T) {
+	isOwnerController := true
+	gracePeriod := int64(300)
+	client := fake.NewSimpleClientset()
+	_, err := client.CoreV1().Nodes().Create(
+		context.Background(),
+		&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+		},
+		metav1.CreateOptions{})
+	h.Ok(t, err)
+
+	_, err = client.CoreV1().Pods("default").Create(
+		context.Background(),
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "stateful-app-pod",
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: "apps/v1",
+						Name:       "stateful-app",
+						Kind:       "StatefulSet",
+						Controller: &isOwnerController,
+					},
+				},
+			},
+			Spec: v1.PodSpec{
+				NodeName:                      nodeName,
+				TerminationGracePeriodSeconds: &gracePeriod,
+			},
+		},
+		metav1.CreateOptions{})
+	h.Ok(t, err)
+
+	fakeRecorder := record.NewFakeRecorder(recorderBufferSize)
+
+	drainHelper := getDrainHelper(client)
+	drainHelper.DisableEviction = true
+	tNode := getNode(t, drainHelper)
+	results, err := tNode.CordonAndDrain(context.Background(), nodeName, "cordonReason", time.Now().Add(30*time.Second), fakeRecorder)
+	close(fakeRecorder.Events)
+	h.Ok(t, err)
+	h.Equals(t, 1, len(results))
+	h.Equals(t, "stateful-app-pod", results[0].Name)
+	h.Equals(t, node.PodDeleted, results[0].Outcome)
+	h.Assert(t, results[0].GracePeriodSeconds <= 25, "Expected the result to report the clamped grace period")
+
+	clampedEventArrived := false
+	for event := range fakeRecorder.Events {
+		if strings.Contains(event, "Warning PodGracePeriodClamped Pod grace period cut from 300s") {
+			clampedEventArrived = true
+		}
+	}
+	h.Assert(t, clampedEventArrived, "PodGracePeriodClamped event was not emitted")
+
+	deleted := false
+	for _, action := range client.Actions() {
+		deleteAction, ok := action.(k8stesting.DeleteActionImpl)
+		if !ok || deleteAction.GetResource().Resource != "pods" {
+			continue
+		}
+		deleted = true
+		clampedGracePeriod := deleteAction.GetDeleteOptions().GracePeriodSeconds
+		h.Assert(t, clampedGracePeriod != nil && *clampedGracePeriod <= 25, "Expected the grace period of the pod to be clamped to the deadline")
+	}
+	h.Assert(t, deleted, "Pod was not deleted")
+}
+
 func TestDrainCordonNodeFailure(t *testing.T) {
 	fakeRecorder := record.NewFakeRecorder(recorderBufferSize)
 	defer close(fakeRecorder.Events)
 	tNode := getNode(t, getDrainHelper(fake.NewSimpleClientset()))
-	err := tNode.CordonAndDrain(nodeName, "cordonReason", fakeRecorder)
+	_, err := tNode.CordonAndDrain(context.Background(), nodeName, "cordonReason", time.Time{}, fakeRecorder)
 	h.Assert(t, true, "Failed to return error on CordonAndDrain failing to cordon node", err != nil)
 }
 
@@ -342,6 +413,46 @@ func TestIsLableledWithActionFailure(t *testing.T) {
 	h.Assert(t, err != nil, "Failed to return error on IsLabeledWithAction failure")
 }
 
+func TestIsMarkedForEvent(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	_, err := client.CoreV1().Nodes().Create(
+		context.Background(),
+		&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   nodeName,
+				Labels: map[string]string{node.EventIDLabelKey: "labeled-event"},
+			},
+			Spec: v1.NodeSpec{
+				Taints: []v1.Taint{{Key: "example.com/other", Value: "foreign-event", Effect: v1.TaintEffectNoSchedule}},
+			},
+		},
+		metav1.CreateOptions{})
+	h.Ok(t, err)
+	tNode, err := node.NewWithValues(config.Config{NodeName: nodeName, TaintNode: true, TaintEffect: "NoSchedule"}, getDrainHelper(client), uptime.Uptime)
+	h.Ok(t, err)
+	h.Ok(t, tNode.TaintSpotItn(nodeName, "tainted-event"))
+
+	marked, err := tNode.IsMarkedForEvent(nodeName, "labeled-event", monitor.ScheduledEventKind, nil)
+	h.Ok(t, err)
+	h.Equals(t, true, marked)
+
+	marked, err = tNode.IsMarkedForEvent(nodeName, "tainted-event", monitor.SpotITNKind, nil)
+	h.Ok(t, err)
+	h.Equals(t, true, marked)
+
+	// a taint NTH did not add is not a mark
+	marked, err = tNode.IsMarkedForEvent(nodeName, "foreign-event", monitor.SpotITNKind, nil)
+	h.Ok(t, err)
+	h.Equals(t, false, marked)
+
+	marked, err = tNode.IsMarkedForEvent(nodeName, "other-event", monitor.SpotITNKind, nil)
+	h.Ok(t, err)
+	h.Equals(t, false, marked)
+
+	_, err = tNode.IsMarkedForEvent("missing-node", "labeled-event", monitor.ScheduledEventKind, nil)
+	h.Assert(t, err != nil, "Failed to return error on IsMarkedForEvent failed to find node")
+}
+
 func TestUncordonIfRebootedDefaultSuccess(t *testing.T) {
 	client := fake.NewSimpleClientset()
 	_, err := client.CoreV1().Nodes().Create(context.Background(),
@@ -386,6 +497,127 @@ func TestUncordonIfRebootedTimeParseFailure(t *testing.T) {
 	h.Assert(t, err != nil, "Failed to return error on UncordonIfReboted failure to parse time")
 }
 
+func rebootMarkedNode(name string, bootID string) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				node.EventIDLabelKey:    "event",
+				node.ActionLabelKey:     node.UncordonAfterRebootLabelVal,
+				node.ActionLabelTimeKey: strconv.FormatInt(time.Now().Unix(), 10),
+			},
+			Annotations: map[string]string{
+				node.BootIDAnnotationKey:    "boot-1",
+				node.OwnershipAnnotationKey: `{"cordoned":true}`,
+			},
+		},
+		Spec:   v1.NodeSpec{Unschedulable: true},
+		Status: v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{BootID: bootID}},
+	}
+}
+
+func TestMarkForUncordonAfterRebootBootID(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "remote-node"},
+		Status:     v1.NodeStatus{NodeInfo: v1.NodeSystemInfo{BootID: "boot-1"}},
+	})
+	tNode := getNode(t, getDrainHelper(client))
+	err := tNode.MarkForUncordonAfterReboot("remote-node")
+	h.Ok(t, err)
+
+	k8sNode, err := client.CoreV1().Nodes().Get(context.Background(), "remote-node", metav1.GetOptions{})
+	h.Ok(t, err)
+	h.Equals(t, "boot-1", k8sNode.Annotations[node.BootIDAnnotationKey])
+	h.Equals(t, node.UncordonAfterRebootLabelVal, k8sNode.Labels[node.ActionLabelKey])
+}
+
+func TestUncordonIfRebootedBootID(t *testing.T) {
+	client := fake.NewSimpleClientset(rebootMarkedNode("remote-node", "boot-1"))
+	tNode := getNode(t, getDrainHelper(client))
+
+	err := tNode.UncordonIfRebooted("remote-node")
+	h.Ok(t, err)
+	k8sNode, err := client.CoreV1().Nodes().Get(context.Background(), "remote-node", metav1.GetOptions{})
+	h.Ok(t, err)
+	h.Assert(t, k8sNode.Spec.Unschedulable, "Node should stay cordoned until its boot ID changes")
+
+	k8sNode.Status.NodeInfo.BootID = "boot-2"
+	_, err = client.CoreV1().Nodes().Update(context.Background(), k8sNode, metav1.UpdateOptions{})
+	h.Ok(t, err)
+	err = tNode.UncordonIfRebooted("remote-node")
+	h.Ok(t, err)
+	k8sNode, err = client.CoreV1().Nodes().Get(context.Background(), "remote-node", metav1.GetOptions{})
+	h.Ok(t, err)
+	h.Assert(t, !k8sNode.Spec.Unschedulable, "Node should be uncordoned once its boot ID changed")
+	_, ok := k8sNode.Labels[node.ActionLabelKey]
+	h.Assert(t, !ok, "Action label should have been removed")
+	_, ok = k8sNode.Annotations[node.BootIDAnnotationKey]
+	h.Assert(t, !ok, "Boot ID annotation should have been removed")
+}
+
+func TestUncordonIfRebootedCordonedByAnotherActor(t *testing.T) {
+	remoteNode := rebootMarkedNode("remote-node", "boot-2")
+	delete(remoteNode.Annotations, node.OwnershipAnnotationKey)
+	client := fake.NewSimpleClientset(remoteNode)
+	tNode := getNode(t, getDrainHelper(client))
+
+	err := tNode.UncordonIfRebooted("remote-node")
+	var conflict *node.OwnershipConflictError
+	h.Assert(t, errors.As(err, &conflict), "Expected an ownership conflict, got %v", err)
+	h.Equals(t, []string{"cordon"}, conflict.Conflicts)
+	k8sNode, err := client.CoreV1().Nodes().Get(context.Background(), "remote-node", metav1.GetOptions{})
+	h.Ok(t, err)
+	h.Assert(t, k8sNode.Spec.Unschedulable, "Node cordoned by another actor should stay cordoned")
+	_, ok := k8sNode.Labels[node.ActionLabelKey]
+	h.Assert(t, !ok, "Action label should have been removed despite the conflict")
+}
+
+func TestUncordonIfRebootedLocalNodeBeforeBootIDUpdate(t *testing.T) {
+	localNode := rebootMarkedNode(nodeName, "boot-1")
+	localNode.Labels[node.ActionLabelTimeKey] = "0"
+	client := fake.NewSimpleClientset(localNode)
+	tNode := getNode(t, getDrainHelper(client))
+
+	// the kubelet has not reported the new boot ID yet, while the uptime of the system shows the reboot
+	err := tNode.UncordonIfRebooted(nodeName)
+	h.Ok(t, err)
+	k8sNode, err := client.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{})
+	h.Ok(t, err)
+	h.Assert(t, !k8sNode.Spec.Unschedulable, "Local node should be uncordoned once its uptime shows the reboot")
+}
+
+func TestUncordonIfRebootedRemoteNodeWithoutBootID(t *testing.T) {
+	remoteNode := rebootMarkedNode("remote-node", "boot-2")
+	remoteNode.Labels[node.ActionLabelTimeKey] = "0"
+	delete(remoteNode.Annotations, node.BootIDAnnotationKey)
+	client := fake.NewSimpleClientset(remoteNode)
+	tNode := getNode(t, getDrainHelper(client))
+
+	// the uptime of the system NTH runs on tells nothing about a remote node
+	err := tNode.UncordonIfRebooted("remote-node")
+	h.Ok(t, err)
+	k8sNode, err := client.CoreV1().Nodes().Get(context.Background(), "remote-node", metav1.GetOptions{})
+	h.Ok(t, err)
+	h.Assert(t, k8sNode.Spec.Unschedulable, "Node without a boot ID annotation should stay cordoned")
+}
+
+func TestUncordonRebootedNodes(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		rebootMarkedNode("rebooted-node", "boot-2"),
+		rebootMarkedNode("pending-node", "boot-1"),
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "cordoned-node"}, Spec: v1.NodeSpec{Unschedulable: true}},
+	)
+	tNode := getNode(t, getDrainHelper(client))
+	err := tNode.UncordonRebootedNodes(context.Background())
+	h.Ok(t, err)
+
+	for name, unschedulable := range map[string]bool{"rebooted-node": false, "pending-node": true, "cordoned-node": true} {
+		k8sNode, err := client.CoreV1().Nodes().Get(context.Background(), name, metav1.GetOptions{})
+		h.Ok(t, err)
+		h.Equals(t, unschedulable, k8sNode.Spec.Unschedulable)
+	}
+}
+
 func TestFetchKubernetesNodeInstanceIds(t *testing.T) {
 	client := fake.NewSimpleClientset(
 		&v1.Node{
@@ -0,0 +1,254 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// OwnershipAnnotationKey is a k8s annotation key whose value records the changes NTH made to the node
+// and the state they replaced, so that only those changes are reverted
+const OwnershipAnnotationKey = "aws-node-termination-handler/ownership"
+
+// ownedValue is a label or annotation value set by NTH, along with the value it replaced, or nil if NTH added the key
+type ownedValue struct {
+	Value    string  `json:"value"`
+	Previous *string `json:"previous,omitempty"`
+}
+
+// ownership records the changes NTH made to a node
+type ownership struct {
+	// Cordoned is true if NTH cordoned the node, which was schedulable before
+	Cordoned    bool                  `json:"cordoned,omitempty"`
+	Taints      []corev1.Taint        `json:"taints,omitempty"`
+	Labels      map[string]ownedValue `json:"labels,omitempty"`
+	Annotations map[string]ownedValue `json:"annotations,omitempty"`
+}
+
+// OwnershipConflictError is returned when NTH leaves some of its changes to a node in place
+// because another actor changed them since, e.g. the node was cordoned by someone else
+type OwnershipConflictError struct {
+	NodeName  string
+	Conflicts []string
+}
+
+func (e *OwnershipConflictError) Error() string {
+	return fmt.Sprintf("left %s of node %s in place as NTH does not own the current state", strings.Join(e.Conflicts, ", "), e.NodeName)
+}
+
+// conflictError returns an OwnershipConflictError for the conflicts, or nil if there are none
+func conflictError(nodeName string, conflicts []string) error {
+	if len(conflicts) == 0 {
+		return nil
+	}
+	return &OwnershipConflictError{NodeName: nodeName, Conflicts: conflicts}
+}
+
+// updateOwned calls update with the latest version of the node and the record of the changes NTH made to it,
+// then saves both if update changed them, retrying on conflicts
+func (n Node) updateOwned(k8sNode *corev1.Node, update func(node *corev1.Node, record *ownership) bool) error {
+	client := n.drainHelper.Client
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		freshNode, err := client.CoreV1().Nodes().Get(context.TODO(), k8sNode.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		record := readOwnership(freshNode)
+		if !update(freshNode, &record) {
+			return nil
+		}
+		if err := writeOwnership(freshNode, record); err != nil {
+			return err
+		}
+		_, err = client.CoreV1().Nodes().Update(context.TODO(), freshNode, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// revertOwnedTaints removes the taints NTH added to the node, and returns the owned taints which another actor changed since
+func (n Node) revertOwnedTaints(nodeName string) ([]string, error) {
+	k8sNode, err := n.fetchKubernetesNode(nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch kubernetes node from API: %w", err)
+	}
+	if _, ok := k8sNode.Annotations[OwnershipAnnotationKey]; !ok {
+		return nil, nil
+	}
+	if n.nthConfig.DryRun {
+		log.Info().Msgf("Would have removed the taints NTH added to node %s, but dry-run flag was set", nodeName)
+		return nil, nil
+	}
+	var conflicts []string
+	err = n.updateOwned(k8sNode, func(node *corev1.Node, record *ownership) bool {
+		if len(record.Taints) == 0 {
+			return false
+		}
+		conflicts = nil
+		for _, owned := range record.Taints {
+			i := slices.IndexFunc(node.Spec.Taints, func(taint corev1.Taint) bool { return taint.Key == owned.Key })
+			switch {
+			case i < 0:
+			case node.Spec.Taints[i].Value != owned.Value || node.Spec.Taints[i].Effect != owned.Effect:
+				conflicts = append(conflicts, "taint "+owned.Key)
+			default:
+				log.Info().Interface("taint", owned).Str("node_name", nodeName).Msg("Releasing taint on node")
+				node.Spec.Taints = slices.Delete(node.Spec.Taints, i, i+1)
+			}
+		}
+		record.Taints = nil
+		return true
+	})
+	return conflicts, err
+}
+
+// revertOwnedLabels restores the labels and annotations NTH set on the node to the values they had before,
+// and returns the owned labels and annotations which another actor changed since
+func (n Node) revertOwnedLabels(nodeName string) ([]string, error) {
+	k8sNode, err := n.fetchKubernetesNode(nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch kubernetes node from API: %w", err)
+	}
+	if _, ok := k8sNode.Annotations[OwnershipAnnotationKey]; !ok {
+		return nil, nil
+	}
+	if n.nthConfig.DryRun {
+		log.Info().Msgf("Would have reverted the labels and annotations NTH set on node %s, but dry-run flag was set", nodeName)
+		return nil, nil
+	}
+	var conflicts []string
+	err = n.updateOwned(k8sNode, func(node *corev1.Node, record *ownership) bool {
+		if len(record.Labels) == 0 && len(record.Annotations) == 0 {
+			return false
+		}
+		var labelConflicts, annotationConflicts []string
+		node.Labels, labelConflicts = restoreOwned(node.Labels, record.Labels)
+		node.Annotations, annotationConflicts = restoreOwned(node.Annotations, record.Annotations)
+		conflicts = nil
+		for _, key := range labelConflicts {
+			conflicts = append(conflicts, "label "+key)
+		}
+		for _, key := range annotationConflicts {
+			conflicts = append(conflicts, "annotation "+key)
+		}
+		record.Labels, record.Annotations = nil, nil
+		return true
+	})
+	return conflicts, err
+}
+
+// readOwnership returns the record of the changes NTH made to the node, or an empty record if it is unreadable
+func readOwnership(node *corev1.Node) ownership {
+	var record ownership
+	if value, ok := node.Annotations[OwnershipAnnotationKey]; ok {
+		if err := json.Unmarshal([]byte(value), &record); err != nil {
+			log.Warn().Err(err).Str("node_name", node.Name).Msgf("Ignoring unreadable %s annotation", OwnershipAnnotationKey)
+			return ownership{}
+		}
+	}
+	return record
+}
+
+// writeOwnership saves the record on the node, or removes it if NTH owns no change left
+func writeOwnership(node *corev1.Node, record ownership) error {
+	if !record.Cordoned && len(record.Taints) == 0 && len(record.Labels) == 0 && len(record.Annotations) == 0 {
+		delete(node.Annotations, OwnershipAnnotationKey)
+		return nil
+	}
+	value, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("an error occurred while marshalling the ownership record of the node: %w", err)
+	}
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+	node.Annotations[OwnershipAnnotationKey] = string(value)
+	return nil
+}
+
+// recordTaint records that NTH added the taint to the node
+func recordTaint(node *corev1.Node, taint corev1.Taint) error {
+	record := readOwnership(node)
+	record.Taints = append(record.Taints, taint)
+	return writeOwnership(node, record)
+}
+
+// setOwned sets the values on current, recording each value along with the value it replaced the first time the key is set.
+// A key which already has the value is left alone.
+func setOwned(current map[string]string, values map[string]string, owned *map[string]ownedValue) map[string]string {
+	if current == nil {
+		current = map[string]string{}
+	}
+	for key, value := range values {
+		old, existed := current[key]
+		entry, recorded := (*owned)[key]
+		if !recorded {
+			if existed && old == value {
+				continue
+			}
+			if existed {
+				entry.Previous = &old
+			}
+		}
+		if *owned == nil {
+			*owned = map[string]ownedValue{}
+		}
+		entry.Value = value
+		(*owned)[key] = entry
+		current[key] = value
+	}
+	return current
+}
+
+// restoreOwned sets each owned key of current back to its previous value, or removes it if NTH added it,
+// and returns the keys left in place because their value is no longer the one NTH set
+func restoreOwned(current map[string]string, owned map[string]ownedValue) (map[string]string, []string) {
+	if current == nil {
+		current = map[string]string{}
+	}
+	var conflicts []string
+	for key, entry := range owned {
+		value, ok := current[key]
+		switch {
+		case !ok:
+		case value != entry.Value:
+			conflicts = append(conflicts, key)
+		case entry.Previous == nil:
+			delete(current, key)
+		default:
+			current[key] = *entry.Previous
+		}
+	}
+	slices.Sort(conflicts)
+	return current, conflicts
+}
+
+// collectConflicts appends the conflicts of err to conflicts and returns true if err is an OwnershipConflictError
+func collectConflicts(err error, conflicts *[]string) bool {
+	var conflict *OwnershipConflictError
+	if !errors.As(err, &conflict) {
+		return false
+	}
+	*conflicts = append(*conflicts, conflict.Conflicts...)
+	return true
+}
@@ -0,0 +1,145 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var nthLabels = map[string]string{
+	EventIDLabelKey:    "event-1",
+	ActionLabelKey:     UncordonAfterRebootLabelVal,
+	ActionLabelTimeKey: "1",
+}
+
+func getOwnershipTestNode(t *testing.T, client *fake.Clientset, nthConfig config.Config) *Node {
+	tNode, err := NewWithValues(nthConfig, getTestDrainHelper(client), nil)
+	h.Ok(t, err)
+	return tNode
+}
+
+func getK8sNode(t *testing.T, client *fake.Clientset) *v1.Node {
+	k8sNode, err := client.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{})
+	h.Ok(t, err)
+	return k8sNode
+}
+
+func assertConflicts(t *testing.T, err error, expected ...string) {
+	var conflict *OwnershipConflictError
+	h.Assert(t, errors.As(err, &conflict), "Expected an ownership conflict, got %v", err)
+	h.Equals(t, nodeName, conflict.NodeName)
+	h.Equals(t, expected, conflict.Conflicts)
+}
+
+func TestCordonAndUncordonOwned(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}})
+	tNode := getOwnershipTestNode(t, client, config.Config{})
+
+	h.Ok(t, tNode.Cordon(nodeName, "cordon"))
+	k8sNode := getK8sNode(t, client)
+	h.Assert(t, k8sNode.Spec.Unschedulable, "Node should be cordoned")
+	h.Equals(t, true, readOwnership(k8sNode).Cordoned)
+
+	h.Ok(t, tNode.Uncordon(nodeName))
+	k8sNode = getK8sNode(t, client)
+	h.Assert(t, !k8sNode.Spec.Unschedulable, "Node cordoned by NTH should be uncordoned")
+	_, recorded := k8sNode.Annotations[OwnershipAnnotationKey]
+	h.Assert(t, !recorded, "Ownership record should be removed once NTH owns no change")
+}
+
+func TestUncordonLeavesNodeCordonedByAnotherActor(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+		Spec:       v1.NodeSpec{Unschedulable: true},
+	})
+	tNode := getOwnershipTestNode(t, client, config.Config{})
+
+	h.Ok(t, tNode.Cordon(nodeName, "cordon"))
+	h.Equals(t, false, readOwnership(getK8sNode(t, client)).Cordoned)
+
+	assertConflicts(t, tNode.Uncordon(nodeName), "cordon")
+	h.Assert(t, getK8sNode(t, client).Spec.Unschedulable, "Node cordoned by another actor should be left cordoned")
+}
+
+func TestRemoveNTHTaintsLeavesChangedTaint(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+		Spec:       v1.NodeSpec{Taints: []v1.Taint{{Key: ScheduledMaintenanceTaint, Value: "other", Effect: v1.TaintEffectNoSchedule}}},
+	})
+	tNode := getOwnershipTestNode(t, client, config.Config{TaintNode: true, TaintEffect: "NoSchedule"})
+
+	h.Ok(t, tNode.TaintSpotItn(nodeName, "event-1"))
+	h.Ok(t, tNode.TaintRebalanceRecommendation(nodeName, "event-1"))
+	h.Ok(t, tNode.TaintScheduledMaintenance(nodeName, "event-1"))
+	k8sNode := getK8sNode(t, client)
+	h.Equals(t, 3, len(k8sNode.Spec.Taints))
+	// another actor takes over the spot interruption taint
+	k8sNode.Spec.Taints[1].Value = "other"
+	_, err := client.CoreV1().Nodes().Update(context.Background(), k8sNode, metav1.UpdateOptions{})
+	h.Ok(t, err)
+
+	assertConflicts(t, tNode.RemoveNTHTaints(nodeName), "taint "+SpotInterruptionTaint)
+	h.Equals(t, []v1.Taint{
+		{Key: ScheduledMaintenanceTaint, Value: "other", Effect: v1.TaintEffectNoSchedule},
+		{Key: SpotInterruptionTaint, Value: "other", Effect: v1.TaintEffectNoSchedule},
+	}, getK8sNode(t, client).Spec.Taints)
+}
+
+func TestRemoveNTHLabelsExclusionFromLoadBalancers(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName, Labels: nthLabels}})
+	tNode := getOwnershipTestNode(t, client, config.Config{ExcludeFromLoadBalancers: true})
+
+	h.Ok(t, tNode.MaybeMarkForExclusionFromLoadBalancers(nodeName))
+	h.Equals(t, ExcludeFromLoadBalancersLabelValue, getK8sNode(t, client).Labels[ExcludeFromLoadBalancersLabelKey])
+
+	h.Ok(t, tNode.RemoveNTHLabels(nodeName))
+	k8sNode := getK8sNode(t, client)
+	h.Equals(t, 0, len(k8sNode.Labels))
+	h.Equals(t, 0, len(k8sNode.Annotations))
+}
+
+func TestRemoveNTHLabelsLeavesExclusionFromLoadBalancersOfAnotherActor(t *testing.T) {
+	labels := map[string]string{ExcludeFromLoadBalancersLabelKey: "true"}
+	for key, value := range nthLabels {
+		labels[key] = value
+	}
+	client := fake.NewSimpleClientset(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName, Labels: labels}})
+	tNode := getOwnershipTestNode(t, client, config.Config{ExcludeFromLoadBalancers: true})
+
+	h.Ok(t, tNode.MaybeMarkForExclusionFromLoadBalancers(nodeName))
+	h.Ok(t, tNode.RemoveNTHLabels(nodeName))
+	h.Equals(t, map[string]string{ExcludeFromLoadBalancersLabelKey: "true"}, getK8sNode(t, client).Labels)
+}
+
+func TestRemoveNTHLabelsLeavesChangedLabel(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName, Labels: nthLabels}})
+	tNode := getOwnershipTestNode(t, client, config.Config{ExcludeFromLoadBalancers: true})
+
+	h.Ok(t, tNode.MaybeMarkForExclusionFromLoadBalancers(nodeName))
+	k8sNode := getK8sNode(t, client)
+	k8sNode.Labels[ExcludeFromLoadBalancersLabelKey] = "true"
+	_, err := client.CoreV1().Nodes().Update(context.Background(), k8sNode, metav1.UpdateOptions{})
+	h.Ok(t, err)
+
+	assertConflicts(t, tNode.RemoveNTHLabels(nodeName), "label "+ExcludeFromLoadBalancersLabelKey)
+	h.Equals(t, map[string]string{ExcludeFromLoadBalancersLabelKey: "true"}, getK8sNode(t, client).Labels)
+}
@@ -0,0 +1,206 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/kubernetes"
+)
+
+const hostnameLabelKey = "kubernetes.io/hostname"
+
+// Instance is an EC2 instance whose Kubernetes node is resolved, any of its fields may be empty
+type Instance struct {
+	InstanceID     string
+	ProviderID     string
+	PrivateDNSName string
+	Tags           map[string]string
+}
+
+// NodeResolver finds the Kubernetes node of an EC2 instance by trying its strategies in order,
+// with the informer cache once it has synced, or else with the API server
+type NodeResolver struct {
+	client          kubernetes.Interface
+	cache           *Cache
+	strategies      []string
+	dnsSuffix       string
+	instanceIDLabel string
+	tag             string
+}
+
+// NewNodeResolver returns a node resolver with the strategies set by the NTH config
+func NewNodeResolver(nthConfig config.Config, client kubernetes.Interface) (*NodeResolver, error) {
+	strategies, err := nthConfig.NodeResolutionStrategies()
+	if err != nil {
+		return nil, fmt.Errorf("invalid node resolution: %w", err)
+	}
+	return &NodeResolver{
+		client:          client,
+		strategies:      strategies,
+		dnsSuffix:       strings.TrimPrefix(nthConfig.NodeResolutionDNSSuffix, "."),
+		instanceIDLabel: nthConfig.NodeResolutionInstanceIDLabel,
+		tag:             nthConfig.NodeResolutionTag,
+	}, nil
+}
+
+// Resolve returns the name of the node of the instance, or a NotFound error if no strategy finds it
+func (r NodeResolver) Resolve(ctx context.Context, instance Instance) (string, error) {
+	node, err := r.ResolveNode(ctx, instance)
+	if err != nil {
+		return "", err
+	}
+	return node.Name, nil
+}
+
+// ResolveNode returns the node of the instance found by the first strategy which finds one, or a NotFound error if none does
+func (r NodeResolver) ResolveNode(ctx context.Context, instance Instance) (*corev1.Node, error) {
+	for _, strategy := range r.strategies {
+		node, err := r.resolve(ctx, strategy, instance)
+		if err != nil {
+			return nil, fmt.Errorf("resolving the node of instance %s with the %s strategy: %w", instance.InstanceID, strategy, err)
+		}
+		if node != nil {
+			log.Debug().Str("instance_id", instance.InstanceID).Str("node_name", node.Name).Str("strategy", strategy).Msg("Resolved the node of the instance")
+			return node, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(corev1.Resource("nodes"), instance.InstanceID)
+}
+
+// resolve returns the node of the instance found by the strategy, or nil if it finds none
+func (r NodeResolver) resolve(ctx context.Context, strategy string, instance Instance) (*corev1.Node, error) {
+	switch strategy {
+	case config.NodeResolutionProviderID:
+		return r.byProviderID(ctx, instance)
+	case config.NodeResolutionPrivateDNS:
+		return r.byName(ctx, r.privateDNSName(instance))
+	case config.NodeResolutionInstanceIDLabel:
+		if instance.InstanceID == "" {
+			return nil, nil
+		}
+		return r.byLabel(ctx, instanceIDLabelIndex, r.instanceIDLabel, instance.InstanceID)
+	case config.NodeResolutionTag:
+		return r.byName(ctx, instance.Tags[r.tag])
+	case config.NodeResolutionHostnameLabel:
+		if instance.PrivateDNSName == "" {
+			return nil, nil
+		}
+		return r.byLabel(ctx, hostnameIndex, hostnameLabelKey, instance.PrivateDNSName, strings.Split(instance.PrivateDNSName, ".")[0])
+	}
+	return nil, fmt.Errorf("unknown node resolution strategy %q", strategy)
+}
+
+// byProviderID returns the node whose provider ID names the instance
+func (r NodeResolver) byProviderID(ctx context.Context, instance Instance) (*corev1.Node, error) {
+	instanceID := instance.InstanceID
+	if instanceID == "" {
+		instanceID = instanceIDFromProviderID(instance.ProviderID)
+	}
+	if instanceID == "" {
+		return nil, nil
+	}
+	if r.cache.Synced() {
+		nodes, err := r.cache.nodesByIndex(providerIDIndex, instanceID)
+		if err != nil || len(nodes) == 0 {
+			return nil, err
+		}
+		return nodes[0], nil
+	}
+	nodes, err := r.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range nodes.Items {
+		if instanceIDFromProviderID(nodes.Items[i].Spec.ProviderID) == instanceID {
+			return &nodes.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// byName returns the node with the given name, if any
+func (r NodeResolver) byName(ctx context.Context, name string) (*corev1.Node, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if r.cache.Synced() {
+		node, _ := r.cache.Node(name)
+		return node, nil
+	}
+	node, err := r.client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return node, err
+}
+
+// byLabel returns the node whose label has one of the values, if any, looking it up in the given index of the informer cache if it has synced
+func (r NodeResolver) byLabel(ctx context.Context, index string, key string, values ...string) (*corev1.Node, error) {
+	var nodes []*corev1.Node
+	if r.cache.Synced() {
+		var err error
+		if nodes, err = r.cache.nodesByIndex(index, values...); err != nil {
+			return nil, err
+		}
+	} else {
+		requirement, err := labels.NewRequirement(key, selection.In, values)
+		if err != nil {
+			// a value which is not a valid label value matches no node
+			log.Debug().Err(err).Str("label", key).Msg("Unable to select nodes by label")
+			return nil, nil
+		}
+		nodeList, err := r.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: labels.NewSelector().Add(*requirement).String()})
+		if err != nil {
+			return nil, err
+		}
+		for i := range nodeList.Items {
+			nodes = append(nodes, &nodeList.Items[i])
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+	if len(nodes) > 1 {
+		log.Warn().Str("label", key).Strs("values", values).Int("nodes", len(nodes)).Msgf("Several nodes match, resolving to %s", nodes[0].Name)
+	}
+	return nodes[0], nil
+}
+
+// privateDNSName returns the private DNS name of the instance, with the domain of the resolver if it has one
+func (r NodeResolver) privateDNSName(instance Instance) string {
+	if r.dnsSuffix == "" || instance.PrivateDNSName == "" {
+		return instance.PrivateDNSName
+	}
+	return strings.Split(instance.PrivateDNSName, ".")[0] + "." + r.dnsSuffix
+}
+
+// instanceIDFromProviderID returns the instance ID of a provider ID such as aws:///us-west-2a/i-0abcd1234efgh5678,
+// or an empty string if it names no instance
+func instanceIDFromProviderID(providerID string) string {
+	parts := strings.Split(providerID, "/")
+	if instanceID := parts[len(parts)-1]; instanceIDRegex.MatchString(instanceID) {
+		return instanceID
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func getTestNodeResolver(t *testing.T, nthConfig config.Config, nodes ...*v1.Node) *NodeResolver {
+	client := fake.NewSimpleClientset()
+	for _, node := range nodes {
+		_, err := client.CoreV1().Nodes().Create(context.Background(), node, metav1.CreateOptions{})
+		h.Ok(t, err)
+	}
+	resolver, err := NewNodeResolver(nthConfig, client)
+	h.Ok(t, err)
+	return resolver
+}
+
+func TestResolveByProviderID(t *testing.T) {
+	resolver := getTestNodeResolver(t, config.Config{NodeResolution: config.NodeResolutionProviderID},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "longer"}, Spec: v1.NodeSpec{ProviderID: "aws:///us-west-2a/i-0abcd1234efgh56789"}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "exact"}, Spec: v1.NodeSpec{ProviderID: "aws:///us-west-2a/i-0abcd1234efgh5678"}},
+	)
+
+	nodeName, err := resolver.Resolve(context.Background(), Instance{InstanceID: "i-0abcd1234efgh5678"})
+	h.Ok(t, err)
+	h.Equals(t, "exact", nodeName)
+
+	nodeName, err = resolver.Resolve(context.Background(), Instance{ProviderID: "aws:///us-west-2a/i-0abcd1234efgh56789"})
+	h.Ok(t, err)
+	h.Equals(t, "longer", nodeName)
+}
+
+func TestResolveByPrivateDNSWithSuffix(t *testing.T) {
+	resolver := getTestNodeResolver(t, config.Config{NodeResolution: config.NodeResolutionPrivateDNS, NodeResolutionDNSSuffix: ".example.internal"},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "ip-10-0-0-1.example.internal"}},
+	)
+
+	nodeName, err := resolver.Resolve(context.Background(), Instance{PrivateDNSName: "ip-10-0-0-1.us-west-2.compute.internal"})
+	h.Ok(t, err)
+	h.Equals(t, "ip-10-0-0-1.example.internal", nodeName)
+}
+
+func TestResolveByInstanceIDLabel(t *testing.T) {
+	resolver := getTestNodeResolver(t, config.Config{NodeResolution: config.NodeResolutionInstanceIDLabel, NodeResolutionInstanceIDLabel: "example.com/instance-id"},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1", Labels: map[string]string{"example.com/instance-id": "i-1234"}}},
+	)
+
+	nodeName, err := resolver.Resolve(context.Background(), Instance{InstanceID: "i-1234"})
+	h.Ok(t, err)
+	h.Equals(t, "worker-1", nodeName)
+}
+
+func TestResolveByTag(t *testing.T) {
+	resolver := getTestNodeResolver(t, config.Config{NodeResolution: config.NodeResolutionTag, NodeResolutionTag: "Name"},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}},
+	)
+
+	nodeName, err := resolver.Resolve(context.Background(), Instance{Tags: map[string]string{"Name": "worker-1"}})
+	h.Ok(t, err)
+	h.Equals(t, "worker-1", nodeName)
+}
+
+func TestResolveByHostnameLabel(t *testing.T) {
+	resolver := getTestNodeResolver(t, config.Config{NodeResolution: config.NodeResolutionHostnameLabel},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1", Labels: map[string]string{hostnameLabelKey: "ip-10-0-0-1"}}},
+	)
+
+	nodeName, err := resolver.Resolve(context.Background(), Instance{PrivateDNSName: "ip-10-0-0-1.us-west-2.compute.internal"})
+	h.Ok(t, err)
+	h.Equals(t, "worker-1", nodeName)
+}
+
+func TestResolveTriesStrategiesInOrder(t *testing.T) {
+	nodes := []*v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "ip-10-0-0-1.us-west-2.compute.internal"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}, Spec: v1.NodeSpec{ProviderID: "aws:///us-west-2a/i-1234"}},
+	}
+	instance := Instance{InstanceID: "i-1234", PrivateDNSName: "ip-10-0-0-1.us-west-2.compute.internal"}
+
+	resolver := getTestNodeResolver(t, config.Config{}, nodes...)
+	nodeName, err := resolver.Resolve(context.Background(), instance)
+	h.Ok(t, err)
+	h.Equals(t, "ip-10-0-0-1.us-west-2.compute.internal", nodeName)
+
+	resolver = getTestNodeResolver(t, config.Config{UseProviderId: true}, nodes...)
+	nodeName, err = resolver.Resolve(context.Background(), instance)
+	h.Ok(t, err)
+	h.Equals(t, "worker-1", nodeName)
+}
+
+func TestResolveNotFound(t *testing.T) {
+	resolver := getTestNodeResolver(t, config.Config{},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}, Spec: v1.NodeSpec{ProviderID: "aws:///us-west-2a/i-1234"}},
+	)
+
+	_, err := resolver.Resolve(context.Background(), Instance{InstanceID: "i-5678", PrivateDNSName: "ip-10-0-0-2.us-west-2.compute.internal"})
+	h.Assert(t, apierrors.IsNotFound(err), "Failed to return NotFound when no strategy finds the node")
+}
@@ -0,0 +1,62 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TerminationAnnotationKey is a node annotation whose JSON value describes the interruption event the node is processed for,
+// so that other controllers can tell when the node goes away without subscribing to NTH webhooks
+const TerminationAnnotationKey = "aws-node-termination-handler/termination"
+
+// TerminationStatus describes an interruption event of a node and how far NTH is with it
+type TerminationStatus struct {
+	EventID string `json:"eventId"`
+	Kind    string `json:"kind"`
+	Monitor string `json:"monitor"`
+	// TerminationTime is the time the interruption is expected to happen at, if the event tells
+	TerminationTime *metav1.Time `json:"terminationTime,omitempty"`
+	// Deadline is the time the node must be drained by, if the interruption has one
+	Deadline *metav1.Time `json:"deadline,omitempty"`
+	// Phase is the lifecycle state of the event, e.g. Scheduled, Draining or Completed
+	Phase              string      `json:"phase"`
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+	// Message is the error of the last transition, if any
+	Message string `json:"message,omitempty"`
+}
+
+// AnnotateTermination sets the termination annotation of the node to the status, or removes it if status is nil
+func (n Node) AnnotateTermination(nodeName string, status *TerminationStatus) error {
+	if !n.nthConfig.NodeTerminationAnnotation {
+		return nil
+	}
+	if status == nil {
+		if err := n.removeAnnotation(nodeName, TerminationAnnotationKey); err != nil {
+			return fmt.Errorf("unable to remove %s from node: %w", TerminationAnnotationKey, err)
+		}
+		return nil
+	}
+	value, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("an error occurred while marshalling the termination status of the node: %w", err)
+	}
+	if err := n.addAnnotation(nodeName, TerminationAnnotationKey, string(value)); err != nil {
+		return fmt.Errorf("unable to annotate node with its termination status: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// volumeDetachmentPollInterval is the interval between two checks of the volumes attached to a node
+var volumeDetachmentPollInterval = 5 * time.Second
+
+// WaitForVolumeDetachment polls the VolumeAttachments of the node, and the volumes attached in its status if configured,
+// until every volume but those of its DaemonSet pods is detached, and returns an error if some are still attached once the timeout expires
+func (n Node) WaitForVolumeDetachment(ctx context.Context, nodeName string, timeout time.Duration) error {
+	if n.nthConfig.DryRun {
+		log.Info().Str("node_name", nodeName).Msg("Would have waited for the volumes of the node to be detached, but dry-run flag was set")
+		return nil
+	}
+	node, err := n.fetchKubernetesNode(nodeName)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	kept, err := n.daemonSetVolumes(ctx, node.Name)
+	if err != nil {
+		log.Warn().Err(err).Str("node_name", nodeName).Msg("Unable to list the volumes of the DaemonSet pods of the node, waiting for all volumes to be detached")
+	}
+	start := time.Now()
+	for {
+		attached, err := n.attachedVolumes(ctx, node.Name, kept)
+		if err != nil {
+			log.Warn().Err(err).Str("node_name", nodeName).Msg("Unable to check the volumes attached to the node")
+		} else if len(attached) == 0 {
+			log.Info().Str("node_name", nodeName).Dur("duration", time.Since(start)).Msg("Volumes of the node are detached")
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("volumes still attached to node %s after %s: %s", nodeName, timeout, strings.Join(attached, ", "))
+		case <-time.After(volumeDetachmentPollInterval):
+		}
+	}
+}
+
+// attachedVolumes returns the volumes attached to the node, other than the kept ones, as reported by its VolumeAttachments
+// and by its status if configured. Both are read from the informer cache once it has synced.
+func (n Node) attachedVolumes(ctx context.Context, nodeName string, kept map[string]bool) ([]string, error) {
+	attachments, err := n.volumeAttachments(ctx, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	var attached []string
+	for _, attachment := range attachments {
+		volume := attachment.Spec.Source.PersistentVolumeName
+		if attachment.Spec.NodeName != nodeName || !attachment.Status.Attached || (volume != nil && kept[*volume]) {
+			continue
+		}
+		attached = append(attached, attachment.Name)
+	}
+	if !n.nthConfig.VolumeDetachmentCheckNodeStatus {
+		return attached, nil
+	}
+	node, ok := n.cache.Node(nodeName)
+	if !ok {
+		node, err = n.drainHelper.Client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("get node: %w", err)
+		}
+	}
+	for _, volume := range node.Status.VolumesAttached {
+		if !isKeptVolume(string(volume.Name), kept) {
+			attached = append(attached, string(volume.Name))
+		}
+	}
+	return attached, nil
+}
+
+// volumeAttachments returns the volume attachments of the node from the informer cache,
+// or all the volume attachments of the cluster from the API server if they are not cached
+func (n Node) volumeAttachments(ctx context.Context, nodeName string) ([]*storagev1.VolumeAttachment, error) {
+	if attachments, ok := n.cache.volumeAttachmentsOnNode(nodeName); ok {
+		return attachments, nil
+	}
+	attachmentList, err := n.drainHelper.Client.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("list volume attachments: %w", err)
+	}
+	attachments := make([]*storagev1.VolumeAttachment, 0, len(attachmentList.Items))
+	for i := range attachmentList.Items {
+		attachments = append(attachments, &attachmentList.Items[i])
+	}
+	return attachments, nil
+}
+
+// daemonSetVolumes returns the names and the CSI volume handles of the persistent volumes of the DaemonSet pods of the node,
+// which stay attached since DaemonSet pods are not drained
+func (n Node) daemonSetVolumes(ctx context.Context, nodeName string) (map[string]bool, error) {
+	kept := map[string]bool{}
+	pods, err := n.fetchAllPods(nodeName)
+	if err != nil {
+		return kept, err
+	}
+	for _, pod := range pods.Items {
+		if !isDaemonSetPod(pod) {
+			continue
+		}
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim == nil {
+				continue
+			}
+			claim, err := n.drainHelper.Client.CoreV1().PersistentVolumeClaims(pod.Namespace).Get(ctx, volume.PersistentVolumeClaim.ClaimName, metav1.GetOptions{})
+			if err != nil {
+				return kept, err
+			}
+			if claim.Spec.VolumeName == "" {
+				continue
+			}
+			kept[claim.Spec.VolumeName] = true
+			pv, err := n.drainHelper.Client.CoreV1().PersistentVolumes().Get(ctx, claim.Spec.VolumeName, metav1.GetOptions{})
+			if err != nil {
+				return kept, err
+			}
+			if pv.Spec.CSI != nil {
+				kept[pv.Spec.CSI.VolumeHandle] = true
+			}
+		}
+	}
+	return kept, nil
+}
+
+// isKeptVolume returns true if a volume attached in the status of a node, named after its plugin and its volume handle
+// such as kubernetes.io/csi/ebs.csi.aws.com^vol-0123, is one of the kept volumes
+func isKeptVolume(uniqueName string, kept map[string]bool) bool {
+	handle := uniqueName[strings.LastIndexAny(uniqueName, "^/")+1:]
+	return kept[handle]
+}
@@ -0,0 +1,78 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func volumeAttachment(name, pvName string) *storagev1.VolumeAttachment {
+	return &storagev1.VolumeAttachment{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: storagev1.VolumeAttachmentSpec{
+			Attacher: "ebs.csi.aws.com",
+			NodeName: nodeName,
+			Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: &pvName},
+		},
+		Status: storagev1.VolumeAttachmentStatus{Attached: true},
+	}
+}
+
+func TestWaitForVolumeDetachment(t *testing.T) {
+	volumeDetachmentPollInterval = 10 * time.Millisecond
+	logShipper := annotatedPod("log-shipper", nil)
+	logShipper.OwnerReferences[0].Kind = daemonSet
+	logShipper.Spec.Volumes = []v1.Volume{{Name: "buffer", VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "buffer"}}}}
+	client := fake.NewSimpleClientset(
+		&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+			Status:     v1.NodeStatus{VolumesAttached: []v1.AttachedVolume{{Name: "kubernetes.io/csi/ebs.csi.aws.com^vol-buffer"}}},
+		},
+		logShipper,
+		&v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "buffer", Namespace: "default"}, Spec: v1.PersistentVolumeClaimSpec{VolumeName: "buffer-pv"}},
+		&v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "buffer-pv"}, Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{CSI: &v1.CSIPersistentVolumeSource{Driver: "ebs.csi.aws.com", VolumeHandle: "vol-buffer"}}}},
+		volumeAttachment("buffer-attachment", "buffer-pv"),
+		volumeAttachment("db-attachment", "db-pv"),
+	)
+	tNode, err := NewWithValues(config.Config{VolumeDetachmentCheckNodeStatus: true}, getTestDrainHelper(client), nil)
+	h.Ok(t, err)
+
+	// the volume of the evicted database is still attached
+	err = tNode.WaitForVolumeDetachment(context.Background(), nodeName, 50*time.Millisecond)
+	h.Assert(t, err != nil, "Expected an error while the volume of the database is attached")
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		h.Ok(t, client.StorageV1().VolumeAttachments().Delete(context.Background(), "db-attachment", metav1.DeleteOptions{}))
+	}()
+	// the volume of the DaemonSet pod stays attached
+	err = tNode.WaitForVolumeDetachment(context.Background(), nodeName, time.Second)
+	h.Ok(t, err)
+}
+
+func TestIsKeptVolume(t *testing.T) {
+	kept := map[string]bool{"vol-0123": true}
+	h.Equals(t, true, isKeptVolume("kubernetes.io/csi/ebs.csi.aws.com^vol-0123", kept))
+	h.Equals(t, false, isKeptVolume("kubernetes.io/csi/ebs.csi.aws.com^vol-4567", kept))
+	h.Equals(t, true, isKeptVolume("kubernetes.io/aws-ebs/vol-0123", kept))
+}
@@ -0,0 +1,119 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// criticalPriorityClasses are the priority classes of the pods the workload wave order evicts after the others
+var criticalPriorityClasses = map[string]bool{
+	"system-cluster-critical": true,
+	"system-node-critical":    true,
+}
+
+// Pod classes of the workload wave order, in eviction order
+const (
+	stateless = iota
+	stateful
+	critical
+	optedInDaemonSet
+	// evictLast pods are evicted after all the others, whatever the wave order
+	evictLast
+)
+
+// wave identifies a drain wave, waves are evicted by ascending class then rank
+type wave struct {
+	class int
+	rank  int64
+}
+
+func (w wave) before(other wave) bool {
+	if w.class != other.class {
+		return w.class < other.class
+	}
+	return w.rank < other.rank
+}
+
+// drainWaves splits the evictions into waves in the given order. The waves are evicted one after the other.
+// Pods with the evict-last annotation make up the last wave, even if the order is none.
+func drainWaves(evictions []podEviction, order string) [][]podEviction {
+	byWave := make(map[wave][]podEviction)
+	for _, eviction := range evictions {
+		w := podWave(eviction, order)
+		byWave[w] = append(byWave[w], eviction)
+	}
+	waves := make([]wave, 0, len(byWave))
+	for w := range byWave {
+		waves = append(waves, w)
+	}
+	sort.Slice(waves, func(i, j int) bool { return waves[i].before(waves[j]) })
+	result := make([][]podEviction, 0, len(waves))
+	for _, w := range waves {
+		result = append(result, byWave[w])
+	}
+	return result
+}
+
+// podWave returns the wave of a pod in the given order
+func podWave(eviction podEviction, order string) wave {
+	pod := eviction.pod
+	switch {
+	case eviction.policy.evictLast:
+		return wave{class: evictLast}
+	case order == config.DrainWaveOrderNone || order == "":
+		return wave{class: stateless}
+	case isDaemonSetPod(pod):
+		return wave{class: optedInDaemonSet, rank: eviction.policy.drainOrder}
+	}
+	switch order {
+	case config.DrainWaveOrderPriority:
+		var priority int64
+		if pod.Spec.Priority != nil {
+			priority = int64(*pod.Spec.Priority)
+		}
+		return wave{rank: priority}
+	case config.DrainWaveOrderAnnotation:
+		return wave{rank: eviction.policy.drainOrder}
+	case config.DrainWaveOrderWorkload:
+		switch {
+		case criticalPriorityClasses[pod.Spec.PriorityClassName]:
+			return wave{class: critical}
+		case isOwnedBy(pod, statefulSet):
+			// the highest ordinal first, as a StatefulSet scales down
+			return wave{class: stateful, rank: -statefulSetOrdinal(pod)}
+		}
+	}
+	return wave{class: stateless}
+}
+
+// statefulSetOrdinal returns the ordinal of a StatefulSet pod, which is the suffix of its name
+func statefulSetOrdinal(pod corev1.Pod) int64 {
+	ordinal, err := strconv.ParseInt(pod.Name[strings.LastIndex(pod.Name, "-")+1:], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return ordinal
+}
+
+// isOptedInDaemonSetPod returns true for a DaemonSet pod which asks to be evicted with the drain-order annotation
+func isOptedInDaemonSetPod(pod corev1.Pod) bool {
+	_, ok := pod.Annotations[DrainOrderAnnotationKey]
+	return ok && isDaemonSetPod(pod)
+}
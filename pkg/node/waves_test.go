@@ -0,0 +1,138 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func wavePod(name, ownerKind string, mutate func(*v1.Pod)) podEviction {
+	pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	if ownerKind != "" {
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: "owner"}}
+	}
+	if mutate != nil {
+		mutate(&pod)
+	}
+	policy, _ := parsePodPolicy(pod)
+	return podEviction{pod: pod, policy: policy}
+}
+
+func waveNames(waves [][]podEviction) [][]string {
+	var names [][]string
+	for _, wave := range waves {
+		var waveNames []string
+		for _, eviction := range wave {
+			waveNames = append(waveNames, eviction.pod.Name)
+		}
+		names = append(names, waveNames)
+	}
+	return names
+}
+
+func withAnnotation(value string) func(*v1.Pod) {
+	return func(pod *v1.Pod) {
+		pod.Annotations = map[string]string{DrainOrderAnnotationKey: value}
+	}
+}
+
+func TestDrainWavesNone(t *testing.T) {
+	evictions := []podEviction{wavePod("web", "ReplicaSet", nil), wavePod("db-0", statefulSet, nil)}
+	h.Equals(t, [][]string{{"web", "db-0"}}, waveNames(drainWaves(evictions, config.DrainWaveOrderNone)))
+}
+
+func TestDrainWavesEvictLast(t *testing.T) {
+	last := func(pod *v1.Pod) { pod.Annotations = map[string]string{EvictLastAnnotationKey: "true"} }
+	evictions := []podEviction{wavePod("cache", "ReplicaSet", last), wavePod("web", "ReplicaSet", nil), wavePod("log-shipper", daemonSet, withAnnotation("0"))}
+	h.Equals(t, [][]string{{"web", "log-shipper"}, {"cache"}}, waveNames(drainWaves(evictions, config.DrainWaveOrderNone)))
+	h.Equals(t, [][]string{{"web"}, {"log-shipper"}, {"cache"}}, waveNames(drainWaves(evictions, config.DrainWaveOrderWorkload)))
+}
+
+func TestDrainWavesWorkload(t *testing.T) {
+	evictions := []podEviction{
+		wavePod("log-shipper-abcde", daemonSet, withAnnotation("0")),
+		wavePod("coredns-abcde", "ReplicaSet", func(pod *v1.Pod) { pod.Spec.PriorityClassName = "system-cluster-critical" }),
+		wavePod("db-0", statefulSet, nil),
+		wavePod("web-abcde", "ReplicaSet", nil),
+		wavePod("db-1", statefulSet, nil),
+		wavePod("bare", "", nil),
+	}
+	h.Equals(t, [][]string{{"web-abcde", "bare"}, {"db-1"}, {"db-0"}, {"coredns-abcde"}, {"log-shipper-abcde"}}, waveNames(drainWaves(evictions, config.DrainWaveOrderWorkload)))
+}
+
+func TestDrainWavesPriority(t *testing.T) {
+	priority := func(value int32) func(*v1.Pod) {
+		return func(pod *v1.Pod) { pod.Spec.Priority = &value }
+	}
+	evictions := []podEviction{
+		wavePod("mesh-proxy", daemonSet, withAnnotation("1")),
+		wavePod("high", "ReplicaSet", priority(1000)),
+		wavePod("default", "ReplicaSet", nil),
+		wavePod("low", "ReplicaSet", priority(-10)),
+	}
+	h.Equals(t, [][]string{{"low"}, {"default"}, {"high"}, {"mesh-proxy"}}, waveNames(drainWaves(evictions, config.DrainWaveOrderPriority)))
+}
+
+func TestDrainWavesAnnotation(t *testing.T) {
+	evictions := []podEviction{
+		wavePod("second", "ReplicaSet", withAnnotation("10")),
+		wavePod("invalid", "ReplicaSet", withAnnotation("last")),
+		wavePod("first", "ReplicaSet", withAnnotation("-5")),
+		wavePod("unannotated", "ReplicaSet", nil),
+	}
+	h.Equals(t, [][]string{{"first"}, {"invalid", "unannotated"}, {"second"}}, waveNames(drainWaves(evictions, config.DrainWaveOrderAnnotation)))
+}
+
+func TestEvictWavesInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var evicted []string
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "pods", evictionReactor(client, nil, func(name string) {
+		mu.Lock()
+		evicted = append(evicted, name)
+		mu.Unlock()
+	}))
+	apps := createPods(t, client, "app-1", "app-2")
+	logShipper := createPods(t, client, "log-shipper")
+
+	results := evictWaves(context.Background(), evictor{client: client, maxParallelism: 10, timeout: time.Second}, [][]podEviction{apps, logShipper}, nodeName, time.Time{}, nil)
+
+	h.Equals(t, 3, len(results))
+	for _, result := range results {
+		h.Equals(t, PodEvicted, result.Outcome)
+	}
+	h.Equals(t, []string{"log-shipper"}, evicted[2:])
+}
+
+func TestEvictWavesClampsGracePeriodsToTheirShare(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "pods", evictionReactor(client, nil, nil))
+	apps := createPods(t, client, "app")
+	logShipper := createPods(t, client, "log-shipper")
+
+	results := evictWaves(context.Background(), evictor{client: client, maxParallelism: 10}, [][]podEviction{apps, logShipper}, nodeName, time.Now().Add(40*time.Second), nil)
+
+	// the first wave gets half of the time left, the second one the rest
+	h.Assert(t, results[0].GracePeriodSeconds <= 15, "Expected the grace period of the first wave to be cut to half of the time left")
+	h.Assert(t, results[1].GracePeriodSeconds > 15 && results[1].GracePeriodSeconds <= 30, "Expected the grace period of the last wave to be cut to the time left")
+}
@@ -0,0 +1,146 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	replicaSet = "ReplicaSet"
+	deployment = "Deployment"
+)
+
+// workloadReadinessPollInterval is the interval between two checks of the workloads of the evicted pods
+var workloadReadinessPollInterval = 5 * time.Second
+
+// workload is a controller which recreates the pods evicted from a node
+type workload struct {
+	namespace string
+	kind      string
+	name      string
+}
+
+func (w workload) String() string {
+	return fmt.Sprintf("%s %s/%s", w.kind, w.namespace, w.name)
+}
+
+// WaitForWorkloadsReady polls the Deployments, StatefulSets and ReplicaSets of the evicted pods until each of them
+// has its desired number of ready replicas, and returns an error naming those which are not ready once the timeout expires.
+// The Deployment of a ReplicaSet is checked rather than the ReplicaSet, since a rollout may replace the ReplicaSet.
+func (n Node) WaitForWorkloadsReady(ctx context.Context, evictions []PodEvictionResult, timeout time.Duration) error {
+	if n.nthConfig.DryRun {
+		log.Info().Msg("Would have waited for the workloads of the evicted pods to be ready, but dry-run flag was set")
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	pending := n.evictedWorkloads(ctx, evictions)
+	start := time.Now()
+	for {
+		for w := range pending {
+			ready, err := n.workloadReady(ctx, w)
+			if err != nil {
+				log.Warn().Err(err).Str("workload", w.String()).Msg("Unable to check the readiness of the workload")
+				continue
+			}
+			if ready {
+				delete(pending, w)
+			}
+		}
+		if len(pending) == 0 {
+			log.Info().Dur("duration", time.Since(start)).Msg("Workloads of the evicted pods are ready")
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			names := make([]string, 0, len(pending))
+			for w := range pending {
+				names = append(names, w.String())
+			}
+			sort.Strings(names)
+			return fmt.Errorf("workloads not ready after %s: %s", timeout, strings.Join(names, ", "))
+		case <-time.After(workloadReadinessPollInterval):
+		}
+	}
+}
+
+// evictedWorkloads returns the workloads of the pods which were drained off the node
+func (n Node) evictedWorkloads(ctx context.Context, evictions []PodEvictionResult) map[workload]bool {
+	workloads := map[workload]bool{}
+	for _, eviction := range evictions {
+		if eviction.Outcome == PodSkipped || !eviction.Succeeded() {
+			continue
+		}
+		w := workload{namespace: eviction.Namespace, kind: eviction.ControllerKind, name: eviction.ControllerName}
+		switch w.kind {
+		case statefulSet, deployment:
+		case replicaSet:
+			rs, err := n.drainHelper.Client.AppsV1().ReplicaSets(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+			if err == nil {
+				if owner := metav1.GetControllerOf(rs); owner != nil && owner.Kind == deployment {
+					w = workload{namespace: w.namespace, kind: deployment, name: owner.Name}
+				}
+			}
+		default:
+			continue
+		}
+		workloads[w] = true
+	}
+	return workloads
+}
+
+// workloadReady returns true if the workload has its desired number of ready replicas, or is gone
+func (n Node) workloadReady(ctx context.Context, w workload) (bool, error) {
+	apps := n.drainHelper.Client.AppsV1()
+	var desired *int32
+	var ready int32
+	var err error
+	switch w.kind {
+	case deployment:
+		d, getErr := apps.Deployments(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			desired, ready = d.Spec.Replicas, d.Status.ReadyReplicas
+		}
+	case statefulSet:
+		s, getErr := apps.StatefulSets(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			desired, ready = s.Spec.Replicas, s.Status.ReadyReplicas
+		}
+	case replicaSet:
+		rs, getErr := apps.ReplicaSets(w.namespace).Get(ctx, w.name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			desired, ready = rs.Spec.Replicas, rs.Status.ReadyReplicas
+		}
+	}
+	if apierrors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if desired == nil {
+		// the API server defaults the replicas to 1
+		return ready >= 1, nil
+	}
+	return ready >= *desired, nil
+}
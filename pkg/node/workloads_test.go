@@ -0,0 +1,73 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWaitForWorkloadsReady(t *testing.T) {
+	workloadReadinessPollInterval = 10 * time.Millisecond
+	replicas := int32(3)
+	controller := true
+	client := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     appsv1.DeploymentStatus{ReadyReplicas: 2},
+		},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name:            "web-abcde",
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{{Kind: deployment, Name: "web", Controller: &controller}},
+		}},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+			Status:     appsv1.StatefulSetStatus{ReadyReplicas: 3},
+		},
+	)
+	tNode, err := NewWithValues(config.Config{}, getTestDrainHelper(client), nil)
+	h.Ok(t, err)
+	evictions := []PodEvictionResult{
+		{Namespace: "default", Name: "web-abcde-1", Outcome: PodEvicted, ControllerKind: replicaSet, ControllerName: "web-abcde"},
+		{Namespace: "default", Name: "db-0", Outcome: PodEvicted, ControllerKind: statefulSet, ControllerName: "db"},
+		{Namespace: "default", Name: "gone", Outcome: PodEvicted, ControllerKind: statefulSet, ControllerName: "deleted"},
+		{Namespace: "default", Name: "log-shipper", Outcome: PodSkipped, ControllerKind: daemonSet, ControllerName: "log-shipper"},
+	}
+
+	// the Deployment of the ReplicaSet is one replica short
+	err = tNode.WaitForWorkloadsReady(context.Background(), evictions, 50*time.Millisecond)
+	h.Assert(t, err != nil, "Expected an error while the Deployment is not ready")
+	h.Equals(t, "workloads not ready after 50ms: Deployment default/web", err.Error())
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		web, err := client.AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+		h.Ok(t, err)
+		web.Status.ReadyReplicas = 3
+		_, err = client.AppsV1().Deployments("default").UpdateStatus(context.Background(), web, metav1.UpdateOptions{})
+		h.Ok(t, err)
+	}()
+	err = tNode.WaitForWorkloadsReady(context.Background(), evictions, time.Second)
+	h.Ok(t, err)
+}
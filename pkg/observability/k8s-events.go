@@ -15,15 +15,18 @@ package observability
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/aws/aws-node-termination-handler/pkg/ec2metadata"
 	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+	"github.com/aws/aws-node-termination-handler/pkg/node"
 	"github.com/rs/zerolog/log"
 	corev1 "k8s.io/api/core/v1"
 	kErr "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -32,34 +35,38 @@ import (
 
 // Kubernetes event types, reasons and messages
 const (
-	Normal                  = corev1.EventTypeNormal
-	Warning                 = corev1.EventTypeWarning
-	MonitorErrReason        = "MonitorError"
-	MonitorErrMsgFmt        = "There was a problem monitoring for events in monitor '%s'"
-	UncordonErrReason       = "UncordonError"
-	UncordonErrMsgFmt       = "There was a problem while trying to uncordon the node: %s"
-	UncordonReason          = "Uncordon"
-	UncordonMsg             = "Node successfully uncordoned"
-	PreDrainErrReason       = "PreDrainError"
-	PreDrainErrMsgFmt       = "There was a problem executing the pre-drain task: %s"
-	PreDrainReason          = "PreDrain"
-	PreDrainMsg             = "Pre-drain task successfully executed"
-	CordonErrReason         = "CordonError"
-	CordonErrMsgFmt         = "There was a problem while trying to cordon the node: %s"
-	CordonReason            = "Cordon"
-	CordonMsg               = "Node successfully cordoned"
-	CordonAndDrainErrReason = "CordonAndDrainError"
-	CordonAndDrainErrMsgFmt = "There was a problem while trying to cordon and drain the node: %s"
-	CordonAndDrainReason    = "CordonAndDrain"
-	CordonAndDrainMsg       = "Node successfully cordoned and drained"
-	PostDrainErrReason      = "PostDrainError"
-	PostDrainErrMsgFmt      = "There was a problem executing the post-drain task: %s"
-	PostDrainReason         = "PostDrain"
-	PostDrainMsg            = "Post-drain task successfully executed"
-	CancelDrainErrReason    = "CancelDrainError"
-	CancelDrainErrMsgFmt    = "There was a problem executing the early exit task: %s"
-	CancelDrainReason       = "CancelDrain"
-	CancelDrainMsg          = "Early exit task successfully executed"
+	Normal                   = corev1.EventTypeNormal
+	Warning                  = corev1.EventTypeWarning
+	MonitorErrReason         = "MonitorError"
+	MonitorErrMsgFmt         = "There was a problem monitoring for events in monitor '%s'"
+	MonitorCircuitOpenReason = "MonitorCircuitOpen"
+	MonitorCircuitOpenMsgFmt = "Monitor '%s' was paused after %d consecutive failures"
+	UncordonErrReason        = "UncordonError"
+	UncordonErrMsgFmt        = "There was a problem while trying to uncordon the node: %s"
+	UncordonReason           = "Uncordon"
+	UncordonMsg              = "Node successfully uncordoned"
+	OwnershipConflictReason  = "OwnershipConflict"
+	OwnershipConflictMsgFmt  = "Left %s in place as another actor changed them since NTH did"
+	PreDrainErrReason        = "PreDrainError"
+	PreDrainErrMsgFmt        = "There was a problem executing the pre-drain task: %s"
+	PreDrainReason           = "PreDrain"
+	PreDrainMsg              = "Pre-drain task successfully executed"
+	CordonErrReason          = "CordonError"
+	CordonErrMsgFmt          = "There was a problem while trying to cordon the node: %s"
+	CordonReason             = "Cordon"
+	CordonMsg                = "Node successfully cordoned"
+	CordonAndDrainErrReason  = "CordonAndDrainError"
+	CordonAndDrainErrMsgFmt  = "There was a problem while trying to cordon and drain the node: %s"
+	CordonAndDrainReason     = "CordonAndDrain"
+	CordonAndDrainMsg        = "Node successfully cordoned and drained"
+	PostDrainErrReason       = "PostDrainError"
+	PostDrainErrMsgFmt       = "There was a problem executing the post-drain task: %s"
+	PostDrainReason          = "PostDrain"
+	PostDrainMsg             = "Post-drain task successfully executed"
+	CancelDrainErrReason     = "CancelDrainError"
+	CancelDrainErrMsgFmt     = "There was a problem executing the early exit task: %s"
+	CancelDrainReason        = "CancelDrain"
+	CancelDrainMsg           = "Early exit task successfully executed"
 )
 
 // Interruption event reasons
@@ -77,13 +84,14 @@ const (
 type K8sEventRecorder struct {
 	annotations map[string]string
 	clientSet   *kubernetes.Clientset
+	nodeCache   *node.Cache
 	enabled     bool
 	sqsMode     bool
 	record.EventRecorder
 }
 
-// InitK8sEventRecorder creates a Kubernetes event recorder
-func InitK8sEventRecorder(enabled bool, nodeName string, sqsMode bool, nodeMetadata ec2metadata.NodeMetadata, extraAnnotationsStr string, clientSet *kubernetes.Clientset) (K8sEventRecorder, error) {
+// InitK8sEventRecorder creates a Kubernetes event recorder, which looks up the nodes of the events in nodeCache, if any, in Queue Processor mode
+func InitK8sEventRecorder(enabled bool, nodeName string, sqsMode bool, nodeMetadata ec2metadata.NodeMetadata, extraAnnotationsStr string, clientSet *kubernetes.Clientset, nodeCache *node.Cache) (K8sEventRecorder, error) {
 	if !enabled {
 		return K8sEventRecorder{}, nil
 	}
@@ -116,6 +124,7 @@ func InitK8sEventRecorder(enabled bool, nodeName string, sqsMode bool, nodeMetad
 	return K8sEventRecorder{
 		annotations: annotations,
 		clientSet:   clientSet,
+		nodeCache:   nodeCache,
 		enabled:     true,
 		sqsMode:     sqsMode,
 		EventRecorder: broadcaster.NewRecorder(
@@ -133,7 +142,13 @@ func (r K8sEventRecorder) Emit(nodeName string, eventType, eventReason, eventMsg
 	if r.enabled {
 		var node *corev1.Node
 		var annotations map[string]string
-		if r.sqsMode {
+		if r.sqsMode && r.nodeCache.Synced() {
+			var found bool
+			if node, found = r.nodeCache.Node(nodeName); !found {
+				return
+			}
+			annotations = generateNodeAnnotations(node, r.annotations)
+		} else if r.sqsMode {
 			var err error
 			node, err = r.clientSet.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{})
 			if err != nil {
@@ -157,6 +172,29 @@ func (r K8sEventRecorder) Emit(nodeName string, eventType, eventReason, eventMsg
 	}
 }
 
+// EmitOwnershipConflicts logs and emits a Kubernetes event for each node change NTH left in place in err,
+// which may aggregate the errors of several nodes, and returns the other errors
+func (r K8sEventRecorder) EmitOwnershipConflicts(err error) error {
+	if err == nil {
+		return nil
+	}
+	errs := []error{err}
+	if aggregate, ok := err.(utilerrors.Aggregate); ok {
+		errs = utilerrors.Flatten(aggregate).Errors()
+	}
+	var others []error
+	for _, err := range errs {
+		var conflict *node.OwnershipConflictError
+		if !errors.As(err, &conflict) {
+			others = append(others, err)
+			continue
+		}
+		log.Warn().Str("node_name", conflict.NodeName).Strs("conflicts", conflict.Conflicts).Msg("Leaving node changes in place as another actor changed them since NTH did")
+		r.Emit(conflict.NodeName, Warning, OwnershipConflictReason, OwnershipConflictMsgFmt, strings.Join(conflict.Conflicts, ", "))
+	}
+	return utilerrors.NewAggregate(others)
+}
+
 // getReasonForKindV1 returns a Kubernetes event reason for the given interruption event kind.
 // Compatible with log format version 1.
 func getReasonForKindV1(eventKind, monitorKind string) string {
@@ -38,11 +38,17 @@ import (
 var (
 	labelEventErrorWhereKey = attribute.Key("event/error/where")
 
-	labelNodeActionKey = attribute.Key("node/action")
-	labelNodeStatusKey = attribute.Key("node/status")
-	labelNodeNameKey   = attribute.Key("node/name")
-	labelEventIDKey    = attribute.Key("node/event-id")
-	metricsEndpoint    = "/metrics"
+	labelNodeActionKey  = attribute.Key("node/action")
+	labelNodeStatusKey  = attribute.Key("node/status")
+	labelNodeNameKey    = attribute.Key("node/name")
+	labelEventIDKey     = attribute.Key("node/event-id")
+	labelMonitorKindKey = attribute.Key("monitor/kind")
+	labelEventKindKey   = attribute.Key("event/kind")
+	labelEventStateKey  = attribute.Key("event/state")
+	labelQueueKey       = attribute.Key("queue")
+	labelOutcomeKey     = attribute.Key("outcome")
+	labelNamespaceKey   = attribute.Key("namespace")
+	metricsEndpoint     = "/metrics"
 )
 
 // Metrics represents the stats for observability
@@ -57,6 +63,13 @@ type Metrics struct {
 	errorEventsCounter      api.Int64Counter
 	nthTaggedNodesGauge     api.Int64Gauge
 	nthTaggedInstancesGauge api.Int64Gauge
+	monitorCircuitGauge     api.Int64Gauge
+	eventQueueDepthGauge    api.Int64Gauge
+	eventQueueWaitHistogram api.Float64Histogram
+	eventTransitionsCounter api.Int64Counter
+	ingestionDepthGauge     api.Int64Gauge
+	podEvictionsHistogram   api.Float64Histogram
+	pdbOverridesCounter     api.Int64Counter
 }
 
 // InitMetrics will initialize, register and expose, via http server, the metrics with Opentelemetry.
@@ -167,6 +180,59 @@ func (m Metrics) InstancesRecord(num int64) {
 	m.nthTaggedInstancesGauge.Record(context.Background(), num)
 }
 
+// MonitorCircuitStateRecord will record the circuit breaker state of a monitor (0 closed, 1 half-open, 2 open), and only if metrics are enabled.
+func (m Metrics) MonitorCircuitStateRecord(monitorKind string, state int64) {
+	if !m.enabled {
+		return
+	}
+
+	m.monitorCircuitGauge.Record(context.Background(), state, api.WithAttributes(labelMonitorKindKey.String(monitorKind)))
+}
+
+// EventQueueDepthRecord will record the number of drainable events waiting for a free worker, and only if metrics are enabled.
+func (m Metrics) EventQueueDepthRecord(depth int64) {
+	if !m.enabled {
+		return
+	}
+
+	m.eventQueueDepthGauge.Record(context.Background(), depth)
+}
+
+// IngestionQueueDepthRecord will record the number of events reported by monitors which the event store has not taken in yet,
+// partitioned by queue, and only if metrics are enabled.
+func (m Metrics) IngestionQueueDepthRecord(queue string, depth int64) {
+	if !m.enabled {
+		return
+	}
+
+	m.ingestionDepthGauge.Record(context.Background(), depth, api.WithAttributes(labelQueueKey.String(queue)))
+}
+
+// EventQueueWaitRecord will record how long an event waited for a free worker, partitioned by event kind, and only if metrics are enabled.
+func (m Metrics) EventQueueWaitRecord(eventKind string, wait time.Duration) {
+	if !m.enabled {
+		return
+	}
+
+	m.eventQueueWaitHistogram.Record(context.Background(), wait.Seconds(), api.WithAttributes(labelEventKindKey.String(eventKind)))
+}
+
+// PodEvictionsRecord will record how long the eviction of each pod took, partitioned by outcome,
+// and count the PodDisruptionBudgets overridden, partitioned by namespace, and only if metrics are enabled.
+// The count of the histogram is the number of pods per outcome.
+func (m Metrics) PodEvictionsRecord(results []node.PodEvictionResult) {
+	if !m.enabled {
+		return
+	}
+
+	for _, result := range results {
+		m.podEvictionsHistogram.Record(context.Background(), result.Duration.Seconds(), api.WithAttributes(labelOutcomeKey.String(string(result.Outcome))))
+		if result.PDBOverridden {
+			m.pdbOverridesCounter.Add(context.Background(), 1, api.WithAttributes(labelNamespaceKey.String(result.Namespace)))
+		}
+	}
+}
+
 func registerMetricsWith(provider *metric.MeterProvider) (Metrics, error) {
 	meter := provider.Meter("aws.node.termination.handler")
 
@@ -208,6 +274,49 @@ func registerMetricsWith(provider *metric.MeterProvider) (Metrics, error) {
 	}
 	nthTaggedInstancesGauge.Record(context.Background(), 0)
 
+	name = "monitor.circuit.state"
+	monitorCircuitGauge, err := meter.Int64Gauge(name, api.WithDescription("Circuit breaker state per monitor (0 closed, 1 half-open, 2 open)"))
+	if err != nil {
+		return Metrics{}, fmt.Errorf("failed to create Prometheus gauge %q: %w", name, err)
+	}
+
+	name = "events.queue.depth"
+	eventQueueDepthGauge, err := meter.Int64Gauge(name, api.WithDescription("Number of drainable events waiting for a free worker"))
+	if err != nil {
+		return Metrics{}, fmt.Errorf("failed to create Prometheus gauge %q: %w", name, err)
+	}
+	eventQueueDepthGauge.Record(context.Background(), 0)
+
+	name = "events.queue.wait"
+	eventQueueWaitHistogram, err := meter.Float64Histogram(name, api.WithDescription("Time drainable events waited for a free worker"), api.WithUnit("s"))
+	if err != nil {
+		return Metrics{}, fmt.Errorf("failed to create Prometheus histogram %q: %w", name, err)
+	}
+
+	name = "events.transitions"
+	eventTransitionsCounter, err := meter.Int64Counter(name, api.WithDescription("Number of interruption event lifecycle transitions, per event kind and state entered"))
+	if err != nil {
+		return Metrics{}, fmt.Errorf("failed to create Prometheus counter %q: %w", name, err)
+	}
+
+	name = "ingestion.queue.depth"
+	ingestionDepthGauge, err := meter.Int64Gauge(name, api.WithDescription("Number of events reported by monitors which the event store has not taken in yet"))
+	if err != nil {
+		return Metrics{}, fmt.Errorf("failed to create Prometheus gauge %q: %w", name, err)
+	}
+
+	name = "pod.evictions"
+	podEvictionsHistogram, err := meter.Float64Histogram(name, api.WithDescription("Time the eviction of pods took until they terminated or the drain gave up on them, per outcome"), api.WithUnit("s"))
+	if err != nil {
+		return Metrics{}, fmt.Errorf("failed to create Prometheus histogram %q: %w", name, err)
+	}
+
+	name = "pdb.overrides"
+	pdbOverridesCounter, err := meter.Int64Counter(name, api.WithDescription("Number of pods deleted despite their PodDisruptionBudget to meet an interruption deadline"))
+	if err != nil {
+		return Metrics{}, fmt.Errorf("failed to create Prometheus counter %q: %w", name, err)
+	}
+
 	return Metrics{
 		meter:                   meter,
 		errorEventsCounter:      errorEventsCounter,
@@ -215,6 +324,13 @@ func registerMetricsWith(provider *metric.MeterProvider) (Metrics, error) {
 		actionsCounterV2:        actionsCounterV2,
 		nthTaggedNodesGauge:     nthTaggedNodesGauge,
 		nthTaggedInstancesGauge: nthTaggedInstancesGauge,
+		monitorCircuitGauge:     monitorCircuitGauge,
+		eventQueueDepthGauge:    eventQueueDepthGauge,
+		eventQueueWaitHistogram: eventQueueWaitHistogram,
+		eventTransitionsCounter: eventTransitionsCounter,
+		ingestionDepthGauge:     ingestionDepthGauge,
+		podEvictionsHistogram:   podEvictionsHistogram,
+		pdbOverridesCounter:     pdbOverridesCounter,
 	}, nil
 }
 
@@ -39,6 +39,7 @@ import (
 
 	"github.com/aws/aws-node-termination-handler/pkg/config"
 	"github.com/aws/aws-node-termination-handler/pkg/ec2helper"
+	"github.com/aws/aws-node-termination-handler/pkg/monitor"
 	"github.com/aws/aws-node-termination-handler/pkg/node"
 	h "github.com/aws/aws-node-termination-handler/pkg/test"
 	"github.com/aws/aws-node-termination-handler/pkg/uptime"
@@ -50,6 +51,8 @@ var (
 	mockNth         = "aws.node.termination.handler"
 	mockErrorEvent  = "mockErrorEvent"
 	mockAction      = "cordon-and-drain"
+	mockMonitorKind = "SQS_MONITOR"
+	mockEventKind   = "SPOT_ITN"
 	mockNodeName1   = "nodeName1"
 	mockNodeName2   = "nodeName2"
 	mockNodeName3   = "nodeName3"
@@ -120,6 +123,111 @@ func TestNodeActionsInc(t *testing.T) {
 	validateActionTotalV2(t, metricsMap, 1, errorStatus)
 }
 
+func TestMonitorCircuitStateRecord(t *testing.T) {
+	metrics := getMetrics(t)
+
+	metrics.MonitorCircuitStateRecord(mockMonitorKind, 2)
+
+	responseRecorder := mockMetricsRequest()
+
+	validateStatus(t, responseRecorder)
+
+	metricsMap := getMetricsMap(responseRecorder.Body.String())
+
+	monitorCircuitStateKey := fmt.Sprintf("monitor_circuit_state{monitor_kind=\"%v\",otel_scope_name=\"%v\",otel_scope_version=\"\"}", mockMonitorKind, mockNth)
+	h.Equals(t, "2", metricsMap[monitorCircuitStateKey])
+}
+
+func TestEventQueueRecord(t *testing.T) {
+	metrics := getMetrics(t)
+
+	metrics.EventQueueDepthRecord(3)
+	metrics.EventQueueWaitRecord(mockEventKind, 2*time.Second)
+	metrics.EventQueueWaitRecord(mockEventKind, 4*time.Second)
+
+	responseRecorder := mockMetricsRequest()
+
+	validateStatus(t, responseRecorder)
+
+	metricsMap := getMetricsMap(responseRecorder.Body.String())
+
+	validateGauge(t, metricsMap, 3, "events_queue_depth")
+	eventQueueWaitCountKey := fmt.Sprintf("events_queue_wait_seconds_count{event_kind=\"%v\",otel_scope_name=\"%v\",otel_scope_version=\"\"}", mockEventKind, mockNth)
+	h.Equals(t, "2", metricsMap[eventQueueWaitCountKey])
+	eventQueueWaitSumKey := fmt.Sprintf("events_queue_wait_seconds_sum{event_kind=\"%v\",otel_scope_name=\"%v\",otel_scope_version=\"\"}", mockEventKind, mockNth)
+	h.Equals(t, "6", metricsMap[eventQueueWaitSumKey])
+}
+
+func TestIngestionQueueDepthRecord(t *testing.T) {
+	metrics := getMetrics(t)
+
+	metrics.IngestionQueueDepthRecord("interruption", 7)
+
+	responseRecorder := mockMetricsRequest()
+
+	validateStatus(t, responseRecorder)
+
+	metricsMap := getMetricsMap(responseRecorder.Body.String())
+
+	ingestionQueueDepthKey := fmt.Sprintf("ingestion_queue_depth{otel_scope_name=\"%v\",otel_scope_version=\"\",queue=\"interruption\"}", mockNth)
+	h.Equals(t, "7", metricsMap[ingestionQueueDepthKey])
+}
+
+func TestPodEvictionsRecord(t *testing.T) {
+	metrics := getMetrics(t)
+
+	metrics.PodEvictionsRecord([]node.PodEvictionResult{
+		{Name: "evicted-1", Outcome: node.PodEvicted, Duration: 2 * time.Second},
+		{Name: "evicted-2", Outcome: node.PodEvicted, Duration: 3 * time.Second},
+		{Name: "blocked", Outcome: node.PodBlockedByPDB, Duration: 10 * time.Second},
+		{Namespace: "db", Name: "overridden", Outcome: node.PodDeleted, Duration: 20 * time.Second, PDBOverridden: true},
+	})
+
+	responseRecorder := mockMetricsRequest()
+
+	validateStatus(t, responseRecorder)
+
+	metricsMap := getMetricsMap(responseRecorder.Body.String())
+
+	evictedCountKey := fmt.Sprintf("pod_evictions_seconds_count{otel_scope_name=\"%v\",otel_scope_version=\"\",outcome=\"Evicted\"}", mockNth)
+	h.Equals(t, "2", metricsMap[evictedCountKey])
+	evictedSumKey := fmt.Sprintf("pod_evictions_seconds_sum{otel_scope_name=\"%v\",otel_scope_version=\"\",outcome=\"Evicted\"}", mockNth)
+	h.Equals(t, "5", metricsMap[evictedSumKey])
+	blockedCountKey := fmt.Sprintf("pod_evictions_seconds_count{otel_scope_name=\"%v\",otel_scope_version=\"\",outcome=\"BlockedByPDB\"}", mockNth)
+	h.Equals(t, "1", metricsMap[blockedCountKey])
+	pdbOverridesKey := fmt.Sprintf("pdb_overrides_total{namespace=\"db\",otel_scope_name=\"%v\",otel_scope_version=\"\"}", mockNth)
+	h.Equals(t, "1", metricsMap[pdbOverridesKey])
+}
+
+func TestObserveTransition(t *testing.T) {
+	metrics := getMetrics(t)
+	event := &monitor.InterruptionEvent{EventID: "123", Kind: mockEventKind, NodeName: mockNodeName1}
+
+	metrics.ObserveTransition(mockNodeName1, event, monitor.Transition{From: monitor.StateReceived, To: monitor.StateScheduled}, nil)
+	metrics.ObserveTransition(mockNodeName1, event, monitor.Transition{From: monitor.StateDraining, To: monitor.StateDrained}, nil)
+	metrics.ObserveTransition(mockNodeName1, event, monitor.Transition{From: monitor.StatePostDrain, To: monitor.StateFailed}, errors.New("post-drain failed"))
+	// a post-drain task followed by a failed step still succeeded
+	metrics.ObserveTransition(mockNodeName1, event, monitor.Transition{From: monitor.StatePostDrain, To: monitor.StateCompleted}, errors.New("taint failed"))
+	// an action interrupted by a restart did not end
+	metrics.ObserveTransition(mockNodeName1, event, monitor.Transition{From: monitor.StateCordoning, To: monitor.StateReceived}, nil)
+
+	responseRecorder := mockMetricsRequest()
+
+	validateStatus(t, responseRecorder)
+
+	metricsMap := getMetricsMap(responseRecorder.Body.String())
+
+	for state, expected := range map[monitor.EventState]string{monitor.StateScheduled: "1", monitor.StateFailed: "1", monitor.StateReceived: "1"} {
+		transitionsKey := fmt.Sprintf("events_transitions_total{event_kind=\"%v\",event_state=\"%v\",otel_scope_name=\"%v\",otel_scope_version=\"\"}", mockEventKind, state, mockNth)
+		h.Equals(t, expected, metricsMap[transitionsKey])
+	}
+	for action, expected := range map[string]string{"cordon-and-drain/success": "1", "post-drain/success": "1", "post-drain/error": "1", "cordon/success": ""} {
+		parts := strings.Split(action, "/")
+		actionTotalKey := fmt.Sprintf("actions_total{node_action=\"%v\",node_status=\"%v\",otel_scope_name=\"%v\",otel_scope_version=\"\"}", parts[0], parts[1], mockNth)
+		h.Equals(t, expected, metricsMap[actionTotalKey])
+	}
+}
+
 func TestRegisterMetricsWith(t *testing.T) {
 	const errorEventMetricsTotal = 23
 	const successActionMetricsTotal = 31
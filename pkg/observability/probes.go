@@ -1,14 +1,33 @@
 package observability
 
 import (
+	"encoding/json"
 	"net"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
 )
 
+var monitorStates = struct {
+	sync.RWMutex
+	reporters map[string]func() string
+}{reporters: map[string]func() string{}}
+
+type probeResponse struct {
+	Health   string            `json:"health"`
+	Monitors map[string]string `json:"monitors,omitempty"`
+}
+
+// RegisterMonitorState adds the state reported by the given func, keyed by monitor kind, to the probes response.
+func RegisterMonitorState(monitorKind string, state func() string) {
+	monitorStates.Lock()
+	defer monitorStates.Unlock()
+	monitorStates.reporters[monitorKind] = state
+}
+
 // InitProbes will initialize, register and expose, via http server, the probes.
 func InitProbes(enabled bool, port int, endpoint string) error {
 	if !enabled {
@@ -35,9 +54,25 @@ func InitProbes(enabled bool, port int, endpoint string) error {
 }
 
 func livenessHandler(w http.ResponseWriter, r *http.Request) {
+	response := probeResponse{Health: "OK"}
+	monitorStates.RLock()
+	if len(monitorStates.reporters) > 0 {
+		response.Monitors = make(map[string]string, len(monitorStates.reporters))
+		for monitorKind, state := range monitorStates.reporters {
+			response.Monitors[monitorKind] = state()
+		}
+	}
+	monitorStates.RUnlock()
+	body, err := json.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Warn().Err(err).Msg("Unable to marshal health response")
+		return
+	}
+
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_, err := w.Write([]byte(`{"health":"OK"}`))
+	_, err = w.Write(body)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		log.Warn().Err(err).Msg("Unable to write health response")
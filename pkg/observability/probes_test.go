@@ -28,3 +28,28 @@ func TestLivenessHandler(t *testing.T) {
 			body, http.StatusText(http.StatusOK))
 	}
 }
+
+func TestLivenessHandler_MonitorStates(t *testing.T) {
+	RegisterMonitorState("SQS_MONITOR", func() string { return "open" })
+	t.Cleanup(func() {
+		monitorStates.Lock()
+		delete(monitorStates.reporters, "SQS_MONITOR")
+		monitorStates.Unlock()
+	})
+	req := httptest.NewRequest("GET", "/healthz", nil)
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(livenessHandler)
+
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	expected := `{"health":"OK","monitors":{"SQS_MONITOR":"open"}}`
+	if body := rr.Body.String(); body != expected {
+		t.Errorf("handler returned wrong body: got %v want %v",
+			body, expected)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package observability
+
+import (
+	"context"
+
+	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+	"github.com/aws/aws-node-termination-handler/pkg/node"
+	"github.com/rs/zerolog/log"
+	api "go.opentelemetry.io/otel/metric"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// nodeActionEvent describes the Kubernetes events emitted when a node action ends
+type nodeActionEvent struct {
+	reason       string
+	msg          string
+	errReason    string
+	errMsgFmt    string
+	skipNotFound bool
+}
+
+var nodeActionEvents = map[string]nodeActionEvent{
+	"pre-drain":        {PreDrainReason, PreDrainMsg, PreDrainErrReason, PreDrainErrMsgFmt, false},
+	"cordon":           {CordonReason, CordonMsg, CordonErrReason, CordonErrMsgFmt, true},
+	"cordon-and-drain": {CordonAndDrainReason, CordonAndDrainMsg, CordonAndDrainErrReason, CordonAndDrainErrMsgFmt, true},
+	"post-drain":       {PostDrainReason, PostDrainMsg, PostDrainErrReason, PostDrainErrMsgFmt, false},
+}
+
+// nodeAction returns the node action which ended with the transition and its error, or an empty action if the transition ended none
+func nodeAction(transition monitor.Transition, err error) (string, error) {
+	if transition.To == monitor.StateReceived || transition.To == monitor.StateCancelled {
+		// the action was interrupted by a restart or a cancellation rather than ended
+		return "", nil
+	}
+	switch transition.From {
+	case monitor.StatePreDrain:
+		return "pre-drain", err
+	case monitor.StateCordoning:
+		return "cordon", err
+	case monitor.StateDraining:
+		return "cordon-and-drain", err
+	case monitor.StatePostDrain:
+		// a completed event may carry the error of a step which followed the post-drain task
+		if transition.To != monitor.StateFailed {
+			err = nil
+		}
+		return "post-drain", err
+	}
+	return "", nil
+}
+
+// ObserveTransition counts the transition, partitioned by event kind and state, and the node action it ended, if any,
+// and only if metrics are enabled.
+func (m Metrics) ObserveTransition(nodeName string, event *monitor.InterruptionEvent, transition monitor.Transition, err error) {
+	if !m.enabled {
+		return
+	}
+
+	m.eventTransitionsCounter.Add(context.Background(), 1, api.WithAttributes(labelEventKindKey.String(event.Kind), labelEventStateKey.String(string(transition.To))))
+	action, actionErr := nodeAction(transition, err)
+	if action == "" || (nodeActionEvents[action].skipNotFound && errors.IsNotFound(actionErr)) {
+		return
+	}
+	m.NodeActionsInc(action, nodeName, event.EventID, actionErr)
+}
+
+// EmitTransition emits a Kubernetes event for the node when the interruption event is handed to a worker and when a node action ends
+func (r K8sEventRecorder) EmitTransition(nodeName string, event *monitor.InterruptionEvent, transition monitor.Transition, err error) {
+	if transition.To == monitor.StateScheduled {
+		r.Emit(nodeName, Normal, GetReasonForKind(event.Kind, event.Monitor), event.Description)
+		return
+	}
+	action, actionErr := nodeAction(transition, err)
+	if action == "" {
+		return
+	}
+	actionEvent := nodeActionEvents[action]
+	switch {
+	case actionErr == nil:
+		r.Emit(nodeName, Normal, actionEvent.reason, actionEvent.msg)
+	case actionEvent.skipNotFound && errors.IsNotFound(actionErr):
+		return
+	default:
+		r.Emit(nodeName, Warning, actionEvent.errReason, actionEvent.errMsgFmt, actionErr.Error())
+	}
+}
+
+// terminationStatus returns the termination status of the node of the event after the transition
+func terminationStatus(event *monitor.InterruptionEvent, transition monitor.Transition) *node.TerminationStatus {
+	status := &node.TerminationStatus{
+		EventID:            event.EventID,
+		Kind:               event.Kind,
+		Monitor:            event.Monitor,
+		Phase:              string(transition.To),
+		LastTransitionTime: metav1.NewTime(transition.Time),
+		Message:            transition.Error,
+	}
+	if !event.StartTime.IsZero() {
+		terminationTime := metav1.NewTime(event.StartTime)
+		status.TerminationTime = &terminationTime
+	}
+	if deadline, ok := event.Deadline(); ok {
+		deadlineTime := metav1.NewTime(deadline)
+		status.Deadline = &deadlineTime
+	}
+	return status
+}
+
+// AnnotateTransition updates the termination annotation of the node as the event advances, and removes it once the event is cancelled.
+// An event merged into another event of the node leaves the annotation to that event.
+func AnnotateTransition(n node.Node, nodeName string, event *monitor.InterruptionEvent, transition monitor.Transition) {
+	if event.Lifecycle.MergedInto != "" {
+		return
+	}
+	var status *node.TerminationStatus
+	if transition.To != monitor.StateCancelled {
+		status = terminationStatus(event, transition)
+	}
+	if err := n.AnnotateTermination(nodeName, status); err != nil {
+		log.Warn().Err(err).Str("node_name", nodeName).Str("event_id", event.EventID).Msg("Unable to update the termination annotation of the node")
+	}
+}
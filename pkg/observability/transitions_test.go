@@ -0,0 +1,76 @@
+// Copyright 2016-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//     http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-node-termination-handler/pkg/config"
+	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+	"github.com/aws/aws-node-termination-handler/pkg/node"
+	h "github.com/aws/aws-node-termination-handler/pkg/test"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kubectl/pkg/drain"
+)
+
+func TestAnnotateTransition(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}})
+	n, err := node.NewWithValues(config.Config{NodeTerminationAnnotation: true}, &drain.Helper{Ctx: context.Background(), Client: client}, nil)
+	h.Ok(t, err)
+	startTime := time.Date(2026, 10, 16, 12, 3, 17, 0, time.UTC)
+	event := &monitor.InterruptionEvent{
+		EventID:       "event-1",
+		Kind:          monitor.SpotITNKind,
+		Monitor:       "SQS_MONITOR",
+		StartTime:     startTime,
+		DrainDeadline: startTime.Add(-10 * time.Second),
+	}
+	getStatus := func() (node.TerminationStatus, bool) {
+		k8sNode, err := client.CoreV1().Nodes().Get(context.Background(), "node", metav1.GetOptions{})
+		h.Ok(t, err)
+		value, ok := k8sNode.Annotations[node.TerminationAnnotationKey]
+		var status node.TerminationStatus
+		if ok {
+			h.Ok(t, json.Unmarshal([]byte(value), &status))
+		}
+		return status, ok
+	}
+
+	AnnotateTransition(*n, "node", event, monitor.Transition{From: monitor.StateScheduled, To: monitor.StateDraining, Time: startTime.Add(-time.Minute)})
+	status, ok := getStatus()
+	h.Assert(t, ok, "Node should be annotated with its termination status")
+	h.Equals(t, "event-1", status.EventID)
+	h.Equals(t, monitor.SpotITNKind, status.Kind)
+	h.Equals(t, "SQS_MONITOR", status.Monitor)
+	h.Equals(t, string(monitor.StateDraining), status.Phase)
+	h.Assert(t, status.TerminationTime.Equal(&metav1.Time{Time: startTime}), "Unexpected termination time %v", status.TerminationTime)
+	h.Assert(t, status.Deadline.Equal(&metav1.Time{Time: event.DrainDeadline}), "Unexpected deadline %v", status.Deadline)
+
+	// an event merged into another event of the node leaves the annotation to that event
+	merged := *event
+	merged.EventID = "event-2"
+	merged.Lifecycle.MergedInto = event.EventID
+	AnnotateTransition(*n, "node", &merged, monitor.Transition{From: monitor.StatePreDrain, To: monitor.StateCompleted, Time: startTime})
+	status, _ = getStatus()
+	h.Equals(t, "event-1", status.EventID)
+
+	AnnotateTransition(*n, "node", event, monitor.Transition{From: monitor.StateDraining, To: monitor.StateCancelled, Time: startTime})
+	_, ok = getStatus()
+	h.Assert(t, !ok, "Termination annotation should be removed once the event is cancelled")
+}
@@ -14,7 +14,10 @@
 package test
 
 import (
+	"sync/atomic"
+
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
 	"github.com/aws/aws-sdk-go/service/ec2"
@@ -26,10 +29,12 @@ import (
 // MockedSQS mocks the SQS API
 type MockedSQS struct {
 	sqsiface.SQSAPI
-	ReceiveMessageResp sqs.ReceiveMessageOutput
-	ReceiveMessageErr  error
-	DeleteMessageResp  sqs.DeleteMessageOutput
-	DeleteMessageErr   error
+	ReceiveMessageResp          sqs.ReceiveMessageOutput
+	ReceiveMessageErr           error
+	DeleteMessageResp           sqs.DeleteMessageOutput
+	DeleteMessageErr            error
+	ChangeMessageVisibilityResp sqs.ChangeMessageVisibilityOutput
+	ChangeMessageVisibilityErr  error
 }
 
 // ReceiveMessage mocks the sqs.ReceiveMessage API call
@@ -37,6 +42,16 @@ func (m MockedSQS) ReceiveMessage(input *sqs.ReceiveMessageInput) (*sqs.ReceiveM
 	return &m.ReceiveMessageResp, m.ReceiveMessageErr
 }
 
+// ReceiveMessageWithContext mocks the sqs.ReceiveMessageWithContext API call
+func (m MockedSQS) ReceiveMessageWithContext(_ aws.Context, input *sqs.ReceiveMessageInput, _ ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	return m.ReceiveMessage(input)
+}
+
+// ChangeMessageVisibility mocks the sqs.ChangeMessageVisibility API call
+func (m MockedSQS) ChangeMessageVisibility(input *sqs.ChangeMessageVisibilityInput) (*sqs.ChangeMessageVisibilityOutput, error) {
+	return &m.ChangeMessageVisibilityResp, m.ChangeMessageVisibilityErr
+}
+
 // DeleteMessage mocks the sqs.DeleteMessage API call
 func (m MockedSQS) DeleteMessage(input *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
 	return &m.DeleteMessageResp, m.DeleteMessageErr
@@ -68,6 +83,8 @@ type MockedASG struct {
 	HeartbeatTimeout                   int
 	AutoScalingGroupName               string
 	LifecycleHookName                  string
+	// HeartbeatSent, if set, receives a value for every recorded heartbeat
+	HeartbeatSent chan<- struct{}
 }
 
 // CompleteLifecycleAction mocks the autoscaling.CompleteLifecycleAction API call
@@ -88,12 +105,16 @@ func (m MockedASG) DescribeTagsPages(input *autoscaling.DescribeTagsInput, fn de
 	return m.DescribeTagsPagesErr
 }
 
-var HeartbeatCallCount int
+// HeartbeatCallCount counts the heartbeats recorded by MockedASG, which are sent from their own goroutine
+var HeartbeatCallCount atomic.Int64
 
 // RecordLifecycleActionHeartbeat mocks the autoscaling.RecordLifecycleActionHeartbeat API call
 func (m MockedASG) RecordLifecycleActionHeartbeat(input *autoscaling.RecordLifecycleActionHeartbeatInput) (*autoscaling.RecordLifecycleActionHeartbeatOutput, error) {
-	HeartbeatCallCount++
-	if m.RecordLifecycleActionHeartbeatErr != nil && HeartbeatCallCount%2 == 1 {
+	callCount := HeartbeatCallCount.Add(1)
+	if m.HeartbeatSent != nil {
+		m.HeartbeatSent <- struct{}{}
+	}
+	if m.RecordLifecycleActionHeartbeatErr != nil && callCount%2 == 1 {
 		return &m.RecordLifecycleActionHeartbeatResp, m.RecordLifecycleActionHeartbeatErr
 	}
 	return &m.RecordLifecycleActionHeartbeatResp, nil
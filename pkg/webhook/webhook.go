@@ -37,6 +37,17 @@ type combinedDrainData struct {
 	InstanceType string
 }
 
+// PostTransition posts the event to the webhook url once an attempt to cordon, or to cordon and drain, its node ended
+func PostTransition(additionalInfo ec2metadata.NodeMetadata, event *monitor.InterruptionEvent, transition monitor.Transition, nthConfig config.Config) {
+	if transition.From != monitor.StateCordoning && transition.From != monitor.StateDraining {
+		return
+	}
+	if transition.To == monitor.StateReceived || transition.To == monitor.StateCancelled {
+		return
+	}
+	Post(additionalInfo, event, nthConfig)
+}
+
 // Post makes a http post to send drain event data to webhook url
 func Post(additionalInfo ec2metadata.NodeMetadata, event *monitor.InterruptionEvent, nthConfig config.Config) {
 	var webhookTemplateContent string
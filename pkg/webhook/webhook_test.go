@@ -28,6 +28,7 @@ import (
 	"github.com/aws/aws-node-termination-handler/pkg/config"
 	"github.com/aws/aws-node-termination-handler/pkg/ec2metadata"
 	"github.com/aws/aws-node-termination-handler/pkg/monitor"
+	"github.com/aws/aws-node-termination-handler/pkg/node"
 	h "github.com/aws/aws-node-termination-handler/pkg/test"
 	"github.com/aws/aws-node-termination-handler/pkg/webhook"
 	"github.com/rs/zerolog/log"
@@ -114,6 +115,39 @@ func TestPostSuccess(t *testing.T) {
 	webhook.Post(nodeMetadata, event, nthconfig)
 }
 
+func TestPostEvictions(t *testing.T) {
+	event := &monitor.InterruptionEvent{
+		EventID: "spot-itn-event-0d59937288b749b32",
+		Evictions: []node.PodEvictionResult{
+			{Namespace: "default", Name: "web", Outcome: node.PodEvicted},
+			{Namespace: "default", Name: "db", Outcome: node.PodBlockedByPDB},
+		},
+	}
+
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requested = true
+		requestBody, err := io.ReadAll(req.Body)
+		h.Ok(t, err)
+		requestMap := map[string]interface{}{}
+		h.Ok(t, json.Unmarshal(requestBody, &requestMap))
+		h.Equals(t, "default/web=Evicted default/db=BlockedByPDB ", requestMap["text"])
+
+		_, err = rw.Write([]byte(`OK`))
+		h.Ok(t, err)
+	}))
+	defer server.Close()
+
+	nthconfig := config.Config{
+		WebhookURL:      server.URL,
+		WebhookHeaders:  testWebhookHeaders,
+		WebhookTemplate: `{"text":"{{ range .Evictions }}{{ .Namespace }}/{{ .Name }}={{ .Outcome }} {{ end }}"}`,
+	}
+
+	webhook.Post(ec2metadata.NodeMetadata{}, event, nthconfig)
+	h.Assert(t, requested, "Webhook was not posted")
+}
+
 func TestPostTemplateParseError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 		t.Error("Request made with invalid webhook")